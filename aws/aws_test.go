@@ -3,11 +3,9 @@ package aws
 import (
 	"context"
 	"log"
-	"os"
-	"path/filepath"
-	"sync"
 	"testing"
 
+	"github.com/RA-Balaji/storage-synk/dryrun"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -77,42 +75,17 @@ func TestSSMParameterGet(t *testing.T) {
 	}
 }
 
-func TestS3FolderUpload(t *testing.T) {
-	ctx := context.Background()
+func TestProvisioningDryRun(t *testing.T) {
+	plan := dryrun.NewPlan()
+	ctx := dryrun.WithPlan(context.Background(), plan)
 
-	// Create bucket if it doesn't exist
-	err := S3BucketCreate(ctx, testRegion, "balaji-tests-2")
+	assert.NoError(t, S3BucketCreate(ctx, "", testBucketName))
+	assert.NoError(t, VPCCreate(ctx, testRegion, testBucketName))
+	assert.NoError(t, IAMRoleCreate(ctx, testProject, testRegion, testBucketName))
 
-	// Create a temporary directory and some files
-	tmpDir, err := os.MkdirTemp("", "testdir")
-	assert.NoError(t, err)
-	log.Println("tmpDir:", tmpDir)
-
-	filePaths := []string{
-		filepath.Join(tmpDir, "file1.txt"),
-		filepath.Join(tmpDir, "subdir", "file2.txt"),
-		filepath.Join(tmpDir, "subdir", "file3.txt"),
-	}
-
-	// Create file and lead content
-	for _, path := range filePaths {
-		os.MkdirAll(filepath.Dir(path), 0755)
-		f, err := os.Create(path)
-		assert.NoError(t, err)
-		f.WriteString("test content")
-		f.Close()
-	}
-
-	// Initialize wait group, and semaphore
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 10) // Limit to 10 concurrent uploads
-
-	// Perform the upload
-	err = S3FolderUpload(ctx, testRegion, "balaji-tests-2", tmpDir, &wg, sem)
-	if err != nil {
-		t.Fatalf("Error: %v", err)
+	actions := plan.Actions()
+	if assert.Len(t, actions, 3) {
+		assert.Equal(t, dryrun.Action{Op: dryrun.Create, Target: "S3 bucket " + testBucketName, Size: -1}, actions[0])
+		assert.Contains(t, actions[1].Target, "balaji-tests-storagesynk-vpc")
 	}
-
-	// Wait for all goroutines to finish
-	wg.Wait()
 }
@@ -0,0 +1,189 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var _ store.BucketManager = (*S3Store)(nil)
+
+// CreateBucket creates bucket in opts.Location, or in the region of the
+// client, and then applies the other options one request each.
+func (s *S3Store) CreateBucket(ctx context.Context, bucket string, opts store.BucketOptions) error {
+	if opts.StorageClass != "" {
+		return fmt.Errorf("S3 buckets have no default storage class: set it per object or with a lifecycle rule")
+	}
+	if opts.Encryption.Mode == store.EncryptionCustomer {
+		return fmt.Errorf("SSE-C cannot be the default encryption of a bucket")
+	}
+	if dryrun.Report(ctx, dryrun.Create, "S3 bucket "+bucket) {
+		return nil
+	}
+
+	region := opts.Location
+	if region == "" {
+		region = s.client.Options().Region
+	}
+	inRegion := func(o *s3.Options) {
+		if region != "" {
+			o.Region = region
+		}
+	}
+
+	input := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+	// us-east-1 is the default and may not be given as a constraint.
+	if region != "" && region != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(region),
+		}
+	}
+	if _, err := s.client.CreateBucket(ctx, input, inRegion); err != nil {
+		return fmt.Errorf("Error creating S3 bucket [%s]: %w", bucket, err)
+	}
+	if err := s.configureBucket(ctx, bucket, opts, inRegion); err != nil {
+		return fmt.Errorf("Error configuring S3 bucket [%s], which was created: %w", bucket, err)
+	}
+	return nil
+}
+
+func (s *S3Store) configureBucket(
+	ctx context.Context,
+	bucket string, opts store.BucketOptions, optFns ...func(*s3.Options)) error {
+
+	if opts.BlockPublicAccess {
+		_, err := s.client.PutPublicAccessBlock(ctx, &s3.PutPublicAccessBlockInput{
+			Bucket: aws.String(bucket),
+			PublicAccessBlockConfiguration: &types.PublicAccessBlockConfiguration{
+				BlockPublicAcls:       aws.Bool(true),
+				IgnorePublicAcls:      aws.Bool(true),
+				BlockPublicPolicy:     aws.Bool(true),
+				RestrictPublicBuckets: aws.Bool(true),
+			},
+		}, optFns...)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Versioning {
+		_, err := s.client.PutBucketVersioning(ctx, &s3.PutBucketVersioningInput{
+			Bucket: aws.String(bucket),
+			VersioningConfiguration: &types.VersioningConfiguration{
+				Status: types.BucketVersioningStatusEnabled,
+			},
+		}, optFns...)
+		if err != nil {
+			return err
+		}
+	}
+	if sse, kmsKey := serverSide(opts.Encryption); sse != "" {
+		_, err := s.client.PutBucketEncryption(ctx, &s3.PutBucketEncryptionInput{
+			Bucket: aws.String(bucket),
+			ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{
+				Rules: []types.ServerSideEncryptionRule{{
+					ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+						SSEAlgorithm:   sse,
+						KMSMasterKeyID: kmsKey,
+					},
+				}},
+			},
+		}, optFns...)
+		if err != nil {
+			return err
+		}
+	}
+	if len(opts.Labels) > 0 {
+		tags := make([]types.Tag, 0, len(opts.Labels))
+		for key, value := range opts.Labels {
+			tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+		sort.Slice(tags, func(i, j int) bool { return *tags[i].Key < *tags[j].Key })
+		_, err := s.client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+			Bucket:  aws.String(bucket),
+			Tagging: &types.Tagging{TagSet: tags},
+		}, optFns...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteBucket deletes bucket. With force it first deletes every object
+// version and delete marker, a page of up to 1000 at a time, and aborts
+// the multipart uploads in progress.
+func (s *S3Store) DeleteBucket(ctx context.Context, bucket string, force bool) error {
+	if dryrun.Report(ctx, dryrun.Delete, "S3 bucket "+bucket) {
+		return nil
+	}
+	if force {
+		if err := s.emptyBucket(ctx, bucket); err != nil {
+			return err
+		}
+	}
+	if _, err := s.client.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		return fmt.Errorf("Error deleting S3 bucket [%s]: %w", bucket, err)
+	}
+	return nil
+}
+
+func (s *S3Store) emptyBucket(ctx context.Context, bucket string) error {
+	versions := s3.NewListObjectVersionsPaginator(s.client, &s3.ListObjectVersionsInput{Bucket: aws.String(bucket)})
+	for versions.HasMorePages() {
+		page, err := versions.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("Error listing versions in S3 bucket [%s]: %w", bucket, err)
+		}
+		var objects []types.ObjectIdentifier
+		for _, v := range page.Versions {
+			objects = append(objects, types.ObjectIdentifier{Key: v.Key, VersionId: v.VersionId})
+		}
+		for _, m := range page.DeleteMarkers {
+			objects = append(objects, types.ObjectIdentifier{Key: m.Key, VersionId: m.VersionId})
+		}
+		for start := 0; start < len(objects); start += maxDeleteKeys {
+			end := start + maxDeleteKeys
+			if end > len(objects) {
+				end = len(objects)
+			}
+			out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(bucket),
+				Delete: &types.Delete{Objects: objects[start:end], Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				return fmt.Errorf("Error deleting objects in S3 bucket [%s]: %w", bucket, err)
+			}
+			if len(out.Errors) > 0 {
+				e := out.Errors[0]
+				return fmt.Errorf("Error deleting s3://%s/%s (version %s): %s: %s, and %d more",
+					bucket, aws.ToString(e.Key), aws.ToString(e.VersionId),
+					aws.ToString(e.Code), aws.ToString(e.Message), len(out.Errors)-1)
+			}
+		}
+	}
+
+	uploads := s3.NewListMultipartUploadsPaginator(s.client, &s3.ListMultipartUploadsInput{Bucket: aws.String(bucket)})
+	for uploads.HasMorePages() {
+		page, err := uploads.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("Error listing multipart uploads in S3 bucket [%s]: %w", bucket, err)
+		}
+		for _, u := range page.Uploads {
+			_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(bucket),
+				Key:      u.Key,
+				UploadId: u.UploadId,
+			})
+			if err != nil {
+				return fmt.Errorf("Error aborting upload of s3://%s/%s: %w", bucket, aws.ToString(u.Key), err)
+			}
+		}
+	}
+	return nil
+}
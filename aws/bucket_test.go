@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// bucketServer records the bucket requests it gets as "METHOD subresource"
+// and answers listings with a page of versions and no uploads.
+type bucketServer struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   map[string]string
+}
+
+func (b *bucketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var sub []string
+	for name := range r.URL.Query() {
+		if name != "x-id" {
+			sub = append(sub, name)
+		}
+	}
+	sort.Strings(sub)
+	request := strings.TrimSpace(r.Method + " " + strings.Join(sub, ","))
+	b.requests = append(b.requests, request)
+	body, _ := io.ReadAll(r.Body)
+	b.bodies[request] = string(body)
+
+	switch request {
+	case "GET versions":
+		fmt.Fprint(w, `<ListVersionsResult><IsTruncated>false</IsTruncated>`+
+			`<Version><Key>a.txt</Key><VersionId>v1</VersionId></Version>`+
+			`<Version><Key>a.txt</Key><VersionId>v2</VersionId></Version>`+
+			`<DeleteMarker><Key>b.txt</Key><VersionId>v3</VersionId></DeleteMarker>`+
+			`</ListVersionsResult>`)
+	case "POST delete":
+		fmt.Fprint(w, `<DeleteResult></DeleteResult>`)
+	case "GET uploads":
+		fmt.Fprint(w, `<ListMultipartUploadsResult><IsTruncated>false</IsTruncated></ListMultipartUploadsResult>`)
+	case "DELETE":
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newBucketServer(t *testing.T) (*S3Store, *bucketServer) {
+	fake := &bucketServer{bodies: map[string]string{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	return &S3Store{client: client}, fake
+}
+
+func TestS3StoreCreateBucket(t *testing.T) {
+	s, fake := newBucketServer(t)
+	err := s.CreateBucket(context.Background(), "new-bucket", store.BucketOptions{
+		Location:          "eu-west-1",
+		Versioning:        true,
+		Encryption:        store.Encryption{Mode: store.EncryptionKMS, KMSKey: "arn:key"},
+		BlockPublicAccess: true,
+		Labels:            map[string]string{"team": "data"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"PUT", "PUT publicAccessBlock", "PUT versioning", "PUT encryption", "PUT tagging"}, fake.requests)
+	assert.Contains(t, fake.bodies["PUT"], "<LocationConstraint>eu-west-1</LocationConstraint>")
+	assert.Contains(t, fake.bodies["PUT encryption"], "<KMSMasterKeyID>arn:key</KMSMasterKeyID>")
+	assert.Contains(t, fake.bodies["PUT tagging"], "<Key>team</Key><Value>data</Value>")
+
+	err = s.CreateBucket(context.Background(), "cold", store.BucketOptions{StorageClass: "GLACIER"})
+	assert.ErrorContains(t, err, "no default storage class")
+}
+
+func TestS3StoreDeleteBucketForce(t *testing.T) {
+	s, fake := newBucketServer(t)
+	assert.NoError(t, s.DeleteBucket(context.Background(), "full", true))
+	assert.Equal(t, []string{"GET versions", "POST delete", "GET uploads", "DELETE"}, fake.requests)
+	for _, version := range []string{"v1", "v2", "v3"} {
+		assert.Contains(t, fake.bodies["POST delete"], "<VersionId>"+version+"</VersionId>")
+	}
+}
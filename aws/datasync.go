@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/RA-Balaji/storage-synk/dryrun"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -38,6 +39,10 @@ func SsmParameterGet(ctx context.Context, region string) (ssm.GetParameterOutput
 }
 
 func LaunchEc2ForDatasync(ctx context.Context, region string) error {
+	if dryrun.Report(ctx, dryrun.Create, "DataSync agent EC2 instance in "+region) {
+		return nil
+	}
+
 	ssmParam, err := SsmParameterGet(ctx, region)
 	if err != nil {
 		return err
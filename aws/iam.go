@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/RA-Balaji/storage-synk/dryrun"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
@@ -41,6 +42,10 @@ func newIAMClient(region string) (*iam.Client, error) {
 }
 
 func IAMRoleCreate(ctx context.Context, project, region, bucketName string) error {
+	if dryrun.Report(ctx, dryrun.Create, "IAM role storage-synk-"+bucketName) {
+		return nil
+	}
+
 	iamClient, err := newIAMClient(region)
 	if err != nil {
 		return fmt.Errorf("Error initializing iam client: %v", err)
@@ -0,0 +1,504 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/RA-Balaji/storage-synk/bwlimit"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	minPartSize            = 5 * 1024 * 1024
+	defaultPartSize        = 8 * 1024 * 1024
+	defaultPartConcurrency = 4
+	maxUploadParts         = 10000
+
+	// maxCopySize is the largest object a single CopyObject copies.
+	maxCopySize = 5 * 1024 * 1024 * 1024
+	// Parts of a copy are copied by S3 itself, so they can be large
+	// without costing memory.
+	defaultCopyPartSize = 256 * 1024 * 1024
+)
+
+type multipartUpload struct {
+	client     *s3.Client
+	bucket     string
+	key        string
+	uploadID   string
+	partSize   int64
+	checkpoint store.Checkpoint
+	// ssec is sent with every part when the object uses SSE-C.
+	ssec sseCustomer
+
+	mu    sync.Mutex
+	parts []types.CompletedPart
+	// done holds the parts uploaded by an earlier attempt.
+	done map[int32]bool
+}
+
+// beginMultipartUpload continues the upload saved in opts.Checkpoint when
+// S3 still knows it, and starts a new one otherwise.
+func (s *S3Store) beginMultipartUpload(
+	ctx context.Context,
+	bucket, key string, partSize int64, opts store.WriteOptions) (*multipartUpload, error) {
+
+	checkpoint := opts.Checkpoint
+
+	upload := &multipartUpload{
+		client:     s.client,
+		bucket:     bucket,
+		key:        key,
+		partSize:   partSize,
+		checkpoint: checkpoint,
+		ssec:       writeCustomerKey(opts.Encryption),
+		done:       map[int32]bool{},
+	}
+
+	if checkpoint != nil {
+		if state, ok := checkpoint.Resume(); ok && state.PartSize > 0 {
+			upload.uploadID = state.UploadID
+			upload.partSize = state.PartSize
+			err := upload.listParts(ctx)
+			if err == nil {
+				return upload, nil
+			}
+			if !isNoSuchUpload(err) {
+				return nil, err
+			}
+			upload.parts = nil
+			upload.done = map[int32]bool{}
+			upload.partSize = partSize
+		}
+	}
+
+	sse, kmsKey := serverSide(opts.Encryption)
+	out, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		ContentType:          optional(opts.ContentType),
+		CacheControl:         optional(opts.CacheControl),
+		ContentEncoding:      optional(opts.ContentEncoding),
+		ContentDisposition:   optional(opts.ContentDisposition),
+		Metadata:             opts.Metadata,
+		ChecksumAlgorithm:    types.ChecksumAlgorithmCrc32c,
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKey,
+		SSECustomerAlgorithm: upload.ssec.algorithm,
+		SSECustomerKey:       upload.ssec.key,
+		SSECustomerKeyMD5:    upload.ssec.keyMD5,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error starting multipart upload to s3://%s/%s: %w", bucket, key, err)
+	}
+	upload.uploadID = aws.ToString(out.UploadId)
+
+	if checkpoint != nil {
+		err := checkpoint.Started(store.UploadState{UploadID: upload.uploadID, PartSize: upload.partSize})
+		if err != nil {
+			upload.abort()
+			return nil, err
+		}
+	}
+	return upload, nil
+}
+
+// listParts loads the parts S3 already holds for the upload.
+func (u *multipartUpload) listParts(ctx context.Context) error {
+	paginator := s3.NewListPartsPaginator(u.client, &s3.ListPartsInput{
+		Bucket:   aws.String(u.bucket),
+		Key:      aws.String(u.key),
+		UploadId: aws.String(u.uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, part := range page.Parts {
+			u.parts = append(u.parts, types.CompletedPart{
+				ETag:           part.ETag,
+				PartNumber:     part.PartNumber,
+				ChecksumCRC32C: part.ChecksumCRC32C,
+			})
+			u.done[aws.ToInt32(part.PartNumber)] = true
+		}
+	}
+	return nil
+}
+
+func isNoSuchUpload(err error) bool {
+	var noSuchUpload *types.NoSuchUpload
+	if errors.As(err, &noSuchUpload) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchUpload"
+}
+
+func (u *multipartUpload) uploadPart(ctx context.Context, num int32, body io.ReadSeeker, size int64) error {
+	out, err := u.client.UploadPart(ctx, &s3.UploadPartInput{
+		Bucket:        aws.String(u.bucket),
+		Key:           aws.String(u.key),
+		UploadId:      aws.String(u.uploadID),
+		PartNumber:    aws.Int32(num),
+		Body:          progress.ReadSeeker(ctx, bwlimit.ReadSeeker(ctx, body)),
+		ContentLength: aws.Int64(size),
+		// S3 verifies every part against the CRC32C the SDK computes.
+		ChecksumAlgorithm: types.ChecksumAlgorithmCrc32c,
+
+		SSECustomerAlgorithm: u.ssec.algorithm,
+		SSECustomerKey:       u.ssec.key,
+		SSECustomerKeyMD5:    u.ssec.keyMD5,
+	})
+	if err != nil {
+		return fmt.Errorf("Error uploading part %d of s3://%s/%s: %w", num, u.bucket, u.key, err)
+	}
+
+	u.mu.Lock()
+	u.parts = append(u.parts, types.CompletedPart{
+		ETag:           out.ETag,
+		PartNumber:     aws.Int32(num),
+		ChecksumCRC32C: out.ChecksumCRC32C,
+	})
+	u.mu.Unlock()
+
+	if u.checkpoint != nil {
+		return u.checkpoint.PartDone(store.Part{Number: num, ETag: aws.ToString(out.ETag), Size: size})
+	}
+	return nil
+}
+
+func (u *multipartUpload) complete(ctx context.Context) error {
+	sort.Slice(u.parts, func(i, j int) bool {
+		return aws.ToInt32(u.parts[i].PartNumber) < aws.ToInt32(u.parts[j].PartNumber)
+	})
+
+	_, err := u.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        aws.String(u.uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: u.parts},
+
+		SSECustomerAlgorithm: u.ssec.algorithm,
+		SSECustomerKey:       u.ssec.key,
+		SSECustomerKeyMD5:    u.ssec.keyMD5,
+	})
+	if err != nil {
+		return fmt.Errorf("Error completing multipart upload to s3://%s/%s: %w", u.bucket, u.key, err)
+	}
+	return nil
+}
+
+// finish completes the upload if err is nil. Otherwise the upload is
+// aborted, unless it has a checkpoint and can be continued later.
+func (u *multipartUpload) finish(ctx context.Context, err error) error {
+	if err == nil {
+		err = u.complete(ctx)
+	}
+	if err != nil && u.checkpoint == nil {
+		u.abort()
+	}
+	return err
+}
+
+// abort discards the uploaded parts. It uses a fresh context so that the
+// cleanup still happens when ctx was cancelled.
+func (u *multipartUpload) abort() {
+	u.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(u.bucket),
+		Key:      aws.String(u.key),
+		UploadId: aws.String(u.uploadID),
+	})
+}
+
+// uploadStream uploads r as the parts of upload without knowing its
+// length. Parts are read into a fixed pool of buffers, so at most
+// concurrency*partSize bytes are held in memory. Parts finished by an
+// earlier attempt are read and skipped.
+func (s *S3Store) uploadStream(
+	ctx context.Context,
+	upload *multipartUpload, r io.Reader, concurrency int) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	setErr := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	buffers := make(chan []byte, concurrency)
+	for i := 0; i < concurrency; i++ {
+		buffers <- make([]byte, upload.partSize)
+	}
+
+	for num := int32(1); ; num++ {
+		var buf []byte
+		select {
+		case buf = <-buffers:
+		case <-ctx.Done():
+		}
+		if buf == nil {
+			break
+		}
+
+		n, readErr := io.ReadFull(r, buf)
+		if n == 0 && num > 1 {
+			break
+		}
+		if num > maxUploadParts {
+			setErr(fmt.Errorf("s3://%s/%s exceeds %d parts of %d bytes",
+				upload.bucket, upload.key, maxUploadParts, upload.partSize))
+			break
+		}
+
+		if upload.done[num] {
+			buffers <- buf
+		} else {
+			wg.Add(1)
+			go func(num int32, buf []byte, n int) {
+				defer func() {
+					buffers <- buf
+					wg.Done()
+				}()
+				if err := upload.uploadPart(ctx, num, bytes.NewReader(buf[:n]), int64(n)); err != nil {
+					setErr(err)
+				}
+			}(num, buf, n)
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			setErr(fmt.Errorf("Error reading content for s3://%s/%s: %v", upload.bucket, upload.key, readErr))
+			break
+		}
+	}
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// uploadReaderAt uploads size bytes of r as the parts of upload. Parts are
+// read with ReadAt by concurrency workers, so nothing is buffered beyond
+// what the HTTP client needs to send a part. Parts finished by an earlier
+// attempt are not read at all.
+func (s *S3Store) uploadReaderAt(
+	ctx context.Context,
+	upload *multipartUpload, r io.ReaderAt, size int64, concurrency int) error {
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	partNums := make(chan int32)
+	partSize := upload.partSize
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for num := range partNums {
+				offset := int64(num-1) * partSize
+				n := partSize
+				if offset+n > size {
+					n = size - offset
+				}
+				err := upload.uploadPart(ctx, num, io.NewSectionReader(r, offset, n), n)
+				if err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	numParts := int32((size + partSize - 1) / partSize)
+	for num := int32(1); num <= numParts; num++ {
+		if upload.done[num] {
+			continue
+		}
+		select {
+		case partNums <- num:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(partNums)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// copyMultipart copies an object too large for CopyObject with
+// UploadPartCopy, reading the source with the customer key of ctx, if any.
+// The headers and metadata of the source are looked up unless opts
+// replaces them, since a multipart upload does not take them over.
+func (s *S3Store) copyMultipart(
+	ctx context.Context,
+	srcBucket, srcKey, dstBucket, dstKey string, opts store.CopyOptions) error {
+
+	writeOpts := store.WriteOptions{
+		Size:       opts.Size,
+		Encryption: opts.Encryption,
+		Headers:    opts.Headers,
+		Metadata:   opts.Metadata,
+	}
+	if !opts.ReplaceMetadata {
+		info, err := s.Stat(ctx, srcBucket, srcKey)
+		if err != nil {
+			return err
+		}
+		writeOpts.Headers, writeOpts.Metadata = info.Headers, info.Metadata
+	}
+
+	partSize := opts.PartSize
+	if partSize <= 0 {
+		partSize = defaultCopyPartSize
+	}
+	partSize = partSizeFor(partSize, opts.Size)
+	concurrency := opts.PartConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultPartConcurrency
+	}
+	upload, err := s.beginMultipartUpload(ctx, dstBucket, dstKey, partSize, writeOpts)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	source := url.PathEscape(srcBucket + "/" + srcKey)
+	srcSSEC := customerKey(store.CustomerKey(ctx))
+	partNums := make(chan int32)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for num := range partNums {
+				start := int64(num-1) * partSize
+				end := start + partSize - 1
+				if end >= opts.Size {
+					end = opts.Size - 1
+				}
+				if err := upload.copyPart(ctx, num, source, srcSSEC, start, end); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	numParts := int32((opts.Size + partSize - 1) / partSize)
+	for num := int32(1); num <= numParts; num++ {
+		select {
+		case partNums <- num:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(partNums)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if err := upload.finish(ctx, firstErr); err != nil {
+		return fmt.Errorf(
+			"Error copying s3://%s/%s to s3://%s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, err)
+	}
+	return nil
+}
+
+// copyPart copies the bytes start to end of source as a part of the upload.
+func (u *multipartUpload) copyPart(
+	ctx context.Context,
+	num int32, source string, srcSSEC sseCustomer, start, end int64) error {
+
+	out, err := u.client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+		Bucket:          aws.String(u.bucket),
+		Key:             aws.String(u.key),
+		UploadId:        aws.String(u.uploadID),
+		PartNumber:      aws.Int32(num),
+		CopySource:      aws.String(source),
+		CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+
+		SSECustomerAlgorithm:           u.ssec.algorithm,
+		SSECustomerKey:                 u.ssec.key,
+		SSECustomerKeyMD5:              u.ssec.keyMD5,
+		CopySourceSSECustomerAlgorithm: srcSSEC.algorithm,
+		CopySourceSSECustomerKey:       srcSSEC.key,
+		CopySourceSSECustomerKeyMD5:    srcSSEC.keyMD5,
+	})
+	if err != nil {
+		return fmt.Errorf("Error copying part %d of s3://%s/%s: %w", num, u.bucket, u.key, err)
+	}
+	part := types.CompletedPart{PartNumber: aws.Int32(num)}
+	if out.CopyPartResult != nil {
+		part.ETag = out.CopyPartResult.ETag
+		part.ChecksumCRC32C = out.CopyPartResult.ChecksumCRC32C
+	}
+	u.mu.Lock()
+	u.parts = append(u.parts, part)
+	u.mu.Unlock()
+	return nil
+}
+
+// partSizeFor grows the part size so that an object of the given size fits
+// in the S3 limit of 10,000 parts.
+func partSizeFor(partSize, size int64) int64 {
+	if partSize < minPartSize {
+		partSize = minPartSize
+	}
+	if size > 0 && (size+partSize-1)/partSize > maxUploadParts {
+		partSize = (size + maxUploadParts - 1) / maxUploadParts
+	}
+	return partSize
+}
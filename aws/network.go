@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/RA-Balaji/storage-synk/dryrun"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -26,6 +27,10 @@ func newEC2Client(region string) (*ec2.Client, error) {
 }
 
 func VPCCreate(ctx context.Context, region, bucketName string) error {
+	if dryrun.Report(ctx, dryrun.Create, "VPC "+getVpcName(bucketName)+" ("+vpcCidr+") in "+region) {
+		return nil
+	}
+
 	client, err := newEC2Client(region)
 	if err != nil {
 		return fmt.Errorf("Error creating ec2 client: %v", err)
@@ -67,6 +72,10 @@ func getVpcName(bucketName string) string {
 }
 
 func UpdateVpcAttribute(ctx context.Context, region, vpcID string) error {
+	if dryrun.Report(ctx, dryrun.Update, "DNS support and hostnames of VPC "+vpcID) {
+		return nil
+	}
+
 	client, err := newEC2Client(region)
 	if err != nil {
 		return fmt.Errorf("Error creating ec2 client: %v", err)
@@ -93,6 +102,10 @@ func UpdateVpcAttribute(ctx context.Context, region, vpcID string) error {
 }
 
 func SubnetCreate(ctx context.Context, region, bucketName string, subnetZones []string) error {
+	if dryrun.Report(ctx, dryrun.Create, fmt.Sprintf("subnets of %s in %v", getVpcName(bucketName), subnetZones)) {
+		return nil
+	}
+
 	client, err := newEC2Client(region)
 	if err != nil {
 		return fmt.Errorf("Error creating ec2 client: %v", err)
@@ -154,6 +167,10 @@ func getSubnet(ctx context.Context, region, bucketName string) ([]types.Subnet,
 }
 
 func VPCEndpointCreate(ctx context.Context, region, bucketName string) (types.VpcEndpoint, error) {
+	if dryrun.Report(ctx, dryrun.Create, "VPC endpoint "+getVpcEpName(bucketName)+" for "+getVPCEndpointServiceName(region)) {
+		return types.VpcEndpoint{}, nil
+	}
+
 	client, err := newEC2Client(region)
 	if err != nil {
 		return types.VpcEndpoint{}, fmt.Errorf("Error creating ec2 client: %v", err)
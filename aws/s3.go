@@ -1,16 +1,12 @@
 package aws
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"os"
-	"path/filepath"
-	"strings"
-	"sync"
 
-	"github.com/RA-Balaji/storage-synk/utils"
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/store"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
@@ -18,16 +14,34 @@ import (
 )
 
 func newS3Client(ctx context.Context, profile string) (*s3.Client, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithSharedConfigProfile(profile))
+	return newS3ClientWith(ctx, S3Options{Profile: profile})
+}
+
+func newS3ClientWith(ctx context.Context, opts S3Options) (*s3.Client, error) {
+	loadOpts := []func(*config.LoadOptions) error{config.WithSharedConfigProfile(opts.Profile)}
+	if opts.Region != "" {
+		loadOpts = append(loadOpts, config.WithRegion(opts.Region))
+	}
+	cfg, err := config.LoadDefaultConfig(context.TODO(), loadOpts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return s3.NewFromConfig(cfg), nil
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if opts.Endpoint != "" {
+			// S3 compatible services rarely support virtual hosted
+			// buckets.
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
 }
 
 func S3BucketCreate(ctx context.Context, profile, bucketName string) error {
+	if dryrun.Report(ctx, dryrun.Create, "S3 bucket "+bucketName) {
+		return nil
+	}
+
 	client, err := newS3Client(ctx, profile)
 	if err != nil {
 		return fmt.Errorf("Error initializing s3client: %v", err)
@@ -71,6 +85,10 @@ func S3BucketGet(ctx context.Context, profile, bucketName string) (*types.Bucket
 }
 
 func S3BucketDelete(ctx context.Context, profile, bucketName string) error {
+	if dryrun.Report(ctx, dryrun.Delete, "S3 bucket "+bucketName) {
+		return nil
+	}
+
 	client, err := newS3Client(ctx, profile)
 	if err != nil {
 		return fmt.Errorf("Error initializing s3client: %v", err)
@@ -88,10 +106,14 @@ func S3BucketDelete(ctx context.Context, profile, bucketName string) error {
 	return nil
 }
 
-func S3FileUpload(ctx context.Context, profile, bucketName, fileName, key string) error {
-	client, err := newS3Client(ctx, profile)
+// S3FileUpload uploads one file, encrypted at rest as enc asks.
+func S3FileUpload(
+	ctx context.Context,
+	profile, bucketName, fileName, key string, enc store.Encryption) error {
+
+	s3Store, err := NewS3Store(ctx, S3Options{Profile: profile})
 	if err != nil {
-		return fmt.Errorf("Error initializing s3client: %v", err)
+		return err
 	}
 
 	// Open the file
@@ -101,18 +123,13 @@ func S3FileUpload(ctx context.Context, profile, bucketName, fileName, key string
 	}
 	defer file.Close()
 
-	// TODO: Use Multi-part upload for files > 100 Mb
-	var buffer bytes.Buffer
-	if _, err := io.Copy(&buffer, file); err != nil {
-		fmt.Errorf("Error reading file: [%v]", err)
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("Error reading file: [%v]", err)
 	}
 
-	// Upload the file to S3
-	_, err = client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(buffer.Bytes()),
-	})
+	// Large files are uploaded in parallel parts read straight from the file
+	err = s3Store.Write(ctx, bucketName, key, file, store.WriteOptions{Size: info.Size(), Encryption: enc})
 	if err != nil {
 		return fmt.Errorf(
 			"Error Uploading file to S3 bucket [%s], File [%s]: %v",
@@ -121,52 +138,3 @@ func S3FileUpload(ctx context.Context, profile, bucketName, fileName, key string
 
 	return nil
 }
-
-func S3FolderUpload(
-	ctx context.Context,
-	region, bucketName, folderName string,
-	wg *sync.WaitGroup, sem chan struct{}) error {
-
-	err := filepath.Walk(folderName, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// If it's a directory, just continue
-		if info.IsDir() {
-			return nil
-		}
-
-		sem <- struct{}{} // Acquire semaphore
-		wg.Add(1)
-
-		go func(path string) error {
-			defer func() {
-				wg.Done()
-				<-sem
-			}()
-
-			key := path[len(folderName)+1:]
-			// To convert '\' to '/'
-			if utils.IsWindowsOS() {
-				key = convKeyToS3Format(key)
-			}
-			if err := S3FileUpload(ctx, region, bucketName, path, key); err != nil {
-				return err
-			}
-			return nil
-		}(path)
-
-		return nil
-	})
-	if err != nil {
-		return fmt.Errorf("Error Uploading folder [%s]", folderName)
-	}
-
-	return nil
-}
-
-func convKeyToS3Format(key string) string {
-	parts := strings.Split(key, "\\")
-	return strings.Join(parts, "/")
-}
@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// sseCustomer holds the SSE-C request fields for a customer key: the
+// algorithm, the base64 key and the base64 MD5 of the key. All are nil
+// without a key.
+type sseCustomer struct {
+	algorithm, key, keyMD5 *string
+}
+
+func customerKey(key []byte) sseCustomer {
+	if key == nil {
+		return sseCustomer{}
+	}
+	sum := md5.Sum(key)
+	return sseCustomer{
+		algorithm: aws.String("AES256"),
+		key:       aws.String(base64.StdEncoding.EncodeToString(key)),
+		keyMD5:    aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+	}
+}
+
+// serverSide returns the SSE-S3 or SSE-KMS fields of e.
+func serverSide(e store.Encryption) (types.ServerSideEncryption, *string) {
+	switch e.Mode {
+	case store.EncryptionManaged:
+		return types.ServerSideEncryptionAes256, nil
+	case store.EncryptionKMS:
+		if e.KMSKey == "" {
+			return types.ServerSideEncryptionAwsKms, nil
+		}
+		return types.ServerSideEncryptionAwsKms, aws.String(e.KMSKey)
+	}
+	return "", nil
+}
+
+// writeCustomerKey returns the SSE-C fields for writing with e.
+func writeCustomerKey(e store.Encryption) sseCustomer {
+	if e.Mode != store.EncryptionCustomer {
+		return sseCustomer{}
+	}
+	return customerKey(e.CustomerKey)
+}
+
+// describeEncryption formats the encryption S3 reports for an object.
+func describeEncryption(sse types.ServerSideEncryption, kmsKey, customerAlgorithm *string) string {
+	switch {
+	case customerAlgorithm != nil:
+		return "SSE-C"
+	case sse == types.ServerSideEncryptionAwsKms || sse == types.ServerSideEncryptionAwsKmsDsse:
+		name := "SSE-KMS"
+		if sse == types.ServerSideEncryptionAwsKmsDsse {
+			name = "DSSE-KMS"
+		}
+		if kmsKey != nil {
+			return name + " " + *kmsKey
+		}
+		return name
+	case sse == types.ServerSideEncryptionAes256:
+		return "SSE-S3"
+	}
+	return ""
+}
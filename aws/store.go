@@ -0,0 +1,405 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/RA-Balaji/storage-synk/bwlimit"
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// S3Store implements store.ObjectStore on top of a single S3 client.
+type S3Store struct {
+	client *s3.Client
+}
+
+var (
+	_ store.ObjectStore    = (*S3Store)(nil)
+	_ store.VersionedStore = (*S3Store)(nil)
+	_ store.BucketLister   = (*S3Store)(nil)
+	_ store.BatchDeleter   = (*S3Store)(nil)
+)
+
+// S3Options configures the S3 client. Empty fields keep the defaults of
+// the AWS shared config and environment.
+type S3Options struct {
+	Profile string
+	Region  string
+	// Endpoint is the URL of an S3 compatible service; buckets are then
+	// addressed by path.
+	Endpoint string
+}
+
+func NewS3Store(ctx context.Context, opts S3Options) (*S3Store, error) {
+	client, err := newS3ClientWith(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("Error initializing s3client: %w", err)
+	}
+	return &S3Store{client: client}, nil
+}
+
+func (s *S3Store) List(
+	ctx context.Context,
+	bucket, prefix string,
+	opts store.ListOptions, fn store.WalkFunc) error {
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}
+	if !opts.Recursive {
+		input.Delimiter = aws.String("/")
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("Error listing objects in S3 bucket [%s]: %w", bucket, err)
+		}
+		for _, p := range page.CommonPrefixes {
+			if err := fn(store.ObjectInfo{Key: aws.ToString(p.Prefix), IsPrefix: true}); err != nil {
+				return err
+			}
+		}
+		for _, obj := range page.Contents {
+			if err := fn(s3ObjectInfo(obj)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListBuckets lists the buckets owned by the account. S3 returns them in
+// a single response; their regions are not looked up.
+func (s *S3Store) ListBuckets(ctx context.Context, fn func(store.BucketInfo) error) error {
+	out, err := s.client.ListBuckets(ctx, &s3.ListBucketsInput{})
+	if err != nil {
+		return fmt.Errorf("Error listing S3 buckets: %w", err)
+	}
+	for _, b := range out.Buckets {
+		if err := fn(store.BucketInfo{Name: aws.ToString(b.Name), Created: aws.ToTime(b.CreationDate)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3Store) Stat(ctx context.Context, bucket, key string) (store.ObjectInfo, error) {
+	return s.StatVersion(ctx, bucket, key, "")
+}
+
+func (s *S3Store) StatVersion(ctx context.Context, bucket, key, version string) (store.ObjectInfo, error) {
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if version != "" {
+		input.VersionId = aws.String(version)
+	}
+	ssec := customerKey(store.CustomerKey(ctx))
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = ssec.algorithm, ssec.key, ssec.keyMD5
+	input.ChecksumMode = types.ChecksumModeEnabled
+
+	out, err := s.client.HeadObject(ctx, input)
+	if err != nil {
+		return store.ObjectInfo{}, s3Error(bucket, key, err)
+	}
+
+	// The ETag of objects encrypted with SSE-KMS or SSE-C is not an MD5.
+	md5 := etagMD5(aws.ToString(out.ETag))
+	if out.SSECustomerAlgorithm != nil || out.ServerSideEncryption == types.ServerSideEncryptionAwsKms ||
+		out.ServerSideEncryption == types.ServerSideEncryptionAwsKmsDsse {
+		md5 = ""
+	}
+
+	return store.ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ModTime:      aws.ToTime(out.LastModified),
+		ETag:         aws.ToString(out.ETag),
+		MD5:          md5,
+		CRC32C:       checksum.FromBase64(aws.ToString(out.ChecksumCRC32C)),
+		SHA256:       checksum.FromBase64(aws.ToString(out.ChecksumSHA256)),
+		StorageClass: string(out.StorageClass),
+		Headers: store.Headers{
+			ContentType:        aws.ToString(out.ContentType),
+			CacheControl:       aws.ToString(out.CacheControl),
+			ContentEncoding:    aws.ToString(out.ContentEncoding),
+			ContentDisposition: aws.ToString(out.ContentDisposition),
+		},
+		Metadata:   out.Metadata,
+		Encryption: describeEncryption(out.ServerSideEncryption, out.SSEKMSKeyId, out.SSECustomerAlgorithm),
+		VersionID:  version,
+	}, nil
+}
+
+func (s *S3Store) Open(
+	ctx context.Context,
+	bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	return s.OpenVersion(ctx, bucket, key, "", offset, length)
+}
+
+func (s *S3Store) OpenVersion(
+	ctx context.Context,
+	bucket, key, version string, offset, length int64) (io.ReadCloser, error) {
+
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	if offset > 0 || length >= 0 {
+		input.Range = aws.String(byteRange(offset, length))
+	}
+	if version != "" {
+		input.VersionId = aws.String(version)
+	}
+	ssec := customerKey(store.CustomerKey(ctx))
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = ssec.algorithm, ssec.key, ssec.keyMD5
+
+	out, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		return nil, s3Error(bucket, key, err)
+	}
+	return out.Body, nil
+}
+
+// Write uses a single PutObject for objects that fit in one part and a
+// multipart upload for everything else. Readers that implement io.ReaderAt,
+// such as local files, have their parts read and uploaded in parallel;
+// other readers of unknown length are streamed through bounded buffers.
+func (s *S3Store) Write(
+	ctx context.Context,
+	bucket, key string, r io.Reader, opts store.WriteOptions) error {
+
+	partSize := opts.PartSize
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+	partSize = partSizeFor(partSize, opts.Size)
+	concurrency := opts.PartConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultPartConcurrency
+	}
+
+	if opts.Size < 0 || opts.Size > partSize {
+		upload, err := s.beginMultipartUpload(ctx, bucket, key, partSize, opts)
+		if err != nil {
+			return err
+		}
+		if ra, ok := r.(io.ReaderAt); ok && opts.Size > 0 {
+			err = s.uploadReaderAt(ctx, upload, ra, opts.Size, concurrency)
+		} else {
+			err = s.uploadStream(ctx, upload, r, concurrency)
+		}
+		return upload.finish(ctx, err)
+	}
+
+	// PutObject needs a seekable body to sign the request; small bodies
+	// are buffered, which is bounded by the part size.
+	body, ok := r.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(io.LimitReader(r, opts.Size))
+		if err != nil {
+			return fmt.Errorf("Error reading content for s3://%s/%s: %w", bucket, key, err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	sse, kmsKey := serverSide(opts.Encryption)
+	ssec := writeCustomerKey(opts.Encryption)
+	input := &s3.PutObjectInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		Body:                 progress.ReadSeeker(ctx, bwlimit.ReadSeeker(ctx, body)),
+		ContentLength:        aws.Int64(opts.Size),
+		ContentType:          optional(opts.ContentType),
+		CacheControl:         optional(opts.CacheControl),
+		ContentEncoding:      optional(opts.ContentEncoding),
+		ContentDisposition:   optional(opts.ContentDisposition),
+		Metadata:             opts.Metadata,
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKey,
+		SSECustomerAlgorithm: ssec.algorithm,
+		SSECustomerKey:       ssec.key,
+		SSECustomerKeyMD5:    ssec.keyMD5,
+	}
+	// S3 verifies the content against the CRC32C of the source, or else
+	// against one the SDK computes from the body.
+	if opts.CRC32C != "" {
+		input.ChecksumCRC32C = aws.String(checksum.ToBase64(opts.CRC32C))
+	} else {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32c
+	}
+	_, err := s.client.PutObject(ctx, input)
+	if err != nil {
+		return fmt.Errorf("Error Uploading to S3 bucket [%s], Key [%s]: %w", bucket, key, err)
+	}
+	return nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, bucket, key string) error {
+	return s.DeleteVersion(ctx, bucket, key, "")
+}
+
+// DeleteVersion removes one version of an object for good. Without a
+// version, a versioned bucket keeps the object behind a delete marker.
+func (s *S3Store) DeleteVersion(ctx context.Context, bucket, key, version string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:    aws.String(bucket),
+		Key:       aws.String(key),
+		VersionId: optional(version),
+	})
+	if err != nil {
+		return fmt.Errorf("Error deleting s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// maxDeleteKeys is the most keys S3 accepts in one DeleteObjects request.
+const maxDeleteKeys = 1000
+
+// DeleteBatch deletes keys with DeleteObjects requests of up to 1000 keys
+// each. When a request fails as a whole, each of its keys gets its error.
+func (s *S3Store) DeleteBatch(ctx context.Context, bucket string, keys []string) map[string]error {
+	failed := map[string]error{}
+	for start := 0; start < len(keys); start += maxDeleteKeys {
+		end := start + maxDeleteKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			for _, key := range keys[start:end] {
+				failed[key] = fmt.Errorf("Error deleting s3://%s/%s: %w", bucket, key, err)
+			}
+			continue
+		}
+		for _, e := range out.Errors {
+			key := aws.ToString(e.Key)
+			failed[key] = fmt.Errorf("Error deleting s3://%s/%s: %w", bucket, key, &smithy.GenericAPIError{
+				Code:    aws.ToString(e.Code),
+				Message: aws.ToString(e.Message),
+			})
+		}
+	}
+	return failed
+}
+
+// Copy reads the source with the customer key of ctx, if any, and encrypts
+// the copy as opts ask.
+func (s *S3Store) Copy(
+	ctx context.Context,
+	srcBucket, srcKey, dstBucket, dstKey string, opts store.CopyOptions) error {
+
+	if opts.Size > maxCopySize {
+		return s.copyMultipart(ctx, srcBucket, srcKey, dstBucket, dstKey, opts)
+	}
+	sse, kmsKey := serverSide(opts.Encryption)
+	ssec := writeCustomerKey(opts.Encryption)
+	srcSSEC := customerKey(store.CustomerKey(ctx))
+	input := &s3.CopyObjectInput{
+		Bucket:                         aws.String(dstBucket),
+		Key:                            aws.String(dstKey),
+		CopySource:                     aws.String(url.PathEscape(srcBucket + "/" + srcKey)),
+		ServerSideEncryption:           sse,
+		SSEKMSKeyId:                    kmsKey,
+		SSECustomerAlgorithm:           ssec.algorithm,
+		SSECustomerKey:                 ssec.key,
+		SSECustomerKeyMD5:              ssec.keyMD5,
+		CopySourceSSECustomerAlgorithm: srcSSEC.algorithm,
+		CopySourceSSECustomerKey:       srcSSEC.key,
+		CopySourceSSECustomerKeyMD5:    srcSSEC.keyMD5,
+	}
+	// Without the REPLACE directive the copy keeps the system and user
+	// metadata of the source.
+	if opts.ReplaceMetadata {
+		input.MetadataDirective = types.MetadataDirectiveReplace
+		input.ContentType = optional(opts.Headers.ContentType)
+		input.CacheControl = optional(opts.Headers.CacheControl)
+		input.ContentEncoding = optional(opts.Headers.ContentEncoding)
+		input.ContentDisposition = optional(opts.Headers.ContentDisposition)
+		input.Metadata = opts.Metadata
+	}
+	_, err := s.client.CopyObject(ctx, input)
+	if err != nil {
+		return fmt.Errorf(
+			"Error copying s3://%s/%s to s3://%s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, err)
+	}
+	return nil
+}
+
+func s3ObjectInfo(obj types.Object) store.ObjectInfo {
+	return store.ObjectInfo{
+		Key:          aws.ToString(obj.Key),
+		Size:         aws.ToInt64(obj.Size),
+		ModTime:      aws.ToTime(obj.LastModified),
+		ETag:         aws.ToString(obj.ETag),
+		MD5:          etagMD5(aws.ToString(obj.ETag)),
+		StorageClass: string(obj.StorageClass),
+		// ListObjectsV2 does not return the headers and user metadata.
+		Partial: true,
+	}
+}
+
+// optional returns nil for an empty string, which leaves the field out of
+// the request.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+// etagMD5 returns the MD5 an ETag stands for. Multipart ETags ("<hash>-<parts>")
+// are not the MD5 of the content.
+func etagMD5(etag string) string {
+	etag = strings.Trim(etag, `"`)
+	if len(etag) != 32 || strings.Contains(etag, "-") {
+		return ""
+	}
+	return etag
+}
+
+func s3Error(bucket, key string, err error) error {
+	var noKey *types.NoSuchKey
+	var notFound *types.NotFound
+	if errors.As(err, &noKey) || errors.As(err, &notFound) {
+		return fmt.Errorf("s3://%s/%s: %w", bucket, key, store.ErrNotExist)
+	}
+	return fmt.Errorf("Error reading s3://%s/%s: %w", bucket, key, err)
+}
+
+// byteRange formats an HTTP Range header value. A negative length means
+// "until the end of the object".
+func byteRange(offset, length int64) string {
+	if length < 0 {
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
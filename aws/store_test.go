@@ -0,0 +1,526 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a minimal path-style S3 endpoint for offline tests. It supports
+// the object and multipart calls used by S3Store.
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	uploads  map[string]map[int][]byte
+	nextID   int
+	aborted  int
+	failPart int
+	partPuts int
+	headers  []http.Header
+	// denied keys fail in DeleteObjects; batches counts its requests.
+	denied  map[string]bool
+	batches []int
+	// versions lists the version IDs of DeleteObject requests.
+	versions []string
+}
+
+func newFakeS3Store(t *testing.T) (*S3Store, *fakeS3) {
+	fake := &fakeS3{objects: map[string][]byte{}, uploads: map[string]map[int][]byte{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	client := s3.New(s3.Options{
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Region:       "us-east-1",
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	return &S3Store{client: client}, fake
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	f.headers = append(f.headers, r.Header)
+
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.nextID++
+		id := fmt.Sprintf("upload-%d", f.nextID)
+		f.uploads[id] = map[int][]byte{}
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, id)
+
+	case r.Method == http.MethodPut && query.Has("uploadId") && r.Header.Get("X-Amz-Copy-Source") != "":
+		num, _ := strconv.Atoi(query.Get("partNumber"))
+		src, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		data := f.objects[strings.TrimPrefix(src, "/")]
+		var start, end int
+		fmt.Sscanf(r.Header.Get("X-Amz-Copy-Source-Range"), "bytes=%d-%d", &start, &end)
+		if end >= len(data) {
+			end = len(data) - 1
+		}
+		if start < len(data) {
+			f.uploads[query.Get("uploadId")][num] = data[start : end+1]
+		}
+		fmt.Fprintf(w, `<CopyPartResult><ETag>"etag-%d"</ETag></CopyPartResult>`, num)
+
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		num, _ := strconv.Atoi(query.Get("partNumber"))
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok || num == f.failPart {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.partPuts++
+		parts[num] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"etag-%d"`, num))
+
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var req struct {
+			Parts []struct {
+				PartNumber int
+			} `xml:"Part"`
+		}
+		xml.Unmarshal(body, &req)
+		parts := f.uploads[query.Get("uploadId")]
+		var data []byte
+		for _, p := range req.Parts {
+			data = append(data, parts[p.PartNumber]...)
+		}
+		f.objects[path] = data
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprint(w, `<CompleteMultipartUploadResult><ETag>"done"</ETag></CompleteMultipartUploadResult>`)
+
+	case r.Method == http.MethodPost && query.Has("delete"):
+		var req struct {
+			Objects []struct {
+				Key string
+			} `xml:"Object"`
+		}
+		xml.Unmarshal(body, &req)
+		f.batches = append(f.batches, len(req.Objects))
+		bucket := strings.SplitN(path, "/", 2)[0]
+		fmt.Fprint(w, `<DeleteResult>`)
+		for _, obj := range req.Objects {
+			if f.denied[obj.Key] {
+				fmt.Fprintf(w, `<Error><Key>%s</Key><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`, obj.Key)
+				continue
+			}
+			delete(f.objects, bucket+"/"+obj.Key)
+		}
+		fmt.Fprint(w, `</DeleteResult>`)
+
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		f.aborted++
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchUpload</Code></Error>`)
+			return
+		}
+		fmt.Fprint(w, `<ListPartsResult>`)
+		for num, data := range parts {
+			fmt.Fprintf(w, `<Part><PartNumber>%d</PartNumber><ETag>"etag-%d"</ETag><Size>%d</Size></Part>`,
+				num, num, len(data))
+		}
+		fmt.Fprint(w, `</ListPartsResult>`)
+
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		src, _ := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+		f.objects[path] = f.objects[strings.TrimPrefix(src, "/")]
+		fmt.Fprint(w, `<CopyObjectResult><ETag>"copy"</ETag></CopyObjectResult>`)
+
+	case r.Method == http.MethodPut:
+		f.objects[path] = body
+
+	case r.Method == http.MethodHead || r.Method == http.MethodGet:
+		data, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			}
+			return
+		}
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		if rng := r.Header.Get("Range"); rng != "" {
+			var start, end int
+			if n, _ := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); n < 2 {
+				end = len(data) - 1
+			}
+			if end >= len(data) {
+				end = len(data) - 1
+			}
+			data = data[start : end+1]
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(f.objects[path])))
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		}
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+
+	case r.Method == http.MethodDelete:
+		if query.Has("versionId") {
+			f.versions = append(f.versions, query.Get("versionId"))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		delete(f.objects, path)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func (f *fakeS3) object(bucket, key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.objects[bucket+"/"+key]
+	return data, ok
+}
+
+// onlyReader hides io.Seeker and io.ReaderAt, like a network stream.
+type onlyReader struct{ io.Reader }
+
+func testContent(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestS3StoreWriteSmallObject(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	content := []byte("small object")
+
+	err := s.Write(context.Background(), "bucket", "small.txt", onlyReader{bytes.NewReader(content)},
+		store.WriteOptions{Size: int64(len(content))})
+	assert.NoError(t, err)
+
+	data, ok := fake.object("bucket", "small.txt")
+	assert.True(t, ok)
+	assert.Equal(t, content, data)
+}
+
+func TestS3StoreStreamsUnknownSizeAsMultipart(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	content := testContent(2*minPartSize + 123)
+
+	err := s.Write(context.Background(), "bucket", "big.bin", onlyReader{bytes.NewReader(content)},
+		store.WriteOptions{Size: -1})
+	assert.NoError(t, err)
+
+	data, ok := fake.object("bucket", "big.bin")
+	assert.True(t, ok)
+	assert.Equal(t, content, data)
+	assert.Empty(t, fake.uploads)
+}
+
+func TestS3StoreAbortsFailedMultipart(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	fake.failPart = 2
+	content := testContent(3 * minPartSize)
+
+	err := s.Write(context.Background(), "bucket", "broken.bin", onlyReader{bytes.NewReader(content)},
+		store.WriteOptions{Size: -1})
+	assert.Error(t, err)
+
+	_, ok := fake.object("bucket", "broken.bin")
+	assert.False(t, ok)
+	assert.Equal(t, 1, fake.aborted)
+}
+
+func TestS3StoreUploadsReaderAtPartsInParallel(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	content := testContent(3*minPartSize + 7)
+
+	err := s.Write(context.Background(), "bucket", "file.bin", bytes.NewReader(content),
+		store.WriteOptions{Size: int64(len(content)), PartSize: minPartSize, PartConcurrency: 3})
+	assert.NoError(t, err)
+
+	data, ok := fake.object("bucket", "file.bin")
+	assert.True(t, ok)
+	assert.Equal(t, content, data)
+	assert.Empty(t, fake.uploads)
+}
+
+func TestS3StoreAbortsFailedReaderAtUpload(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	fake.failPart = 3
+	content := testContent(4 * minPartSize)
+
+	err := s.Write(context.Background(), "bucket", "broken.bin", bytes.NewReader(content),
+		store.WriteOptions{Size: int64(len(content)), PartSize: minPartSize})
+	assert.ErrorContains(t, err, "part 3")
+
+	_, ok := fake.object("bucket", "broken.bin")
+	assert.False(t, ok)
+	assert.Equal(t, 1, fake.aborted)
+	assert.Empty(t, fake.uploads)
+}
+
+// memCheckpoint keeps the checkpoint of a single upload in memory.
+type memCheckpoint struct {
+	mu    sync.Mutex
+	state *store.UploadState
+}
+
+func (c *memCheckpoint) Resume() (store.UploadState, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == nil {
+		return store.UploadState{}, false
+	}
+	return *c.state, true
+}
+
+func (c *memCheckpoint) Started(state store.UploadState) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state = &state
+	return nil
+}
+
+func (c *memCheckpoint) PartDone(part store.Part) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.state.Parts = append(c.state.Parts, part)
+	return nil
+}
+
+func TestS3StoreResumesMultipartUpload(t *testing.T) {
+	for name, reader := range map[string]func([]byte) io.Reader{
+		"ReaderAt": func(b []byte) io.Reader { return bytes.NewReader(b) },
+		"Stream":   func(b []byte) io.Reader { return onlyReader{bytes.NewReader(b)} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, fake := newFakeS3Store(t)
+			fake.failPart = 3
+			content := testContent(4*minPartSize + 10)
+			checkpoint := &memCheckpoint{}
+			opts := store.WriteOptions{
+				Size:            int64(len(content)),
+				PartSize:        minPartSize,
+				PartConcurrency: 1,
+				Checkpoint:      checkpoint,
+			}
+
+			err := s.Write(context.Background(), "bucket", "big.bin", reader(content), opts)
+			assert.Error(t, err)
+			// The upload is kept so that it can be continued.
+			assert.Equal(t, 0, fake.aborted)
+			assert.Len(t, fake.uploads, 1)
+			assert.Len(t, checkpoint.state.Parts, 2)
+
+			fake.failPart = 0
+			fake.partPuts = 0
+			err = s.Write(context.Background(), "bucket", "big.bin", reader(content), opts)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, fake.partPuts)
+
+			data, _ := fake.object("bucket", "big.bin")
+			assert.Equal(t, content, data)
+		})
+	}
+}
+
+func TestS3StoreRestartsExpiredUpload(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	content := testContent(2*minPartSize + 1)
+	checkpoint := &memCheckpoint{state: &store.UploadState{UploadID: "gone", PartSize: minPartSize}}
+
+	err := s.Write(context.Background(), "bucket", "big.bin", bytes.NewReader(content),
+		store.WriteOptions{Size: int64(len(content)), PartSize: minPartSize, Checkpoint: checkpoint})
+	assert.NoError(t, err)
+	assert.NotEqual(t, "gone", checkpoint.state.UploadID)
+
+	data, _ := fake.object("bucket", "big.bin")
+	assert.Equal(t, content, data)
+}
+
+func TestS3StoreOpenRangeAndStat(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	fake.objects["bucket/obj.txt"] = []byte("0123456789")
+	ctx := context.Background()
+
+	r, err := s.Open(ctx, "bucket", "obj.txt", 2, 3)
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "234", string(data))
+
+	info, err := s.Stat(ctx, "bucket", "obj.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), info.Size)
+
+	_, err = s.Stat(ctx, "bucket", "missing.txt")
+	assert.True(t, store.IsNotExist(err))
+}
+
+func TestS3StoreSendsEncryption(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	key := bytes.Repeat([]byte{1}, 32)
+	content := testContent(2*minPartSize + 1)
+
+	err := s.Write(context.Background(), "bucket", "big.bin", bytes.NewReader(content),
+		store.WriteOptions{
+			Size:       int64(len(content)),
+			Encryption: store.Encryption{Mode: store.EncryptionCustomer, CustomerKey: key},
+		})
+	assert.NoError(t, err)
+	// Create, two parts and complete all carry the key.
+	assert.Len(t, fake.headers, 4)
+	for _, h := range fake.headers {
+		assert.Equal(t, "AES256", h.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm"))
+		assert.NotEmpty(t, h.Get("X-Amz-Server-Side-Encryption-Customer-Key-Md5"))
+	}
+
+	fake.headers = nil
+	arn := "arn:aws:kms:us-east-1:123456789012:key/abc"
+	err = s.Write(context.Background(), "bucket", "small.txt", bytes.NewReader([]byte("small")),
+		store.WriteOptions{Size: 5, Encryption: store.Encryption{Mode: store.EncryptionKMS, KMSKey: arn}})
+	assert.NoError(t, err)
+	assert.Equal(t, "aws:kms", fake.headers[0].Get("X-Amz-Server-Side-Encryption"))
+	assert.Equal(t, arn, fake.headers[0].Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+
+	// Reads send the customer key of the context.
+	fake.headers = nil
+	_, err = s.Stat(store.WithCustomerKey(context.Background(), key), "bucket", "big.bin")
+	assert.NoError(t, err)
+	assert.Equal(t, "AES256", fake.headers[0].Get("X-Amz-Server-Side-Encryption-Customer-Algorithm"))
+}
+
+func TestPartSizeFor(t *testing.T) {
+	assert.Equal(t, int64(minPartSize), partSizeFor(1024, -1))
+	assert.Equal(t, int64(defaultPartSize), partSizeFor(defaultPartSize, 1<<30))
+
+	huge := int64(defaultPartSize) * maxUploadParts * 2
+	size := partSizeFor(defaultPartSize, huge)
+	assert.LessOrEqual(t, (huge+size-1)/size, int64(maxUploadParts))
+}
+
+func TestS3StoreSendsChecksum(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	err := s.Write(context.Background(), "bucket", "small.txt", bytes.NewReader([]byte("hello world")),
+		store.WriteOptions{Size: 11, CRC32C: "c99465aa"})
+	assert.NoError(t, err)
+	assert.Equal(t, "yZRlqg==", fake.headers[0].Get("X-Amz-Checksum-Crc32c"))
+}
+
+func TestS3StoreSendsHeaders(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	headers := store.Headers{ContentType: "text/html", CacheControl: "max-age=60"}
+	err := s.Write(context.Background(), "bucket", "index.html", bytes.NewReader([]byte("<p>")),
+		store.WriteOptions{Size: 3, Headers: headers, Metadata: map[string]string{"owner": "web"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html", fake.headers[0].Get("Content-Type"))
+	assert.Equal(t, "max-age=60", fake.headers[0].Get("Cache-Control"))
+	assert.Equal(t, "web", fake.headers[0].Get("X-Amz-Meta-Owner"))
+
+	// Copies keep the metadata of the source unless it is replaced.
+	fake.headers = nil
+	err = s.Copy(context.Background(), "bucket", "index.html", "bucket", "copy.html", store.CopyOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, fake.headers[0].Get("X-Amz-Metadata-Directive"))
+
+	fake.headers = nil
+	err = s.Copy(context.Background(), "bucket", "index.html", "bucket", "copy.html", store.CopyOptions{
+		ReplaceMetadata: true, Headers: headers, Metadata: map[string]string{"team": "data"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "REPLACE", fake.headers[0].Get("X-Amz-Metadata-Directive"))
+	assert.Equal(t, "text/html", fake.headers[0].Get("Content-Type"))
+	assert.Equal(t, "data", fake.headers[0].Get("X-Amz-Meta-Team"))
+}
+
+func TestS3StoreCopiesLargeObjectsInParts(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	content := testContent(2*minPartSize + 1)
+	fake.objects["bucket/big.bin"] = content
+
+	err := s.Copy(context.Background(), "bucket", "big.bin", "bucket", "copy.bin",
+		store.CopyOptions{Size: int64(len(content)), PartSize: minPartSize})
+	assert.NoError(t, err)
+	assert.Equal(t, content, fake.objects["bucket/copy.bin"])
+	for _, h := range fake.headers {
+		assert.Empty(t, h.Get("X-Amz-Copy-Source-Range"), "small objects are copied at once")
+	}
+
+	// Above 5 GiB the copy is made of ranges of the source. The fake object
+	// is small, so the ranges past its end are empty.
+	fake.headers = nil
+	err = s.Copy(context.Background(), "bucket", "big.bin", "bucket", "huge.bin",
+		store.CopyOptions{Size: maxCopySize + 1, PartSize: minPartSize})
+	assert.NoError(t, err)
+	assert.Equal(t, content, fake.objects["bucket/huge.bin"])
+	var ranges []string
+	for _, h := range fake.headers {
+		if rng := h.Get("X-Amz-Copy-Source-Range"); rng != "" {
+			ranges = append(ranges, rng)
+		}
+	}
+	assert.Len(t, ranges, int((maxCopySize+minPartSize)/minPartSize))
+	assert.Contains(t, ranges, fmt.Sprintf("bytes=%d-%d", minPartSize, 2*minPartSize-1))
+}
+
+func TestS3StoreDeleteBatch(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	keys := make([]string, 1500)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("logs/%04d", i)
+		fake.objects["bucket/"+keys[i]] = []byte("x")
+	}
+	fake.denied = map[string]bool{"logs/1200": true}
+
+	failed := s.DeleteBatch(context.Background(), "bucket", keys)
+	assert.Len(t, failed, 1)
+	assert.ErrorContains(t, failed["logs/1200"], "AccessDenied")
+	assert.Equal(t, []int{1000, 500}, fake.batches)
+	assert.Len(t, fake.objects, 1)
+	_, ok := fake.object("bucket", "logs/1200")
+	assert.True(t, ok)
+}
+
+func TestS3StoreDeleteVersion(t *testing.T) {
+	s, fake := newFakeS3Store(t)
+	fake.objects["bucket/a.txt"] = []byte("current")
+
+	assert.NoError(t, s.DeleteVersion(context.Background(), "bucket", "a.txt", "v1"))
+	assert.Equal(t, []string{"v1"}, fake.versions)
+	_, ok := fake.object("bucket", "a.txt")
+	assert.True(t, ok)
+
+	assert.NoError(t, s.Delete(context.Background(), "bucket", "a.txt"))
+	assert.Equal(t, []string{"v1"}, fake.versions)
+	_, ok = fake.object("bucket", "a.txt")
+	assert.False(t, ok)
+}
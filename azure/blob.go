@@ -0,0 +1,485 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/RA-Balaji/storage-synk/bwlimit"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+const (
+	defaultBlockSize = 8 * 1024 * 1024
+	maxBlocks        = 50000
+
+	copyPollInterval = 500 * time.Millisecond
+)
+
+// Credentials selects how the blob client authenticates. The first non-empty
+// of ConnectionString, AccountKey and SASToken is used.
+type Credentials struct {
+	ConnectionString string
+	AccountKey       string
+	SASToken         string
+	// Endpoint overrides the blob service URL, e.g.
+	// http://127.0.0.1:10000/devstoreaccount1 for a local Azurite emulator.
+	Endpoint string
+}
+
+// CredentialsFromEnv reads the same variables as the az CLI, plus
+// AZURE_STORAGE_ENDPOINT for emulators.
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		ConnectionString: os.Getenv("AZURE_STORAGE_CONNECTION_STRING"),
+		AccountKey:       os.Getenv("AZURE_STORAGE_KEY"),
+		SASToken:         os.Getenv("AZURE_STORAGE_SAS_TOKEN"),
+		Endpoint:         os.Getenv("AZURE_STORAGE_ENDPOINT"),
+	}
+}
+
+// BlobStore implements store.ObjectStore for one storage account. Buckets
+// are containers.
+type BlobStore struct {
+	client    *azblob.Client
+	blockSize int64
+}
+
+var (
+	_ store.ObjectStore    = (*BlobStore)(nil)
+	_ store.VersionedStore = (*BlobStore)(nil)
+	_ store.BucketLister   = (*BlobStore)(nil)
+)
+
+func NewBlobStore(account string, creds Credentials) (*BlobStore, error) {
+	client, err := newBlobClient(account, creds)
+	if err != nil {
+		return nil, fmt.Errorf("Error initializing azure blob client: %w", err)
+	}
+	return &BlobStore{client: client, blockSize: defaultBlockSize}, nil
+}
+
+func newBlobClient(account string, creds Credentials) (*azblob.Client, error) {
+	switch {
+	case creds.ConnectionString != "":
+		return azblob.NewClientFromConnectionString(creds.ConnectionString, nil)
+	case creds.AccountKey != "":
+		cred, err := azblob.NewSharedKeyCredential(account, creds.AccountKey)
+		if err != nil {
+			return nil, err
+		}
+		return azblob.NewClientWithSharedKeyCredential(serviceURL(account, creds.Endpoint), cred, nil)
+	case creds.SASToken != "":
+		url := serviceURL(account, creds.Endpoint) + "?" + strings.TrimPrefix(creds.SASToken, "?")
+		return azblob.NewClientWithNoCredential(url, nil)
+	}
+	return nil, fmt.Errorf("no credentials for account [%s]: set a connection string, account key or SAS token", account)
+}
+
+func serviceURL(account, endpoint string) string {
+	if endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/"
+	}
+	return fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+}
+
+// ListBuckets calls fn with every container in the account.
+func (b *BlobStore) ListBuckets(ctx context.Context, fn func(store.BucketInfo) error) error {
+	pager := b.client.NewListContainersPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("Error listing containers: %w", err)
+		}
+		for _, c := range page.ContainerItems {
+			// Containers report when they were last modified, not
+			// created.
+			if err := fn(store.BucketInfo{Name: deref(c.Name)}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *BlobStore) List(
+	ctx context.Context,
+	bucket, prefix string,
+	opts store.ListOptions, fn store.WalkFunc) error {
+
+	include := container.ListBlobsInclude{Metadata: true}
+	containerClient := b.client.ServiceClient().NewContainerClient(bucket)
+
+	if opts.Recursive {
+		pager := containerClient.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+			Prefix:  to.Ptr(prefix),
+			Include: include,
+		})
+		for pager.More() {
+			page, err := pager.NextPage(ctx)
+			if err != nil {
+				return fmt.Errorf("Error listing blobs in container [%s]: %w", bucket, err)
+			}
+			for _, item := range page.Segment.BlobItems {
+				if err := fn(blobItemInfo(item)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	pager := containerClient.NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{
+		Prefix:  to.Ptr(prefix),
+		Include: include,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("Error listing blobs in container [%s]: %w", bucket, err)
+		}
+		for _, p := range page.Segment.BlobPrefixes {
+			if err := fn(store.ObjectInfo{Key: *p.Name, IsPrefix: true}); err != nil {
+				return err
+			}
+		}
+		for _, item := range page.Segment.BlobItems {
+			if err := fn(blobItemInfo(item)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (b *BlobStore) Stat(ctx context.Context, bucket, key string) (store.ObjectInfo, error) {
+	return b.StatVersion(ctx, bucket, key, "")
+}
+
+func (b *BlobStore) StatVersion(ctx context.Context, bucket, key, version string) (store.ObjectInfo, error) {
+	client, err := b.versionClient(bucket, key, version)
+	if err != nil {
+		return store.ObjectInfo{}, err
+	}
+
+	props, err := client.GetProperties(ctx, nil)
+	if err != nil {
+		return store.ObjectInfo{}, blobError(bucket, key, err)
+	}
+
+	info := store.ObjectInfo{
+		Key:          key,
+		Size:         deref(props.ContentLength),
+		ModTime:      deref(props.LastModified),
+		MD5:          hex.EncodeToString(props.ContentMD5),
+		StorageClass: deref(props.AccessTier),
+		Headers: store.Headers{
+			ContentType:        deref(props.ContentType),
+			CacheControl:       deref(props.CacheControl),
+			ContentEncoding:    deref(props.ContentEncoding),
+			ContentDisposition: deref(props.ContentDisposition),
+		},
+		Metadata:   metadataValues(props.Metadata),
+		Encryption: blobEncryption(props),
+		VersionID:  version,
+	}
+	if props.ETag != nil {
+		info.ETag = string(*props.ETag)
+	}
+	return info, nil
+}
+
+func (b *BlobStore) Open(
+	ctx context.Context,
+	bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	return b.OpenVersion(ctx, bucket, key, "", offset, length)
+}
+
+func (b *BlobStore) OpenVersion(
+	ctx context.Context,
+	bucket, key, version string, offset, length int64) (io.ReadCloser, error) {
+
+	if length == 0 {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	// A zero Count means "until the end of the blob".
+	rng := blob.HTTPRange{Offset: offset}
+	if length > 0 {
+		rng.Count = length
+	}
+	client, err := b.versionClient(bucket, key, version)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.DownloadStream(ctx, &blob.DownloadStreamOptions{Range: rng})
+	if err != nil {
+		return nil, blobError(bucket, key, err)
+	}
+	return resp.Body, nil
+}
+
+// Write uploads r as a block blob: the content is staged block by block and
+// committed at the end, so only one block is held in memory at a time.
+func (b *BlobStore) Write(
+	ctx context.Context,
+	bucket, key string, r io.Reader, opts store.WriteOptions) error {
+
+	if opts.Encryption.Mode != store.EncryptionDefault {
+		return errEncryptionUnsupported
+	}
+	client := b.client.ServiceClient().NewContainerClient(bucket).NewBlockBlobClient(key)
+	blockSize := b.blockSize
+	if opts.PartSize > 0 {
+		blockSize = opts.PartSize
+	}
+	buf := make([]byte, blockSizeFor(blockSize, opts.Size))
+
+	var blockIDs []string
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			id := blockID(len(blockIDs))
+			body := progress.ReadSeeker(ctx, bwlimit.ReadSeeker(ctx, bytes.NewReader(buf[:n])))
+			_, stageErr := client.StageBlock(ctx, id, streaming.NopCloser(body), nil)
+			if stageErr != nil {
+				return fmt.Errorf("Error staging block %d of az://%s/%s: %w", len(blockIDs), bucket, key, stageErr)
+			}
+			blockIDs = append(blockIDs, id)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading content for az://%s/%s: %w", bucket, key, err)
+		}
+	}
+
+	commitOpts := &blockblob.CommitBlockListOptions{
+		HTTPHeaders: httpHeaders(opts.Headers),
+		Metadata:    metadataPointers(opts.Metadata),
+	}
+	if _, err := client.CommitBlockList(ctx, blockIDs, commitOpts); err != nil {
+		return fmt.Errorf("Error committing block list of az://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+func (b *BlobStore) Delete(ctx context.Context, bucket, key string) error {
+	_, err := b.client.DeleteBlob(ctx, bucket, key, nil)
+	if err != nil {
+		return blobError(bucket, key, err)
+	}
+	return nil
+}
+
+// DeleteVersion deletes one previous version of a blob.
+func (b *BlobStore) DeleteVersion(ctx context.Context, bucket, key, version string) error {
+	client, err := b.versionClient(bucket, key, version)
+	if err != nil {
+		return err
+	}
+	if _, err := client.Delete(ctx, nil); err != nil {
+		return blobError(bucket, key, err)
+	}
+	return nil
+}
+
+// Copy starts a server-side copy and waits for it to finish.
+func (b *BlobStore) Copy(
+	ctx context.Context,
+	srcBucket, srcKey, dstBucket, dstKey string, opts store.CopyOptions) error {
+
+	if opts.Encryption.Mode != store.EncryptionDefault {
+		return errEncryptionUnsupported
+	}
+	src := b.blobClient(srcBucket, srcKey)
+	dst := b.blobClient(dstBucket, dstKey)
+
+	resp, err := dst.StartCopyFromURL(ctx, src.URL(), nil)
+	if err != nil {
+		return fmt.Errorf(
+			"Error copying az://%s/%s to az://%s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, err)
+	}
+
+	status := deref(resp.CopyStatus)
+	for status == blob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(copyPollInterval):
+		}
+		props, err := dst.GetProperties(ctx, nil)
+		if err != nil {
+			return blobError(dstBucket, dstKey, err)
+		}
+		status = deref(props.CopyStatus)
+		if status == blob.CopyStatusTypeFailed || status == blob.CopyStatusTypeAborted {
+			return fmt.Errorf("Copy to az://%s/%s %s: %s",
+				dstBucket, dstKey, status, deref(props.CopyStatusDescription))
+		}
+	}
+	// A copy takes the properties and metadata of its source, even when
+	// it is given no metadata; they can only be replaced once it is done.
+	if opts.ReplaceMetadata {
+		if _, err := dst.SetHTTPHeaders(ctx, *httpHeaders(opts.Headers), nil); err != nil {
+			return fmt.Errorf("Error setting the headers of az://%s/%s: %w", dstBucket, dstKey, err)
+		}
+		// Without metadata, SetMetadata removes all of it.
+		if _, err := dst.SetMetadata(ctx, metadataPointers(opts.Metadata), nil); err != nil {
+			return fmt.Errorf("Error setting the metadata of az://%s/%s: %w", dstBucket, dstKey, err)
+		}
+	}
+	return nil
+}
+
+// CreateContainer creates a container; it is mostly useful against emulators.
+func (b *BlobStore) CreateContainer(ctx context.Context, name string) error {
+	_, err := b.client.CreateContainer(ctx, name, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return fmt.Errorf("Error creating container [%s]: %w", name, err)
+	}
+	return nil
+}
+
+func (b *BlobStore) blobClient(bucket, key string) *blob.Client {
+	return b.client.ServiceClient().NewContainerClient(bucket).NewBlobClient(key)
+}
+
+func (b *BlobStore) versionClient(bucket, key, version string) (*blob.Client, error) {
+	client := b.blobClient(bucket, key)
+	if version == "" {
+		return client, nil
+	}
+	client, err := client.WithVersionID(version)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid version [%s] for az://%s/%s: %w", version, bucket, key, err)
+	}
+	return client, nil
+}
+
+func blobItemInfo(item *container.BlobItem) store.ObjectInfo {
+	info := store.ObjectInfo{
+		Key:      deref(item.Name),
+		Metadata: metadataValues(item.Metadata),
+	}
+	if p := item.Properties; p != nil {
+		info.Size = deref(p.ContentLength)
+		info.ModTime = deref(p.LastModified)
+		info.Headers = store.Headers{
+			ContentType:        deref(p.ContentType),
+			CacheControl:       deref(p.CacheControl),
+			ContentEncoding:    deref(p.ContentEncoding),
+			ContentDisposition: deref(p.ContentDisposition),
+		}
+		info.MD5 = hex.EncodeToString(p.ContentMD5)
+		if p.AccessTier != nil {
+			info.StorageClass = string(*p.AccessTier)
+		}
+		if p.ETag != nil {
+			info.ETag = string(*p.ETag)
+		}
+	}
+	return info
+}
+
+// errEncryptionUnsupported is returned for server-side encryption options:
+// blobs are encrypted with the settings of the storage account.
+var errEncryptionUnsupported = errors.New("Server-side encryption options are not supported for Azure Blob Storage")
+
+func blobEncryption(props blob.GetPropertiesResponse) string {
+	switch {
+	case props.EncryptionScope != nil:
+		return "encryption scope " + *props.EncryptionScope
+	case props.EncryptionKeySHA256 != nil:
+		return "customer key"
+	case deref(props.IsServerEncrypted):
+		return "Microsoft-managed"
+	}
+	return ""
+}
+
+func blobError(bucket, key string, err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return fmt.Errorf("az://%s/%s: %w", bucket, key, store.ErrNotExist)
+	}
+	return fmt.Errorf("Error accessing az://%s/%s: %w", bucket, key, err)
+}
+
+// blockSizeFor grows the block size so that a blob of the given size fits
+// in the service limit of 50,000 blocks.
+func blockSizeFor(blockSize, size int64) int64 {
+	for size > 0 && size/blockSize >= maxBlocks {
+		blockSize *= 2
+	}
+	return blockSize
+}
+
+// blockID returns a base64 block ID. All IDs of a blob must have the same
+// length, hence the fixed width.
+func blockID(i int) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%06d", i)))
+}
+
+func metadataValues(md map[string]*string) map[string]string {
+	if len(md) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(md))
+	for k, v := range md {
+		res[k] = deref(v)
+	}
+	return res
+}
+
+// metadataPointers also turns the dashes of S3 and GCS metadata names into
+// underscores: Azure metadata names must be identifiers.
+func metadataPointers(md map[string]string) map[string]*string {
+	if len(md) == 0 {
+		return nil
+	}
+	res := make(map[string]*string, len(md))
+	for k, v := range md {
+		res[strings.ReplaceAll(k, "-", "_")] = to.Ptr(v)
+	}
+	return res
+}
+
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
+
+func httpHeaders(h store.Headers) *blob.HTTPHeaders {
+	return &blob.HTTPHeaders{
+		BlobContentType:        optional(h.ContentType),
+		BlobCacheControl:       optional(h.CacheControl),
+		BlobContentEncoding:    optional(h.ContentEncoding),
+		BlobContentDisposition: optional(h.ContentDisposition),
+	}
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return to.Ptr(s)
+}
@@ -0,0 +1,161 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// Well-known development account of the Azurite emulator.
+const (
+	azuriteAccount = "devstoreaccount1"
+	azuriteKey     = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	testContainer  = "storage-synk-tests"
+)
+
+// newAzuriteStore connects to a local emulator, e.g. started with
+// `docker run -p 10000:10000 mcr.microsoft.com/azure-storage/azurite azurite-blob --blobHost 0.0.0.0`
+// and AZURITE_BLOB_ENDPOINT=http://127.0.0.1:10000/devstoreaccount1.
+func newAzuriteStore(t *testing.T) *BlobStore {
+	endpoint := os.Getenv("AZURITE_BLOB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("AZURITE_BLOB_ENDPOINT not set")
+	}
+
+	bs, err := NewBlobStore(azuriteAccount, Credentials{AccountKey: azuriteKey, Endpoint: endpoint})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.CreateContainer(context.Background(), testContainer); err != nil {
+		t.Fatal(err)
+	}
+	return bs
+}
+
+func TestBlobStoreRoundTrip(t *testing.T) {
+	bs := newAzuriteStore(t)
+	bs.blockSize = 4 // force several staged blocks
+	ctx := context.Background()
+
+	content := []byte("hello from storage-synk")
+	err := bs.Write(ctx, testContainer, "dir/hello.txt", bytes.NewReader(content),
+		store.WriteOptions{Size: int64(len(content))})
+	assert.NoError(t, err)
+
+	info, err := bs.Stat(ctx, testContainer, "dir/hello.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(content)), info.Size)
+
+	r, err := bs.Open(ctx, testContainer, "dir/hello.txt", 6, 4)
+	assert.NoError(t, err)
+	part, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "from", string(part))
+
+	var prefixes []string
+	err = bs.List(ctx, testContainer, "", store.ListOptions{}, func(obj store.ObjectInfo) error {
+		if obj.IsPrefix {
+			prefixes = append(prefixes, obj.Key)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, prefixes, "dir/")
+
+	assert.NoError(t, bs.Copy(ctx, testContainer, "dir/hello.txt", testContainer, "copy.txt", store.CopyOptions{}))
+	assert.NoError(t, bs.Delete(ctx, testContainer, "dir/hello.txt"))
+	assert.NoError(t, bs.Delete(ctx, testContainer, "copy.txt"))
+
+	_, err = bs.Stat(ctx, testContainer, "dir/hello.txt")
+	assert.True(t, store.IsNotExist(err))
+}
+
+func TestBlockIDsHaveFixedLength(t *testing.T) {
+	first, _ := base64.StdEncoding.DecodeString(blockID(0))
+	last, _ := base64.StdEncoding.DecodeString(blockID(maxBlocks - 1))
+	assert.Equal(t, len(first), len(last))
+}
+
+func TestBlockSizeFor(t *testing.T) {
+	assert.Equal(t, int64(defaultBlockSize), blockSizeFor(defaultBlockSize, -1))
+	assert.Equal(t, int64(defaultBlockSize), blockSizeFor(defaultBlockSize, 1024))
+
+	huge := int64(defaultBlockSize) * maxBlocks * 3
+	size := blockSizeFor(defaultBlockSize, huge)
+	assert.Less(t, huge/size, int64(maxBlocks))
+}
+
+func TestServiceURL(t *testing.T) {
+	assert.Equal(t, "https://acct.blob.core.windows.net/", serviceURL("acct", ""))
+	assert.Equal(t, "http://127.0.0.1:10000/devstoreaccount1/",
+		serviceURL(azuriteAccount, "http://127.0.0.1:10000/devstoreaccount1"))
+}
+
+func TestNewBlobStoreWithoutCredentials(t *testing.T) {
+	_, err := NewBlobStore("acct", Credentials{})
+	assert.Error(t, err)
+}
+
+// copyServer answers blob copies as finished at once and records every
+// request as "METHOD comp" with the metadata headers it carried.
+type copyServer struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (c *copyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var meta []string
+	for name, values := range r.Header {
+		if strings.HasPrefix(strings.ToLower(name), "x-ms-meta-") {
+			meta = append(meta, strings.ToLower(name)+"="+values[0])
+		}
+	}
+	sort.Strings(meta)
+	c.requests = append(c.requests, strings.TrimSpace(strings.Join(
+		append([]string{r.Method, r.URL.Query().Get("comp")}, meta...), " ")))
+	if r.Header.Get("X-Ms-Copy-Source") != "" {
+		w.Header().Set("x-ms-copy-status", "success")
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func TestBlobStoreCopyReplacesMetadata(t *testing.T) {
+	fake := &copyServer{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	bs, err := NewBlobStore(azuriteAccount, Credentials{AccountKey: azuriteKey, Endpoint: srv.URL + "/" + azuriteAccount})
+	assert.NoError(t, err)
+
+	// The copy keeps the metadata of the source by itself.
+	assert.NoError(t, bs.Copy(context.Background(), "c", "a.txt", "c", "b.txt", store.CopyOptions{}))
+	assert.Equal(t, []string{"PUT"}, fake.requests)
+
+	// Replacing with no metadata clears it after the copy.
+	fake.requests = nil
+	err = bs.Copy(context.Background(), "c", "a.txt", "c", "b.txt", store.CopyOptions{
+		ReplaceMetadata: true, Headers: store.Headers{ContentType: "text/plain"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"PUT", "PUT properties", "PUT metadata"}, fake.requests)
+
+	fake.requests = nil
+	err = bs.Copy(context.Background(), "c", "a.txt", "c", "b.txt", store.CopyOptions{
+		ReplaceMetadata: true, Metadata: map[string]string{"build-id": "42"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "PUT metadata x-ms-meta-build_id=42", fake.requests[2])
+}
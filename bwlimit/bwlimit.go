@@ -0,0 +1,181 @@
+// Package bwlimit caps the bandwidth of a whole transfer. One Limiter
+// travels in the context and is shared by every worker; the backends charge
+// the bytes they send or receive through the readers and writers returned
+// by Reader, ReadSeeker and WriterAt, or by calling Wait.
+package bwlimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket refilled at the rate of its schedule. Bytes
+// may be taken ahead of time; later callers then wait until the debt is
+// paid back, so the average rate holds across all callers.
+type Limiter struct {
+	schedule Schedule
+	now      func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewLimiter(schedule Schedule) *Limiter {
+	return &Limiter{schedule: schedule, now: time.Now}
+}
+
+// WaitN takes n bytes from the bucket and waits until they are covered by
+// the current rate.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	wait := l.reserve(n)
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes n bytes and returns how long the caller has to wait for
+// them.
+func (l *Limiter) reserve(n int) time.Duration {
+	if n <= 0 {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	rate := float64(l.schedule.RateAt(now))
+	if rate <= 0 {
+		l.tokens, l.last = 0, now
+		return 0
+	}
+	if !l.last.IsZero() && now.After(l.last) {
+		l.tokens += now.Sub(l.last).Seconds() * rate
+	}
+	// Idle time saves up at most one second of traffic.
+	if l.tokens > rate {
+		l.tokens = rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / rate * float64(time.Second))
+}
+
+type limiterKey struct{}
+
+func WithLimiter(ctx context.Context, l *Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, l)
+}
+
+// FromContext returns the limiter of ctx, or nil.
+func FromContext(ctx context.Context) *Limiter {
+	l, _ := ctx.Value(limiterKey{}).(*Limiter)
+	return l
+}
+
+// Wait charges n bytes to the limiter of ctx, if any.
+func Wait(ctx context.Context, n int) error {
+	if l := FromContext(ctx); l != nil {
+		return l.WaitN(ctx, n)
+	}
+	return nil
+}
+
+// Reader limits the bytes read from r. It returns r itself when ctx has no
+// limiter.
+func Reader(ctx context.Context, r io.Reader) io.Reader {
+	l := FromContext(ctx)
+	if l == nil {
+		return r
+	}
+	return &reader{ctx: ctx, r: r, limiter: l}
+}
+
+// ReadSeeker is like Reader for seekable bodies. Bytes read again after a
+// rewind, as HTTP clients do to sign a request, are only charged once.
+func ReadSeeker(ctx context.Context, r io.ReadSeeker) io.ReadSeeker {
+	l := FromContext(ctx)
+	if l == nil {
+		return r
+	}
+	return &seekReader{ctx: ctx, r: r, limiter: l}
+}
+
+// WriterAt limits the bytes written through w.
+func WriterAt(ctx context.Context, w io.WriterAt) io.WriterAt {
+	l := FromContext(ctx)
+	if l == nil {
+		return w
+	}
+	return &writerAt{ctx: ctx, w: w, limiter: l}
+}
+
+type reader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *Limiter
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if waitErr := r.limiter.WaitN(r.ctx, n); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+type seekReader struct {
+	ctx     context.Context
+	r       io.ReadSeeker
+	limiter *Limiter
+	// pos is the current offset and high the furthest offset charged.
+	pos, high int64
+}
+
+func (r *seekReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.pos += int64(n)
+	if r.pos > r.high {
+		charge := r.pos - r.high
+		r.high = r.pos
+		if waitErr := r.limiter.WaitN(r.ctx, int(charge)); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+func (r *seekReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.r.Seek(offset, whence)
+	if err == nil {
+		r.pos = pos
+	}
+	return pos, err
+}
+
+type writerAt struct {
+	ctx     context.Context
+	w       io.WriterAt
+	limiter *Limiter
+}
+
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	if err := w.limiter.WaitN(w.ctx, len(p)); err != nil {
+		return 0, err
+	}
+	return w.w.WriteAt(p, off)
+}
@@ -0,0 +1,85 @@
+package bwlimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func at(clock string) time.Time {
+	t, _ := time.Parse("15:04", clock)
+	return t
+}
+
+func TestParseSchedule(t *testing.T) {
+	sched, err := ParseSchedule("50MiB/s")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50<<20), sched.RateAt(at("03:00")))
+	assert.True(t, sched.Limited())
+
+	sched, err = ParseSchedule("18:00,off 08:00,10MiB/s 12:00,512K")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), sched.RateAt(at("07:59")))
+	assert.Equal(t, int64(10<<20), sched.RateAt(at("08:00")))
+	assert.Equal(t, int64(512<<10), sched.RateAt(at("17:59")))
+	assert.Equal(t, int64(0), sched.RateAt(at("23:00")))
+
+	sched, err = ParseSchedule("")
+	assert.NoError(t, err)
+	assert.False(t, sched.Limited())
+
+	for _, bad := range []string{"fast", "08:00", "25:00,1M", "08:00,lots 18:00,off"} {
+		_, err := ParseSchedule(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLimiterReserve(t *testing.T) {
+	sched, _ := ParseSchedule("1000")
+	l := NewLimiter(sched)
+	now := at("12:00")
+	l.now = func() time.Time { return now }
+
+	// The bucket starts empty; debt is paid back at the rate.
+	assert.Equal(t, 500*time.Millisecond, l.reserve(500))
+	assert.Equal(t, 1500*time.Millisecond, l.reserve(1000))
+
+	// Idle time refills at most one second worth of bytes.
+	now = now.Add(time.Minute)
+	assert.Equal(t, time.Duration(0), l.reserve(1000))
+	assert.Equal(t, 100*time.Millisecond, l.reserve(100))
+}
+
+func TestLimiterFollowsSchedule(t *testing.T) {
+	sched, _ := ParseSchedule("08:00,1000 18:00,off")
+	l := NewLimiter(sched)
+	now := at("20:00")
+	l.now = func() time.Time { return now }
+	assert.Equal(t, time.Duration(0), l.reserve(1<<30))
+
+	// The next morning, with one second of saved up bytes.
+	now = at("09:00").Add(24 * time.Hour)
+	assert.Equal(t, time.Second, l.reserve(2000))
+}
+
+func TestReadSeekerChargesRereadsOnce(t *testing.T) {
+	sched, _ := ParseSchedule("1000000")
+	l := NewLimiter(sched)
+	ctx := WithLimiter(context.Background(), l)
+
+	r := ReadSeeker(ctx, bytes.NewReader(make([]byte, 100)))
+	io.ReadAll(r)
+	r.Seek(0, io.SeekStart)
+	io.ReadAll(r)
+	assert.InDelta(t, -100, l.tokens, 1)
+}
+
+func TestWrappersWithoutLimiter(t *testing.T) {
+	r := bytes.NewReader(nil)
+	assert.Same(t, r, Reader(context.Background(), r))
+	assert.NoError(t, Wait(context.Background(), 1<<30))
+}
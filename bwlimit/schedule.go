@@ -0,0 +1,99 @@
+package bwlimit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/utils"
+)
+
+// Schedule is a bandwidth limit that may change with the time of day.
+type Schedule struct {
+	// entries are sorted by minute of the day.
+	entries []entry
+}
+
+type entry struct {
+	minute int
+	rate   int64
+}
+
+// ParseSchedule parses either a single rate such as "50MiB/s", or
+// space-separated "HH:MM,rate" entries such as
+// "08:00,10MiB/s 18:00,off". Each entry applies from its time until the
+// next one, wrapping around midnight. "off" or 0 means unlimited.
+func ParseSchedule(s string) (Schedule, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Schedule{}, nil
+	}
+	if len(fields) == 1 && !strings.Contains(fields[0], ",") {
+		rate, err := ParseRate(fields[0])
+		if err != nil {
+			return Schedule{}, err
+		}
+		return Schedule{entries: []entry{{rate: rate}}}, nil
+	}
+
+	var sched Schedule
+	for _, field := range fields {
+		at, rateStr, ok := strings.Cut(field, ",")
+		if !ok {
+			return Schedule{}, fmt.Errorf("Invalid schedule entry %q: expected HH:MM,rate", field)
+		}
+		t, err := time.Parse("15:04", at)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("Invalid time in schedule entry %q", field)
+		}
+		rate, err := ParseRate(rateStr)
+		if err != nil {
+			return Schedule{}, err
+		}
+		sched.entries = append(sched.entries, entry{minute: t.Hour()*60 + t.Minute(), rate: rate})
+	}
+	sort.Slice(sched.entries, func(i, j int) bool { return sched.entries[i].minute < sched.entries[j].minute })
+	return sched, nil
+}
+
+// ParseRate parses a rate such as "50MiB/s" or "512K" into bytes per
+// second. "off" and 0 mean unlimited.
+func ParseRate(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "off") {
+		return 0, nil
+	}
+	rate, err := utils.ParseSize(strings.TrimSuffix(s, "/s"))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid bandwidth %q: %v", s, err)
+	}
+	return rate, nil
+}
+
+// RateAt returns the limit in bytes per second at t, or 0 if unlimited.
+func (s Schedule) RateAt(t time.Time) int64 {
+	if len(s.entries) == 0 {
+		return 0
+	}
+	minute := t.Hour()*60 + t.Minute()
+	// Before the first entry of the day the last one still applies.
+	current := s.entries[len(s.entries)-1]
+	for _, e := range s.entries {
+		if e.minute > minute {
+			break
+		}
+		current = e
+	}
+	return current.rate
+}
+
+// Limited reports whether the schedule limits bandwidth at any time.
+func (s Schedule) Limited() bool {
+	for _, e := range s.entries {
+		if e.rate > 0 {
+			return true
+		}
+	}
+	return false
+}
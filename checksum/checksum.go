@@ -0,0 +1,160 @@
+// Package checksum verifies transferred content against the checksums the
+// stores keep for their objects: MD5, CRC32C and SHA-256.
+package checksum
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+// ErrMismatch is returned (wrapped) when content does not match its
+// checksum. Such copies are retried.
+var ErrMismatch = errors.New("checksum mismatch")
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Sums holds hex encoded checksums. Empty fields are unknown.
+type Sums struct {
+	MD5    string
+	CRC32C string
+	SHA256 string
+}
+
+// Of returns the checksums a store reported for obj.
+func Of(obj store.ObjectInfo) Sums {
+	return Sums{MD5: obj.MD5, CRC32C: obj.CRC32C, SHA256: obj.SHA256}
+}
+
+func (s Sums) Empty() bool {
+	return s.MD5 == "" && s.CRC32C == "" && s.SHA256 == ""
+}
+
+// Verify returns an error wrapping ErrMismatch if a checksum known on both
+// sides differs.
+func Verify(got, want Sums) error {
+	for _, c := range []struct{ name, got, want string }{
+		{"MD5", got.MD5, want.MD5},
+		{"CRC32C", got.CRC32C, want.CRC32C},
+		{"SHA-256", got.SHA256, want.SHA256},
+	} {
+		if c.got != "" && c.want != "" && !strings.EqualFold(c.got, c.want) {
+			return fmt.Errorf("%w: %s is %s, expected %s", ErrMismatch, c.name, c.got, c.want)
+		}
+	}
+	return nil
+}
+
+// Reader computes the checksums of the content read through it. CRC32C is
+// always computed; MD5 and SHA-256 only when want has them, since they
+// cost more CPU than they are worth without something to compare to.
+type Reader struct {
+	r      io.Reader
+	crc32c hash.Hash32
+	md5    hash.Hash
+	sha256 hash.Hash
+	n      int64
+}
+
+func NewReader(r io.Reader, want Sums) *Reader {
+	c := &Reader{r: r, crc32c: crc32.New(castagnoli)}
+	if want.MD5 != "" {
+		c.md5 = md5.New()
+	}
+	if want.SHA256 != "" {
+		c.sha256 = sha256.New()
+	}
+	return c
+}
+
+func (c *Reader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.crc32c.Write(p[:n])
+		if c.md5 != nil {
+			c.md5.Write(p[:n])
+		}
+		if c.sha256 != nil {
+			c.sha256.Write(p[:n])
+		}
+		c.n += int64(n)
+	}
+	return n, err
+}
+
+// Size returns the number of bytes read.
+func (c *Reader) Size() int64 {
+	return c.n
+}
+
+// Sums returns the checksums of the content read so far.
+func (c *Reader) Sums() Sums {
+	sums := Sums{CRC32C: FormatCRC32C(c.crc32c.Sum32())}
+	if c.md5 != nil {
+		sums.MD5 = hex.EncodeToString(c.md5.Sum(nil))
+	}
+	if c.sha256 != nil {
+		sums.SHA256 = hex.EncodeToString(c.sha256.Sum(nil))
+	}
+	return sums
+}
+
+// Check verifies that the content read matches want and has the given
+// size.
+func (c *Reader) Check(want Sums, size int64) error {
+	if size >= 0 && c.n != size {
+		return fmt.Errorf("%w: read %d bytes, expected %d", ErrMismatch, c.n, size)
+	}
+	return Verify(c.Sums(), want)
+}
+
+// CRC32C returns the CRC32C of data in hex.
+func CRC32C(data []byte) string {
+	return FormatCRC32C(crc32.Checksum(data, castagnoli))
+}
+
+func FormatCRC32C(sum uint32) string {
+	return fmt.Sprintf("%08x", sum)
+}
+
+// ParseCRC32C parses a hex CRC32C.
+func ParseCRC32C(s string) (uint32, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 4 {
+		return 0, fmt.Errorf("Invalid CRC32C %q", s)
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+// FromBase64 converts a base64 checksum, as S3 and GCS headers carry them,
+// to hex. S3 checksums of multipart objects ("<base64>-<parts>") are not
+// checksums of the content and yield "".
+func FromBase64(s string) string {
+	if s == "" || strings.Contains(s, "-") {
+		return ""
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// ToBase64 converts a hex checksum to base64.
+func ToBase64(s string) string {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,51 @@
+package checksum
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReaderSums(t *testing.T) {
+	data := []byte("hello world")
+	want := Sums{
+		MD5:    "5eb63bbbe01eeed093cb22bb8f5acdc3",
+		CRC32C: "c99465aa",
+		SHA256: "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9",
+	}
+
+	r := NewReader(bytes.NewReader(data), want)
+	_, err := io.Copy(io.Discard, r)
+	assert.NoError(t, err)
+	assert.Equal(t, want, r.Sums())
+	assert.NoError(t, r.Check(want, int64(len(data))))
+	assert.ErrorIs(t, r.Check(want, 12), ErrMismatch)
+
+	// Only CRC32C is computed when there is nothing else to compare to.
+	r = NewReader(bytes.NewReader(data), Sums{})
+	io.Copy(io.Discard, r)
+	assert.Equal(t, Sums{CRC32C: want.CRC32C}, r.Sums())
+}
+
+func TestVerify(t *testing.T) {
+	assert.NoError(t, Verify(Sums{CRC32C: "c99465aa"}, Sums{MD5: "5eb63bbbe01eeed093cb22bb8f5acdc3"}))
+	assert.NoError(t, Verify(Sums{CRC32C: "C99465AA"}, Sums{CRC32C: "c99465aa"}))
+	err := Verify(Sums{CRC32C: "00000000"}, Sums{CRC32C: "c99465aa"})
+	assert.ErrorIs(t, err, ErrMismatch)
+	assert.ErrorContains(t, err, "CRC32C is 00000000, expected c99465aa")
+}
+
+func TestBase64(t *testing.T) {
+	assert.Equal(t, "c99465aa", FromBase64("yZRlqg=="))
+	assert.Equal(t, "yZRlqg==", ToBase64("c99465aa"))
+	// Checksums of S3 multipart objects cover the parts, not the content.
+	assert.Equal(t, "", FromBase64("yZRlqg==-3"))
+
+	crc, err := ParseCRC32C("c99465aa")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0xc99465aa), crc)
+	_, err = ParseCRC32C("xyz")
+	assert.Error(t, err)
+}
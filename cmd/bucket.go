@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/RA-Balaji/storage-synk/config"
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/transfer"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var mbCmd = &cobra.Command{
+	Use:   "mb uri",
+	Short: "creates an S3 or GCS bucket",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		opts, err := bucketOptions(cmd)
+		if err != nil {
+			return err
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("Error parsing dry-run: %v", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ctx, plan := withPlan(ctx, dryRun)
+		u, _, manager, err := openBucket(ctx, cmd, cfg, args[0])
+		if err != nil {
+			return err
+		}
+		if err := manager.CreateBucket(ctx, u.Bucket, opts); err != nil {
+			return err
+		}
+		if plan != nil {
+			plan.Print(cmd.OutOrStdout())
+			return nil
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Created %s\n", u)
+		return nil
+	},
+}
+
+var rbCmd = &cobra.Command{
+	Use:   "rb uri",
+	Short: "removes an S3 or GCS bucket, with --force including everything in it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			return fmt.Errorf("Error parsing force: %v", err)
+		}
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return fmt.Errorf("Error parsing yes: %v", err)
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("Error parsing dry-run: %v", err)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ctx, plan := withPlan(ctx, dryRun)
+		u, s, manager, err := openBucket(ctx, cmd, cfg, args[0])
+		if err != nil {
+			return err
+		}
+
+		// The current objects are listed to show what --force deletes;
+		// noncurrent versions are deleted as well.
+		if force && (plan != nil || (!yes && stdinIsTerminal())) {
+			target, err := transfer.NewEndpoint(s, u)
+			if err != nil {
+				return err
+			}
+			objects, err := transfer.List(ctx, target, transfer.Options{})
+			if err != nil {
+				return err
+			}
+			if plan != nil {
+				if _, err := transfer.Remove(ctx, target, objects, transfer.Options{}); err != nil {
+					return err
+				}
+			} else if !confirm(cmd, fmt.Sprintf("Remove %s and the %d objects in it, with all their versions?", u, len(objects))) {
+				return nil
+			}
+		}
+
+		if err := manager.DeleteBucket(ctx, u.Bucket, force); err != nil {
+			return err
+		}
+		if plan != nil {
+			plan.Print(cmd.OutOrStdout())
+			return nil
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", u)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mbCmd)
+	rootCmd.AddCommand(rbCmd)
+
+	mbCmd.Flags().String("location", "",
+		"S3 region or GCS location, e.g. eu-west-1 or EU (default: client region, GCS US); --region is an alias")
+	mbCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "region" {
+			name = "location"
+		}
+		return pflag.NormalizedName(name)
+	})
+	mbCmd.Flags().String("storage-class", "", "Default storage class of the bucket, e.g. NEARLINE (GCS only)")
+	mbCmd.Flags().Bool("versioning", false, "Keep every version of the objects")
+	mbCmd.Flags().String("sse", "",
+		"Default encryption: managed (SSE-S3) or kms (SSE-KMS, GCS CMEK) (default: provider default)")
+	mbCmd.Flags().String("sse-kms-key", "",
+		"KMS key for --sse kms: an AWS KMS key ARN or a Cloud KMS key name")
+	mbCmd.Flags().Bool("block-public-access", false,
+		"Block public access (S3 public access block, GCS public access prevention)")
+	mbCmd.Flags().StringArray("label", nil, "Tag (S3) or label (GCS) as key=value (repeatable)")
+	mbCmd.Flags().Bool("dry-run", false, "Print the bucket that would be created")
+
+	rbCmd.Flags().Bool("force", false,
+		"Delete every object in the bucket first, noncurrent versions and delete markers included")
+	rbCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation with --force")
+	rbCmd.Flags().Bool("dry-run", false, "Print what would be removed without removing anything")
+}
+
+// withPlan attaches a new dry-run plan to ctx if dryRun is set.
+func withPlan(ctx context.Context, dryRun bool) (context.Context, *dryrun.Plan) {
+	if !dryRun {
+		return ctx, nil
+	}
+	plan := dryrun.NewPlan()
+	return dryrun.WithPlan(ctx, plan), plan
+}
+
+// openBucket parses a bucket URI such as s3://bucket or remote:bucket and
+// opens its store.
+func openBucket(
+	ctx context.Context,
+	cmd *cobra.Command, cfg *config.Config,
+	raw string) (uri.URI, store.ObjectStore, store.BucketManager, error) {
+
+	u, err := cfg.Parse(raw)
+	if err != nil {
+		return uri.URI{}, nil, nil, err
+	}
+	if u.Bucket == "" || strings.Trim(u.Key, "/") != "" {
+		return uri.URI{}, nil, nil, fmt.Errorf("Expected a bucket such as s3://bucket or gs://bucket, got %s", raw)
+	}
+	u.Key = ""
+	s, err := newStore(ctx, u, storeSettings(cmd, cfg, u))
+	if err != nil {
+		return uri.URI{}, nil, nil, err
+	}
+	manager, ok := s.(store.BucketManager)
+	if !ok {
+		return uri.URI{}, nil, nil, fmt.Errorf("Buckets cannot be created or removed for %s", u)
+	}
+	return u, s, manager, nil
+}
+
+// bucketOptions reads the flags of mb.
+func bucketOptions(cmd *cobra.Command) (store.BucketOptions, error) {
+	var opts store.BucketOptions
+	var err error
+
+	opts.Location, err = cmd.Flags().GetString("location")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing location: %v", err)
+	}
+	opts.StorageClass, err = cmd.Flags().GetString("storage-class")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing storage-class: %v", err)
+	}
+	opts.Versioning, err = cmd.Flags().GetBool("versioning")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing versioning: %v", err)
+	}
+	opts.BlockPublicAccess, err = cmd.Flags().GetBool("block-public-access")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing block-public-access: %v", err)
+	}
+
+	mode, err := cmd.Flags().GetString("sse")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing sse: %v", err)
+	}
+	opts.Encryption.KMSKey, err = cmd.Flags().GetString("sse-kms-key")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing sse-kms-key: %v", err)
+	}
+	opts.Encryption.Mode = store.EncryptionMode(strings.ToLower(mode))
+	if opts.Encryption.Mode == store.EncryptionDefault && opts.Encryption.KMSKey != "" {
+		opts.Encryption.Mode = store.EncryptionKMS
+	}
+	switch opts.Encryption.Mode {
+	case store.EncryptionDefault, store.EncryptionManaged, store.EncryptionKMS:
+	default:
+		return opts, fmt.Errorf("Invalid --sse %q: expected managed or kms", mode)
+	}
+	if opts.Encryption.KMSKey != "" && opts.Encryption.Mode != store.EncryptionKMS {
+		return opts, fmt.Errorf("--sse-kms-key needs --sse kms")
+	}
+
+	labels, err := cmd.Flags().GetStringArray("label")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing label: %v", err)
+	}
+	for _, label := range labels {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return opts, fmt.Errorf("Invalid --label %q: expected key=value", label)
+		}
+		if opts.Labels == nil {
+			opts.Labels = map[string]string{}
+		}
+		opts.Labels[key] = value
+	}
+	return opts, nil
+}
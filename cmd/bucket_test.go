@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMb(t *testing.T) {
+	fakes := useFakeStores(t)
+	gcs := fakes[uri.SchemeGCS]
+
+	err := runCommand(t, "mb", "--region", "EU", "--storage-class", "NEARLINE", "--versioning",
+		"--sse-kms-key", "projects/p/locations/eu/keyRings/r/cryptoKeys/k",
+		"--block-public-access", "--label", "team=data", "--label", "env=prod", "gs://new-bucket")
+	assert.NoError(t, err)
+	opts, ok := gcs.BucketOptions("new-bucket")
+	assert.True(t, ok)
+	assert.Equal(t, store.BucketOptions{
+		Location:          "EU",
+		StorageClass:      "NEARLINE",
+		Versioning:        true,
+		Encryption:        store.Encryption{Mode: store.EncryptionKMS, KMSKey: "projects/p/locations/eu/keyRings/r/cryptoKeys/k"},
+		BlockPublicAccess: true,
+		Labels:            map[string]string{"team": "data", "env": "prod"},
+	}, opts)
+
+	err = runCommand(t, "mb", "gs://new-bucket")
+	assert.ErrorContains(t, err, "already exists")
+
+	err = runCommand(t, "mb", "--dry-run", "s3://planned")
+	assert.NoError(t, err)
+	_, ok = fakes[uri.SchemeS3].BucketOptions("planned")
+	assert.False(t, ok)
+
+	err = runCommand(t, "mb", "--sse", "customer", "s3://secret")
+	assert.ErrorContains(t, err, "expected managed or kms")
+	err = runCommand(t, "mb", "gs://bucket/path")
+	assert.ErrorContains(t, err, "Expected a bucket")
+	err = runCommand(t, "mb", "--label", "novalue", "gs://labelled")
+	assert.ErrorContains(t, err, "key=value")
+}
+
+func TestRb(t *testing.T) {
+	fakes := useFakeStores(t)
+	s3 := fakes[uri.SchemeS3]
+	s3.Put("full", "a.txt", []byte("a"))
+	s3.Put("full", "dir/b.txt", []byte("bb"))
+
+	err := runCommand(t, "rb", "s3://full")
+	assert.ErrorContains(t, err, "not empty")
+
+	out, err := runWithInput(t, "", "rb", "--force", "--dry-run", "s3://full")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "s3://full/dir/b.txt")
+	assert.Contains(t, out, "mem bucket full")
+	assert.Len(t, s3.Keys("full"), 2)
+
+	out, err = runWithInput(t, "", "rb", "--force", "s3://full")
+	assert.NoError(t, err)
+	assert.Equal(t, "Removed s3://full\n", out)
+	_, ok := s3.BucketOptions("full")
+	assert.False(t, ok)
+	assert.Empty(t, s3.Keys("full"))
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/RA-Balaji/storage-synk/config"
+	"github.com/RA-Balaji/storage-synk/transfer"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/spf13/cobra"
+)
+
+// loadConfig reads the file given by --config, or the default config file
+// if it exists.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	path, err := cmd.Flags().GetString("config")
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing config: %v", err)
+	}
+	if path == "" {
+		return config.LoadDefault()
+	}
+	return config.Load(path)
+}
+
+// locations returns the source and destination, given either as
+// arguments or with --source and --destination.
+func locations(cmd *cobra.Command, args []string) (string, string, error) {
+	source, err := cmd.Flags().GetString("source")
+	if err != nil {
+		return "", "", fmt.Errorf("Source incorrect, error: %v", err)
+	}
+	destination, err := cmd.Flags().GetString("destination")
+	if err != nil {
+		return "", "", fmt.Errorf("Destination incorrect, error: %v", err)
+	}
+	if len(args) == 0 {
+		return source, destination, nil
+	}
+	if source != "" || destination != "" {
+		return "", "", fmt.Errorf("Give source and destination either as arguments or with --source and --destination")
+	}
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("Expected a source and a destination, got only %s", args[0])
+	}
+	return args[0], args[1], nil
+}
+
+// storeSettings returns the settings for the store of u. Flags take
+// precedence over environment variables, which take precedence over the
+// remote in the config file.
+func storeSettings(cmd *cobra.Command, cfg *config.Config, u uri.URI) config.Remote {
+	remote, _ := cfg.Remote(u.Remote)
+	remote = remote.WithEnv(u.Scheme, os.Getenv)
+	if cmd.Flags().Changed("aws-profile") {
+		remote.Profile, _ = cmd.Flags().GetString("aws-profile")
+	}
+	return remote
+}
+
+// applyRemoteSettings fills in the encryption settings of the remotes of
+// src and dst that no flag overrides.
+func applyRemoteSettings(cmd *cobra.Command, cfg *config.Config, src, dst uri.URI, opts *transfer.Options) error {
+	if remote, ok := cfg.Remote(dst.Remote); ok && !anyChanged(cmd, "sse", "sse-kms-key", "sse-customer-key-file") {
+		enc, err := remote.Encryption()
+		if err != nil {
+			return fmt.Errorf("Error in remote %s: %v", dst.Remote, err)
+		}
+		opts.Encryption = enc
+	}
+	if remote, ok := cfg.Remote(src.Remote); ok && !anyChanged(cmd, "source-sse-customer-key-file") {
+		key, err := remote.CustomerKey()
+		if err != nil {
+			return fmt.Errorf("Error in remote %s: %v", src.Remote, err)
+		}
+		opts.SourceKey = key
+	}
+	return nil
+}
+
+func anyChanged(cmd *cobra.Command, names ...string) bool {
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/spf13/cobra"
+)
+
+var duCmd = &cobra.Command{
+	Use:   "du uri",
+	Short: "totals the size and number of objects under a bucket path",
+	Long: `Totals the bytes and objects under a bucket path, optionally grouped by
+prefix, storage class or age. The listing is streamed, so only the totals
+are kept in memory.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		groupBy, err := cmd.Flags().GetString("group-by")
+		if err != nil {
+			return fmt.Errorf("Error parsing group-by: %v", err)
+		}
+		depth, err := cmd.Flags().GetInt("depth")
+		if err != nil {
+			return fmt.Errorf("Error parsing depth: %v", err)
+		}
+		if depth < 1 {
+			return fmt.Errorf("Invalid --depth %d: expected at least 1", depth)
+		}
+		l := &lister{out: cmd.OutOrStdout()}
+		l.human, err = cmd.Flags().GetBool("human-readable")
+		if err != nil {
+			return fmt.Errorf("Error parsing human-readable: %v", err)
+		}
+		asJSON, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			return fmt.Errorf("Error parsing json: %v", err)
+		}
+		if asJSON {
+			l.json = json.NewEncoder(l.out)
+		}
+
+		u, err := cfg.Parse(args[0])
+		if err != nil {
+			return err
+		}
+		usage, err := newUsage(u, groupBy, depth, time.Now())
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		s, err := newStore(ctx, u, storeSettings(cmd, cfg, u))
+		if err != nil {
+			return err
+		}
+		err = walkObjects(ctx, s, u, true, func(obj store.ObjectInfo) error {
+			if !obj.IsPrefix && !strings.HasSuffix(obj.Key, "/") {
+				usage.add(obj)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return usage.print(l)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(duCmd)
+	duCmd.Flags().String("group-by", "", "Group the totals by prefix, storage-class or age")
+	duCmd.Flags().Int("depth", 1, "Number of path levels below the URI that make up a prefix group")
+	duCmd.Flags().Bool("human-readable", false, "Print sizes as KiB, MiB, GiB")
+	duCmd.Flags().Bool("json", false, "Print one JSON object per group and one for the total")
+}
+
+// ageBuckets are the groups of --group-by age, youngest first.
+var ageBuckets = []struct {
+	name   string
+	before time.Duration
+}{
+	{"<1d", 24 * time.Hour},
+	{"1d-7d", 7 * 24 * time.Hour},
+	{"7d-30d", 30 * 24 * time.Hour},
+	{"30d-90d", 90 * 24 * time.Hour},
+	{"90d-1y", 365 * 24 * time.Hour},
+	{">1y", 0},
+}
+
+// usage totals the objects below a URI by group.
+type usage struct {
+	base    uri.URI
+	groupBy string
+	depth   int
+	now     time.Time
+	// prefix is the part of the keys that groups by prefix leave out.
+	prefix string
+
+	groups map[string]*usageTotal
+	total  usageTotal
+}
+
+type usageTotal struct {
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+func newUsage(base uri.URI, groupBy string, depth int, now time.Time) (*usage, error) {
+	switch groupBy {
+	case "", "prefix", "storage-class", "age":
+	default:
+		return nil, fmt.Errorf("Invalid --group-by %q: expected prefix, storage-class or age", groupBy)
+	}
+	u := &usage{base: base, groupBy: groupBy, depth: depth, now: now, groups: map[string]*usageTotal{}}
+	u.prefix = base.Prefix()
+	if base.HasWildcard() {
+		u.prefix = u.prefix[:strings.LastIndex(u.prefix, "/")+1]
+	} else if u.prefix != "" && !strings.HasSuffix(u.prefix, "/") {
+		u.prefix += "/"
+	}
+	return u, nil
+}
+
+func (u *usage) add(obj store.ObjectInfo) {
+	u.total.Objects++
+	u.total.Bytes += obj.Size
+	if u.groupBy == "" {
+		return
+	}
+	name := u.group(obj)
+	g, ok := u.groups[name]
+	if !ok {
+		g = &usageTotal{}
+		u.groups[name] = g
+	}
+	g.Objects++
+	g.Bytes += obj.Size
+}
+
+// group returns the name of the group obj belongs to.
+func (u *usage) group(obj store.ObjectInfo) string {
+	switch u.groupBy {
+	case "storage-class":
+		return orDash(obj.StorageClass)
+	case "age":
+		age := u.now.Sub(obj.ModTime)
+		for _, b := range ageBuckets {
+			if b.before == 0 || age < b.before {
+				return b.name
+			}
+		}
+	}
+
+	// A single object is its own group; objects above the depth are
+	// counted in their own directory.
+	if !strings.HasPrefix(obj.Key, u.prefix) {
+		return u.base.String()
+	}
+	dirs := strings.Split(strings.TrimPrefix(obj.Key, u.prefix), "/")
+	dirs = dirs[:len(dirs)-1]
+	if len(dirs) > u.depth {
+		dirs = dirs[:u.depth]
+	}
+	dir := u.base
+	dir.Key, dir.VersionID = u.prefix, ""
+	if len(dirs) > 0 {
+		dir.Key += strings.Join(dirs, "/") + "/"
+	}
+	return dir.String()
+}
+
+// names returns the groups in print order: by age, or else by name.
+func (u *usage) names() []string {
+	var names []string
+	if u.groupBy == "age" {
+		for _, b := range ageBuckets {
+			if _, ok := u.groups[b.name]; ok {
+				names = append(names, b.name)
+			}
+		}
+		return names
+	}
+	for name := range u.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (u *usage) print(l *lister) error {
+	if l.json != nil {
+		for _, name := range u.names() {
+			if err := l.json.Encode(duEntry{Group: name, usageTotal: *u.groups[name]}); err != nil {
+				return err
+			}
+		}
+		return l.json.Encode(duEntry{Group: u.base.String(), Total: true, usageTotal: u.total})
+	}
+
+	for _, name := range u.names() {
+		g := u.groups[name]
+		fmt.Fprintf(l.out, "%12s  %10d  %s\n", l.size(g.Bytes), g.Objects, name)
+	}
+	label := u.base.String()
+	if u.groupBy != "" {
+		label = "TOTAL"
+	}
+	_, err := fmt.Fprintf(l.out, "%12s  %10d  %s\n", l.size(u.total.Bytes), u.total.Objects, label)
+	return err
+}
+
+// duEntry is the JSON form of a du group, or of the total.
+type duEntry struct {
+	Group string `json:"group"`
+	Total bool   `json:"total,omitempty"`
+	usageTotal
+}
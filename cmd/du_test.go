@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDu(t *testing.T) {
+	fakes := useFakeStores(t)
+	gcs := fakes[uri.SchemeGCS]
+	gcs.Put("bucket", "data/top.csv", []byte("1"))
+	gcs.Put("bucket", "data/logs/2024/a.gz", []byte("aaaa"))
+	gcs.Put("bucket", "data/logs/2023/b.gz", []byte("bb"))
+	gcs.Put("bucket", "data/images/c.png", []byte("ccc"))
+	gcs.Touch("bucket", "data/logs/2023/b.gz", time.Now().Add(-400*24*time.Hour))
+
+	out, err := runWithInput(t, "", "du", "gs://bucket/data/")
+	assert.NoError(t, err)
+	assert.Equal(t, "          10           4  gs://bucket/data/\n", out)
+
+	out, err = runWithInput(t, "", "du", "--group-by", "prefix", "gs://bucket/data")
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"           1           1  gs://bucket/data/",
+		"           3           1  gs://bucket/data/images/",
+		"           6           2  gs://bucket/data/logs/",
+		"          10           4  TOTAL",
+	}, "\n")+"\n", out)
+
+	out, err = runWithInput(t, "", "du", "--group-by", "prefix", "--depth", "2", "gs://bucket/data/logs/**.gz")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "           2           1  gs://bucket/data/logs/2023/\n")
+
+	out, err = runWithInput(t, "", "du", "--group-by", "age", "--json", "gs://bucket/data/")
+	assert.NoError(t, err)
+	var entries []duEntry
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var e duEntry
+		assert.NoError(t, json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+	assert.Equal(t, []duEntry{
+		{Group: "<1d", usageTotal: usageTotal{Objects: 3, Bytes: 8}},
+		{Group: ">1y", usageTotal: usageTotal{Objects: 1, Bytes: 2}},
+		{Group: "gs://bucket/data/", Total: true, usageTotal: usageTotal{Objects: 4, Bytes: 10}},
+	}, entries)
+
+	out, err = runWithInput(t, "", "du", "--group-by", "storage-class", "gs://bucket/data/")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "          10           4  -\n")
+
+	_, err = runWithInput(t, "", "du", "--group-by", "owner", "gs://bucket/data/")
+	assert.ErrorContains(t, err, "Invalid --group-by")
+}
+
+func TestStat(t *testing.T) {
+	fakes := useFakeStores(t)
+	s3 := fakes[uri.SchemeS3]
+	err := s3.Write(context.Background(), "bucket", "dir/a.txt", strings.NewReader("hello world"),
+		store.WriteOptions{Metadata: map[string]string{"owner": "data", "app": "etl"}})
+	assert.NoError(t, err)
+
+	out, err := runWithInput(t, "", "stat", "s3://bucket/dir/a.txt")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "URI:                 s3://bucket/dir/a.txt\nSize:                11\n")
+	assert.Contains(t, out, "MD5:                 5eb63bbbe01eeed093cb22bb8f5acdc3\n")
+	assert.Contains(t, out, "CRC32C:              c99465aa\n")
+	assert.Contains(t, out, "Storage-Class:       -\n")
+	assert.Contains(t, out, "Metadata:\n  app: etl\n  owner: data\n")
+
+	out, err = runWithInput(t, "", "stat", "--json", "s3://bucket/dir/a.txt")
+	assert.NoError(t, err)
+	var entry lsEntry
+	assert.NoError(t, json.Unmarshal([]byte(out), &entry))
+	assert.Equal(t, int64(11), *entry.Size)
+	assert.Equal(t, map[string]string{"owner": "data", "app": "etl"}, entry.Metadata)
+
+	_, err = runWithInput(t, "", "stat", "s3://bucket/dir/")
+	assert.ErrorContains(t, err, "single object")
+	_, err = runWithInput(t, "", "stat", "s3://bucket/dir/missing")
+	assert.Error(t, err)
+}
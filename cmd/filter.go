@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/filter"
+	"github.com/spf13/cobra"
+)
+
+// addFilterFlags defines the flags that select the source objects.
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("include", nil,
+		"Only transfer keys matching this glob (repeatable); \"**\" crosses directories")
+	cmd.Flags().StringArray("exclude", nil,
+		"Skip keys matching this glob (repeatable)")
+	cmd.Flags().StringArray("include-regex", nil,
+		"Only transfer keys matching this regular expression (repeatable)")
+	cmd.Flags().StringArray("exclude-regex", nil,
+		"Skip keys matching this regular expression (repeatable)")
+	cmd.Flags().StringArray("include-from", nil,
+		"Read include globs from this file, one per line")
+	cmd.Flags().StringArray("exclude-from", nil,
+		"Read exclude globs from this file, one per line")
+	cmd.Flags().String("min-size", "0", "Skip objects smaller than this, e.g. 1MiB")
+	cmd.Flags().String("max-size", "0", "Skip objects larger than this, e.g. 1GiB (default: no limit)")
+	cmd.Flags().String("newer-than", "",
+		"Only transfer objects modified within this age (e.g. 7d) or since this date (e.g. 2024-05-01)")
+	cmd.Flags().String("older-than", "",
+		"Only transfer objects modified before this age (e.g. 30d) or date")
+}
+
+// sourceFilter reads the flags defined by addFilterFlags. It returns nil
+// when they select every object.
+func sourceFilter(cmd *cobra.Command) (*filter.Filter, error) {
+	var opts filter.Options
+	var err error
+
+	for name, value := range map[string]*[]string{
+		"include":       &opts.Include,
+		"exclude":       &opts.Exclude,
+		"include-regex": &opts.IncludeRegex,
+		"exclude-regex": &opts.ExcludeRegex,
+		"include-from":  &opts.IncludeFrom,
+		"exclude-from":  &opts.ExcludeFrom,
+	} {
+		*value, err = cmd.Flags().GetStringArray(name)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %v", name, err)
+		}
+	}
+	opts.MinSize, err = sizeFlag(cmd, "min-size")
+	if err != nil {
+		return nil, err
+	}
+	opts.MaxSize, err = sizeFlag(cmd, "max-size")
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	opts.NewerThan, err = timeFlag(cmd, "newer-than", now)
+	if err != nil {
+		return nil, err
+	}
+	opts.OlderThan, err = timeFlag(cmd, "older-than", now)
+	if err != nil {
+		return nil, err
+	}
+	return filter.New(opts)
+}
+
+// timeFlag reads a flag holding an age or a date; unset flags yield the
+// zero time.
+func timeFlag(cmd *cobra.Command, name string, now time.Time) (time.Time, error) {
+	value, err := cmd.Flags().GetString(name)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Error parsing %s: %v", name, err)
+	}
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := filter.ParseTime(value, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Error parsing %s: %v", name, err)
+	}
+	return t, nil
+}
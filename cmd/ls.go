@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/config"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/RA-Balaji/storage-synk/utils"
+	"github.com/spf13/cobra"
+)
+
+var lsCmd = &cobra.Command{
+	Use:   "ls [uri]",
+	Short: "lists buckets, or objects and prefixes under a bucket path",
+	Long: `Lists the buckets of a provider (s3://, gs://, az://account or remote:),
+or the objects and prefixes under a bucket path. Without a URI the remotes of
+the config file are listed.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		l, err := newLister(cmd)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return l.remotes(cfg)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if root, ok := cfg.ParseRoot(args[0]); ok {
+			s, err := newStore(ctx, root, storeSettings(cmd, cfg, root))
+			if err != nil {
+				return err
+			}
+			return l.buckets(ctx, s, root)
+		}
+
+		u, err := cfg.Parse(args[0])
+		if err != nil {
+			return err
+		}
+		s, err := newStore(ctx, u, storeSettings(cmd, cfg, u))
+		if err != nil {
+			return err
+		}
+		if err := l.objects(ctx, s, u); err != nil {
+			return err
+		}
+		return l.total()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(lsCmd)
+	lsCmd.Flags().BoolP("recursive", "r", false, "List every object below the path instead of one level")
+	lsCmd.Flags().BoolP("long", "l", false, "Show size, modification time, storage class and checksum")
+	lsCmd.Flags().Bool("human-readable", false, "Print sizes as KiB, MiB, GiB")
+	lsCmd.Flags().Bool("json", false, "Print one JSON object per line")
+}
+
+// lister writes listing entries as they arrive, so that huge buckets are
+// never held in memory.
+type lister struct {
+	out                    io.Writer
+	recursive, long, human bool
+	json                   *json.Encoder
+	// objectCount and bytes total the objects listed so far.
+	objectCount, bytes int64
+}
+
+func newLister(cmd *cobra.Command) (*lister, error) {
+	l := &lister{out: cmd.OutOrStdout()}
+	var err error
+	for name, value := range map[string]*bool{
+		"recursive":      &l.recursive,
+		"long":           &l.long,
+		"human-readable": &l.human,
+	} {
+		*value, err = cmd.Flags().GetBool(name)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %v", name, err)
+		}
+	}
+	asJSON, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing json: %v", err)
+	}
+	if asJSON {
+		l.json = json.NewEncoder(l.out)
+	}
+	return l, nil
+}
+
+// lsEntry is the JSON form of a listed object, prefix or bucket.
+type lsEntry struct {
+	URI          string            `json:"uri"`
+	Prefix       bool              `json:"prefix,omitempty"`
+	Bucket       bool              `json:"bucket,omitempty"`
+	Size         *int64            `json:"size,omitempty"`
+	ModTime      string            `json:"mtime,omitempty"`
+	Created      string            `json:"created,omitempty"`
+	Location     string            `json:"location,omitempty"`
+	StorageClass string            `json:"storage_class,omitempty"`
+	ETag         string            `json:"etag,omitempty"`
+	MD5          string            `json:"md5,omitempty"`
+	CRC32C       string            `json:"crc32c,omitempty"`
+	SHA256       string            `json:"sha256,omitempty"`
+	VersionID    string            `json:"version_id,omitempty"`
+	ContentType  string            `json:"content_type,omitempty"`
+	CacheControl string            `json:"cache_control,omitempty"`
+	Encoding     string            `json:"content_encoding,omitempty"`
+	Disposition  string            `json:"content_disposition,omitempty"`
+	Encryption   string            `json:"encryption,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// objectEntry returns the JSON form of obj, found at u.
+func objectEntry(u uri.URI, obj store.ObjectInfo) lsEntry {
+	size := obj.Size
+	return lsEntry{
+		URI:          u.String(),
+		Size:         &size,
+		ModTime:      formatTime(obj.ModTime),
+		StorageClass: obj.StorageClass,
+		ETag:         obj.ETag,
+		MD5:          obj.MD5,
+		CRC32C:       obj.CRC32C,
+		SHA256:       obj.SHA256,
+		VersionID:    obj.VersionID,
+		ContentType:  obj.ContentType,
+		CacheControl: obj.CacheControl,
+		Encoding:     obj.ContentEncoding,
+		Disposition:  obj.ContentDisposition,
+		Encryption:   obj.Encryption,
+		Metadata:     obj.Metadata,
+	}
+}
+
+func (l *lister) remotes(cfg *config.Config) error {
+	names := make([]string, 0, len(cfg.Remotes))
+	for name := range cfg.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if l.json != nil {
+			err := l.json.Encode(struct {
+				Name string `json:"name"`
+				Type string `json:"type"`
+			}{name, cfg.Remotes[name].Type})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		fmt.Fprintf(l.out, "%-20s %s\n", name+":", cfg.Remotes[name].Type)
+	}
+	return nil
+}
+
+func (l *lister) buckets(ctx context.Context, s store.ObjectStore, root uri.URI) error {
+	bl, ok := s.(store.BucketLister)
+	if !ok {
+		return fmt.Errorf("Buckets cannot be listed for %s", root)
+	}
+	return bl.ListBuckets(ctx, func(b store.BucketInfo) error {
+		u := uri.URI{Scheme: root.Scheme, Account: root.Account, Bucket: b.Name}
+		if l.json != nil {
+			return l.json.Encode(lsEntry{
+				URI:          u.String(),
+				Bucket:       true,
+				Created:      formatTime(b.Created),
+				Location:     b.Location,
+				StorageClass: b.StorageClass,
+			})
+		}
+		if l.long {
+			_, err := fmt.Fprintf(l.out, "%-20s  %-14s  %-13s  %s\n",
+				orDash(formatTime(b.Created)), orDash(b.Location), orDash(b.StorageClass), u)
+			return err
+		}
+		_, err := fmt.Fprintln(l.out, u.String())
+		return err
+	})
+}
+
+// objects lists u: a single object, or the entries below a prefix that
+// match the wildcard pattern of u, if any.
+func (l *lister) objects(ctx context.Context, s store.ObjectStore, u uri.URI) error {
+	return walkObjects(ctx, s, u, l.recursive, func(obj store.ObjectInfo) error {
+		return l.entry(u, obj)
+	})
+}
+
+// walkObjects calls fn for the object u names, or for the objects and
+// prefixes below u that match its wildcard pattern, if any. Entries are
+// passed on as the store lists them, a page at a time.
+func walkObjects(ctx context.Context, s store.ObjectStore, u uri.URI, recursive bool, fn store.WalkFunc) error {
+	if u.VersionID != "" {
+		versioned, ok := s.(store.VersionedStore)
+		if !ok {
+			return fmt.Errorf("Object versions are not supported for %s", u)
+		}
+		info, err := versioned.StatVersion(ctx, u.Bucket, u.Key, u.VersionID)
+		if err != nil {
+			return err
+		}
+		return fn(info)
+	}
+
+	prefix := u.Prefix()
+	if u.Key != "" && !u.IsDir() && !u.HasWildcard() {
+		info, err := s.Stat(ctx, u.Bucket, u.Key)
+		if err == nil {
+			return fn(info)
+		}
+		if !store.IsNotExist(err) {
+			return err
+		}
+		prefix += "/"
+	}
+
+	// A pattern that stays within one level is listed like a directory;
+	// others need every key below the prefix.
+	var pattern func(string) bool
+	if u.HasWildcard() {
+		rest := u.Key[len(prefix):]
+		if strings.Contains(rest, "/") || strings.Contains(rest, "**") {
+			recursive = true
+		}
+		pattern = u.Match
+	}
+
+	return s.List(ctx, u.Bucket, prefix, store.ListOptions{Recursive: recursive}, func(obj store.ObjectInfo) error {
+		if pattern != nil && !pattern(strings.TrimSuffix(obj.Key, "/")) {
+			return nil
+		}
+		return fn(obj)
+	})
+}
+
+// entry writes one object or prefix found when listing u.
+func (l *lister) entry(u uri.URI, obj store.ObjectInfo) error {
+	u.Key, u.VersionID = obj.Key, obj.VersionID
+	if obj.IsPrefix {
+		if l.json != nil {
+			return l.json.Encode(lsEntry{URI: u.String(), Prefix: true})
+		}
+		if l.long {
+			_, err := fmt.Fprintf(l.out, "%12s  %-20s  %-13s  %-41s  %s\n", "", "", "", "", u)
+			return err
+		}
+		_, err := fmt.Fprintln(l.out, u.String())
+		return err
+	}
+
+	l.objectCount++
+	l.bytes += obj.Size
+	if l.json != nil {
+		return l.json.Encode(objectEntry(u, obj))
+	}
+	if l.long {
+		_, err := fmt.Fprintf(l.out, "%12s  %-20s  %-13s  %-41s  %s\n",
+			l.size(obj.Size), orDash(formatTime(obj.ModTime)), orDash(obj.StorageClass), checksumOf(obj), u)
+		return err
+	}
+	_, err := fmt.Fprintln(l.out, u.String())
+	return err
+}
+
+// total ends a long listing with the number of objects and their size.
+func (l *lister) total() error {
+	if !l.long || l.json != nil {
+		return nil
+	}
+	_, err := fmt.Fprintf(l.out, "TOTAL: %d objects, %s\n", l.objectCount, l.size(l.bytes))
+	return err
+}
+
+func (l *lister) size(n int64) string {
+	if l.human {
+		return utils.FormatSize(n)
+	}
+	return fmt.Sprint(n)
+}
+
+// checksumOf returns the best checksum the store reported for obj.
+func checksumOf(obj store.ObjectInfo) string {
+	switch {
+	case obj.MD5 != "":
+		return "md5:" + obj.MD5
+	case obj.CRC32C != "":
+		return "crc32c:" + obj.CRC32C
+	case obj.ETag != "":
+		return "etag:" + strings.Trim(obj.ETag, `"`)
+	}
+	return "-"
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/config"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/stretchr/testify/assert"
+)
+
+// runLs runs ls with args and returns what it printed.
+func runLs(t *testing.T, args ...string) (string, error) {
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+	err := runCommand(t, append([]string{"ls"}, args...)...)
+	return out.String(), err
+}
+
+func TestLs(t *testing.T) {
+	fakes := useFakeStores(t)
+	gcs := fakes[uri.SchemeGCS]
+	gcs.Put("bucket", "logs/a.gz", []byte("aaaa"))
+	gcs.Put("bucket", "logs/b.txt", []byte("bb"))
+	gcs.Put("bucket", "logs/2024/c.gz", []byte("c"))
+	gcs.Put("other", "x", []byte("x"))
+
+	out, err := runLs(t, "gs://")
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://bucket\ngs://other\n", out)
+
+	out, err = runLs(t, "gs://bucket/logs/")
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://bucket/logs/2024/\ngs://bucket/logs/a.gz\ngs://bucket/logs/b.txt\n", out)
+
+	// Without a trailing "/" a key that is no object is a directory.
+	out, err = runLs(t, "gs://bucket/logs", "-r")
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://bucket/logs/2024/c.gz\ngs://bucket/logs/a.gz\ngs://bucket/logs/b.txt\n", out)
+
+	out, err = runLs(t, "gs://bucket/logs/*.gz")
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://bucket/logs/a.gz\n", out)
+	out, err = runLs(t, "gs://bucket/logs/**.gz")
+	assert.NoError(t, err)
+	assert.Equal(t, "gs://bucket/logs/2024/c.gz\ngs://bucket/logs/a.gz\n", out)
+
+	out, err = runLs(t, "-l", "gs://bucket/logs/a.gz")
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Len(t, lines, 2)
+	assert.Regexp(t, `^\s*4  \d{4}-\d\d-\d\dT\S+Z\s+-\s+md5:[0-9a-f]{32}\s+gs://bucket/logs/a.gz$`, lines[0])
+	assert.Equal(t, "TOTAL: 1 objects, 4", lines[1])
+
+	out, err = runLs(t, "-lr", "--human-readable", "gs://bucket/")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "TOTAL: 3 objects, 7 B\n")
+}
+
+func TestLsJSON(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeS3].Put("bucket", "dir/a.txt", []byte("aaa"))
+	fakes[uri.SchemeS3].Put("bucket", "dir/sub/b.txt", []byte("b"))
+
+	out, err := runLs(t, "--json", "s3://bucket/dir/")
+	assert.NoError(t, err)
+	var entries []lsEntry
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var e lsEntry
+		assert.NoError(t, json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "s3://bucket/dir/a.txt", entries[0].URI)
+	assert.Equal(t, int64(3), *entries[0].Size)
+	assert.NotEmpty(t, entries[0].MD5)
+	assert.Equal(t, lsEntry{URI: "s3://bucket/dir/sub/", Prefix: true}, entries[1])
+}
+
+func TestLsRemotes(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeS3].Put("bucket", "a.txt", []byte("a"))
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(configFile, []byte("remotes:\n  prod-s3:\n    type: s3\n  scratch:\n    type: local\n"), 0600))
+	t.Setenv(config.EnvPath, configFile)
+
+	out, err := runLs(t)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-s3:             s3\nscratch:             local\n", out)
+
+	out, err = runLs(t, "prod-s3:")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3://bucket\n", out)
+	out, err = runLs(t, "prod-s3:bucket")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3://bucket/a.txt\n", out)
+
+	_, err = runLs(t, "file:///tmp/missing/dir/")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/transfer"
+	"github.com/RA-Balaji/storage-synk/utils"
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+var rmCmd = &cobra.Command{
+	Use:   "rm [-r] uri",
+	Short: "removes objects, or everything under a prefix with -r",
+	Long: `Removes an object, the objects matching a wildcard, or with -r every
+object under a prefix. S3 objects are deleted in batches of 1000 keys,
+other backends one object per request, --concurrency requests at a time.
+A URI with a version removes only that version.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		opts, err := removeOptions(cmd)
+		if err != nil {
+			return err
+		}
+		recursive, err := cmd.Flags().GetBool("recursive")
+		if err != nil {
+			return fmt.Errorf("Error parsing recursive: %v", err)
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("Error parsing dry-run: %v", err)
+		}
+
+		u, err := cfg.Parse(args[0])
+		if err != nil {
+			return err
+		}
+		if u.Bucket == "" && u.Key == "" {
+			return fmt.Errorf("Nothing to remove at %s: give a bucket path", args[0])
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		var plan *dryrun.Plan
+		if dryRun {
+			plan = dryrun.NewPlan()
+			ctx = dryrun.WithPlan(ctx, plan)
+		}
+
+		s, err := newStore(ctx, u, storeSettings(cmd, cfg, u))
+		if err != nil {
+			return err
+		}
+		target, err := transfer.NewEndpoint(s, u)
+		if err != nil {
+			return err
+		}
+		objects, err := transfer.List(ctx, target, opts)
+		if err != nil {
+			return err
+		}
+		if len(objects) == 0 {
+			return fmt.Errorf("No objects found at %s", u)
+		}
+		// Without -r only a single object or the matches of a wildcard
+		// are removed.
+		if !recursive && target.Pattern == nil && (len(objects) > 1 || objects[0].Key != target.Prefix) {
+			return fmt.Errorf("%s is a prefix with %d objects below it: use -r to remove them", u, len(objects))
+		}
+
+		if plan != nil {
+			if _, err := transfer.Remove(ctx, target, objects, opts); err != nil {
+				return err
+			}
+			plan.Print(cmd.OutOrStdout())
+			return nil
+		}
+		ok, err := confirmRemove(cmd, u.String(), objects)
+		if err != nil || !ok {
+			return err
+		}
+
+		removed, err := transfer.Remove(ctx, target, objects, opts)
+		for _, r := range removed {
+			fmt.Fprintf(cmd.OutOrStdout(), "removed: %s\n", r)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed %d of %d objects\n", len(removed), len(objects))
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rmCmd)
+	rmCmd.Flags().BoolP("recursive", "r", false, "Remove every object under the prefix")
+	rmCmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	rmCmd.Flags().Int("confirm-above", 100,
+		"Ask for confirmation on a terminal before removing more than this many objects")
+	rmCmd.Flags().Int("concurrency", 10, "Number of delete requests in parallel")
+	rmCmd.Flags().Bool("dry-run", false, "Print what would be removed without removing anything")
+	rmCmd.Flags().Bool("fail-fast", false,
+		"Stop at the first failed object instead of attempting all of them")
+	addFilterFlags(rmCmd)
+	rmCmd.Flags().Int("max-attempts", 5,
+		"Attempts per request on throttling and transient errors (1 disables retries)")
+	rmCmd.Flags().Duration("retry-deadline", 0,
+		"Stop retrying a request after this long, e.g. 10m (default: no limit)")
+}
+
+// removeOptions reads the flags of rm that transfer.Remove uses.
+func removeOptions(cmd *cobra.Command) (transfer.Options, error) {
+	var opts transfer.Options
+	var err error
+
+	opts.Concurrency, err = cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing concurrency: %v", err)
+	}
+	opts.FailFast, err = cmd.Flags().GetBool("fail-fast")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing fail-fast: %v", err)
+	}
+	opts.Filter, err = sourceFilter(cmd)
+	if err != nil {
+		return opts, err
+	}
+	opts.Retry.MaxAttempts, err = cmd.Flags().GetInt("max-attempts")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing max-attempts: %v", err)
+	}
+	opts.Retry.Deadline, err = cmd.Flags().GetDuration("retry-deadline")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing retry-deadline: %v", err)
+	}
+	return opts, nil
+}
+
+// stdinIsTerminal is a variable so that tests can simulate a terminal.
+var stdinIsTerminal = func() bool {
+	return isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd())
+}
+
+// confirmRemove asks before more than --confirm-above objects are removed,
+// unless --yes is given or stdin is not a terminal.
+func confirmRemove(cmd *cobra.Command, target string, objects []store.ObjectInfo) (bool, error) {
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return false, fmt.Errorf("Error parsing yes: %v", err)
+	}
+	threshold, err := cmd.Flags().GetInt("confirm-above")
+	if err != nil {
+		return false, fmt.Errorf("Error parsing confirm-above: %v", err)
+	}
+	if yes || len(objects) <= threshold || !stdinIsTerminal() {
+		return true, nil
+	}
+
+	var size int64
+	for _, obj := range objects {
+		size += obj.Size
+	}
+	return confirm(cmd, fmt.Sprintf("Remove %d objects (%s) from %s?",
+		len(objects), utils.FormatSize(size), target)), nil
+}
+
+// confirm asks question on stderr and reports whether it was answered
+// with yes.
+func confirm(cmd *cobra.Command, question string) bool {
+	fmt.Fprintf(cmd.ErrOrStderr(), "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	fmt.Fprintln(cmd.ErrOrStderr(), "Nothing removed")
+	return false
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/stretchr/testify/assert"
+)
+
+// runWithInput runs a command with args reading stdin and returns what it
+// printed.
+func runWithInput(t *testing.T, stdin string, args ...string) (string, error) {
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetIn(strings.NewReader(stdin))
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetIn(nil)
+	})
+	err := runCommand(t, args...)
+	return out.String(), err
+}
+
+func TestRm(t *testing.T) {
+	fakes := useFakeStores(t)
+	gcs := fakes[uri.SchemeGCS]
+	gcs.Put("bucket", "logs/a.gz", []byte("aaaa"))
+	gcs.Put("bucket", "logs/b.txt", []byte("bb"))
+	gcs.Put("bucket", "logs/2024/c.gz", []byte("c"))
+	gcs.Put("bucket", "keep.txt", []byte("k"))
+
+	_, err := runWithInput(t, "", "rm", "gs://bucket/logs/")
+	assert.ErrorContains(t, err, "use -r")
+
+	out, err := runWithInput(t, "", "rm", "-r", "--dry-run", "gs://bucket/logs/")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "gs://bucket/logs/2024/c.gz")
+	assert.Len(t, gcs.Keys("bucket"), 4)
+
+	out, err = runWithInput(t, "", "rm", "gs://bucket/logs/b.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "removed: gs://bucket/logs/b.txt\nRemoved 1 of 1 objects\n", out)
+
+	out, err = runWithInput(t, "", "rm", "-r", "--exclude", "2024/", "gs://bucket/logs/")
+	assert.NoError(t, err)
+	assert.Equal(t, "removed: gs://bucket/logs/a.gz\nRemoved 1 of 1 objects\n", out)
+	assert.Equal(t, []string{"keep.txt", "logs/2024/c.gz"}, gcs.Keys("bucket"))
+
+	_, err = runWithInput(t, "", "rm", "gs://bucket/missing")
+	assert.ErrorContains(t, err, "No objects found")
+}
+
+func TestRmConfirms(t *testing.T) {
+	fakes := useFakeStores(t)
+	s3 := fakes[uri.SchemeS3]
+	s3.Put("bucket", "data/a", []byte("a"))
+	s3.Put("bucket", "data/b", []byte("b"))
+
+	orig := stdinIsTerminal
+	stdinIsTerminal = func() bool { return true }
+	t.Cleanup(func() { stdinIsTerminal = orig })
+
+	out, err := runWithInput(t, "n\n", "rm", "-r", "--confirm-above", "1", "s3://bucket/data/")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Remove 2 objects (2 B) from s3://bucket/data/? [y/N] Nothing removed")
+	assert.Len(t, s3.Keys("bucket"), 2)
+
+	out, err = runWithInput(t, "y\n", "rm", "-r", "--confirm-above", "1", "s3://bucket/data/")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Removed 2 of 2 objects")
+	assert.Empty(t, s3.Keys("bucket"))
+}
@@ -16,11 +16,17 @@ var rootCmd = &cobra.Command{
 		color.Green("Welcome To:")
 		color.Cyan(banner.Inline("storage-synk"))
 	},
+	// Errors are printed once by Execute. Usage is only shown for
+	// mistakes on the command line, not for failures while running.
+	SilenceErrors: true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		cmd.SilenceUsage = true
+	},
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
 		os.Exit(1)
 	}
 }
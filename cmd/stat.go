@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/spf13/cobra"
+)
+
+var statCmd = &cobra.Command{
+	Use:   "stat uri",
+	Short: "prints every metadata field of an object",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		asJSON, err := cmd.Flags().GetBool("json")
+		if err != nil {
+			return fmt.Errorf("Error parsing json: %v", err)
+		}
+
+		u, err := cfg.Parse(args[0])
+		if err != nil {
+			return err
+		}
+		if u.Key == "" || u.IsDir() || u.HasWildcard() {
+			return fmt.Errorf("stat needs a single object, got %s: use ls or du for prefixes", u)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		s, err := newStore(ctx, u, storeSettings(cmd, cfg, u))
+		if err != nil {
+			return err
+		}
+		var info store.ObjectInfo
+		if u.VersionID != "" {
+			versioned, ok := s.(store.VersionedStore)
+			if !ok {
+				return fmt.Errorf("Object versions are not supported for %s", u)
+			}
+			info, err = versioned.StatVersion(ctx, u.Bucket, u.Key, u.VersionID)
+		} else {
+			info, err = s.Stat(ctx, u.Bucket, u.Key)
+		}
+		if err != nil {
+			return err
+		}
+
+		u.VersionID = info.VersionID
+		if asJSON {
+			return json.NewEncoder(cmd.OutOrStdout()).Encode(objectEntry(u, info))
+		}
+		out := cmd.OutOrStdout()
+		for _, field := range []struct{ name, value string }{
+			{"URI", u.String()},
+			{"Size", fmt.Sprint(info.Size)},
+			{"Modified", formatTime(info.ModTime)},
+			{"Content-Type", info.ContentType},
+			{"Cache-Control", info.CacheControl},
+			{"Content-Encoding", info.ContentEncoding},
+			{"Content-Disposition", info.ContentDisposition},
+			{"Storage-Class", info.StorageClass},
+			{"ETag", info.ETag},
+			{"MD5", info.MD5},
+			{"CRC32C", info.CRC32C},
+			{"SHA256", info.SHA256},
+			{"Encryption", info.Encryption},
+			{"Version", info.VersionID},
+		} {
+			fmt.Fprintf(out, "%-20s %s\n", field.name+":", orDash(field.value))
+		}
+		if info.Mode != 0 {
+			fmt.Fprintf(out, "%-20s %s\n", "Mode:", info.Mode)
+		}
+
+		keys := make([]string, 0, len(info.Metadata))
+		for key := range info.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		if len(keys) == 0 {
+			_, err = fmt.Fprintf(out, "%-20s -\n", "Metadata:")
+			return err
+		}
+		fmt.Fprintln(out, "Metadata:")
+		for _, key := range keys {
+			fmt.Fprintf(out, "  %s: %s\n", key, info.Metadata[key])
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statCmd)
+	statCmd.Flags().Bool("json", false, "Print the fields as a JSON object")
+}
@@ -4,172 +4,673 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"regexp"
-	"sync"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/RA-Balaji/storage-synk/aws"
+	"github.com/RA-Balaji/storage-synk/azure"
+	"github.com/RA-Balaji/storage-synk/bwlimit"
+	"github.com/RA-Balaji/storage-synk/config"
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/encrypt"
 	"github.com/RA-Balaji/storage-synk/gcp"
-	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/RA-Balaji/storage-synk/journal"
+	"github.com/RA-Balaji/storage-synk/local"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/transfer"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/RA-Balaji/storage-synk/utils"
 	"github.com/spf13/cobra"
 )
 
-var csp, source, dest string
-
-const (
-	cspGcp   = "gcp"
-	cspAws   = "aws"
-	srcLocal = "local"
-)
-
 var cpCmd = &cobra.Command{
-	Use:   "cp",
+	Use:   "cp [source] [destination]",
+	Args:  cobra.MaximumNArgs(2),
 	Short: "copies files/folder between source and destination",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		source, err := cmd.Flags().GetString("source")
+		source, destination, err := locations(cmd, args)
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		opts, err := transferOptions(cmd)
+		if err != nil {
+			return err
+		}
+		resume, err := cmd.Flags().GetString("resume")
 		if err != nil {
-			return fmt.Errorf("Source incorrect, error: %v", err)
+			return fmt.Errorf("Error parsing resume: %v", err)
 		}
-		destination, err := cmd.Flags().GetString("destination")
+		resumable, err := cmd.Flags().GetBool("resumable")
 		if err != nil {
-			return fmt.Errorf("Destination incorrect, error: %v", err)
+			return fmt.Errorf("Error parsing resumable: %v", err)
 		}
-		tmpPath, err := cmd.Flags().GetString("destination")
+		dryRun, err := cmd.Flags().GetBool("dry-run")
 		if err != nil {
-			return fmt.Errorf("Error loading temp path: %v", err)
+			return fmt.Errorf("Error parsing dry-run: %v", err)
+		}
+
+		jobDir, err := journal.DefaultDir()
+		if err != nil {
+			return err
+		}
+		var job *journal.Journal
+		if resume != "" {
+			job, err = journal.Open(jobDir, resume)
+			if err != nil {
+				return err
+			}
+			defer job.Close()
+			if (source != "" && source != job.Source) || (destination != "" && destination != job.Destination) {
+				return fmt.Errorf("Job %s copies %s to %s", job.ID, job.Source, job.Destination)
+			}
+			source, destination = job.Source, job.Destination
+		}
+
+		src, dst, err := parseSrcDst(cfg, source, destination)
+		if err != nil {
+			return err
+		}
+		if err := applyRemoteSettings(cmd, cfg, src, dst, &opts); err != nil {
+			return err
+		}
+
+		// A dry run of a resumed job reports the objects the job already
+		// copied as skipped, but never records anything itself.
+		opts.Journal = job
+		if dryRun {
+			plan := dryrun.NewPlan()
+			ctx := dryrun.WithPlan(context.Background(), plan)
+			srcEndpoint, dstEndpoint, err := openEndpoints(ctx, cmd, cfg, src, dst)
+			if err != nil {
+				return err
+			}
+			if err := transfer.Copy(ctx, srcEndpoint, dstEndpoint, opts); err != nil {
+				return err
+			}
+			plan.Print(cmd.OutOrStdout())
+			return nil
 		}
-		awsProfile, err := cmd.Flags().GetString("aws-profile")
+
+		// Ctrl-C stops the transfer but keeps the journal and any partial
+		// uploads, so that the job can be resumed.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, err = limitBandwidth(ctx, cmd)
 		if err != nil {
-			return fmt.Errorf("Error parsing aws-profile: %v", err)
+			return err
+		}
+
+		// Only resumable jobs are journaled: their failed uploads are kept
+		// to be continued, while those of other copies are aborted.
+		if job == nil && resumable {
+			job, err = journal.Create(jobDir, source, destination)
+			if err != nil {
+				return err
+			}
+			defer job.Close()
+			opts.Journal = job
+		}
+		if job != nil {
+			fmt.Fprintf(os.Stderr, "Job %s: %s -> %s\n", job.ID, source, destination)
 		}
 
-		err = validateSrcDst(source, destination)
+		ctx, stopProgress, err := startProgress(ctx, cmd)
 		if err != nil {
 			return err
 		}
 
-		ctx := context.Background()
-		if source == cspGcp && destination == cspAws {
-			err = TransferFromGcpToAWS(
-				ctx, awsProfile, source, destination, tmpPath)
-		} else if source == srcLocal && destination == cspAws {
-			err = TransferFromLocalToAWS(
-				ctx, awsProfile, source, destination)
+		err = transferBetweenStores(ctx, cmd, cfg, src, dst, opts)
+		stopProgress()
+		if opts.Report != nil {
+			opts.Report.Print(cmd.OutOrStdout())
 		}
 		if err != nil {
+			if job != nil {
+				fmt.Fprintf(os.Stderr, "Resume this transfer with: storage-synk cp --resume %s\n", job.ID)
+			}
 			return err
 		}
+		fmt.Println("Transfer completed successfully!")
+		if job == nil {
+			return nil
+		}
+		return job.Remove()
+	},
+}
 
+var syncCmd = &cobra.Command{
+	Use:   "sync [source] [destination]",
+	Args:  cobra.MaximumNArgs(2),
+	Short: "copies only new and changed files/objects from source to destination",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, destination, err := locations(cmd, args)
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(cmd)
+		if err != nil {
+			return err
+		}
+		opts, err := transferOptions(cmd)
+		if err != nil {
+			return err
+		}
+		compareFlag, err := cmd.Flags().GetString("compare")
+		if err != nil {
+			return fmt.Errorf("Error parsing compare: %v", err)
+		}
+		compare, err := transfer.ParseCompare(compareFlag)
+		if err != nil {
+			return err
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			return fmt.Errorf("Error parsing dry-run: %v", err)
+		}
+
+		src, dst, err := parseSrcDst(cfg, source, destination)
+		if err != nil {
+			return err
+		}
+		if err := applyRemoteSettings(cmd, cfg, src, dst, &opts); err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		var plan *dryrun.Plan
+		stopProgress := func() {}
+		if dryRun {
+			plan = dryrun.NewPlan()
+			ctx = dryrun.WithPlan(ctx, plan)
+		} else {
+			ctx, err = limitBandwidth(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			ctx, stopProgress, err = startProgress(ctx, cmd)
+			if err != nil {
+				return err
+			}
+		}
+
+		srcEndpoint, dstEndpoint, err := openEndpoints(ctx, cmd, cfg, src, dst)
+		if err != nil {
+			return err
+		}
+		stats, err := transfer.Sync(ctx, srcEndpoint, dstEndpoint, compare, opts)
+		stopProgress()
+		if plan != nil {
+			if err != nil {
+				return err
+			}
+			plan.Print(cmd.OutOrStdout())
+			return nil
+		}
+		if opts.Report != nil {
+			opts.Report.Print(cmd.OutOrStdout())
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Sync completed: %d copied (%d bytes), %d unchanged\n",
+			stats.Copied, stats.Bytes, stats.Unchanged)
 		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(cpCmd)
+	rootCmd.AddCommand(syncCmd)
+
+	addTransferFlags(cpCmd)
+	cpCmd.Flags().String("resume", "",
+		"Resume an interrupted job by its ID, skipping objects it already copied")
+	cpCmd.Flags().Bool("resumable", false,
+		"Journal the job so that it can be resumed with --resume if it is interrupted")
 
-	cpCmd.Flags().StringP("source", "s", "", "Source bucket path")
-	cpCmd.Flags().StringP("destination", "d", "", "Destination bucket path")
-	cpCmd.PersistentFlags().Lookup("download-location").NoOptDefVal = os.TempDir()
-	cpCmd.PersistentFlags().Lookup("aws-profile").NoOptDefVal = "default"
+	addTransferFlags(syncCmd)
+	syncCmd.Flags().String("compare", string(transfer.CompareMtime),
+		"How existing objects are compared: size-only, mtime (size and modification time) or checksum")
+
+	rootCmd.PersistentFlags().String("aws-profile", "", "AWS shared config profile to use")
+	rootCmd.PersistentFlags().String("config", "",
+		"Config file with named remotes (default: $"+config.EnvPath+" or ~/.config/storage-synk/config.yaml)")
 }
 
-func validateSrcDst(src, dst string) error {
-	var validGCPBucketPath = regexp.MustCompile(`^gs://[a-zA-Z0-9._-]+(/[a-zA-Z0-9._-]+)*$`)
-	var validS3Path = regexp.MustCompile(`^s3://[a-zA-Z0-9._-]+(/[a-zA-Z0-9._-]+)*$`)
+// addTransferFlags defines the flags shared by the commands that copy data.
+func addTransferFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("source", "s", "", "Source bucket path")
+	cmd.Flags().StringP("destination", "d", "", "Destination bucket path")
+	cmd.Flags().Int("concurrency", 10, "Number of objects transferred in parallel")
+	cmd.Flags().String("bwlimit", "",
+		"Limit total bandwidth, e.g. 50MiB/s, or by time of day: \"08:00,10MiB/s 18:00,off\"")
+	cmd.Flags().String("download-location", "",
+		"Stage cloud to cloud copies in this local directory instead of streaming them")
+	cmd.Flags().Lookup("download-location").NoOptDefVal = os.TempDir()
+	cmd.Flags().String("part-size", "0",
+		"Size of each part in multipart uploads, e.g. 64MiB (default: backend specific)")
+	cmd.Flags().Int("part-concurrency", 0,
+		"Number of parts of one object uploaded in parallel (default: backend specific)")
+	cmd.Flags().String("slice-size", "0",
+		"Size of each range when downloading large objects in parallel (default 32MiB)")
+	cmd.Flags().String("slice-threshold", "0",
+		"Download objects at least this large as parallel ranges, or off (default 128MiB)")
+	cmd.Flags().Int("slice-concurrency", 0,
+		"Number of ranges of one object downloaded in parallel (default 4)")
+	cmd.Flags().Bool("dry-run", false,
+		"Print what would be copied, overwritten or skipped without changing anything")
+	cmd.Flags().Bool("no-progress", false, "Do not report transfer progress")
+	cmd.Flags().Bool("fail-fast", false,
+		"Stop at the first failed object instead of attempting all of them")
+	cmd.Flags().Bool("encrypt", false,
+		"Encrypt objects client-side before uploading (needs --key-file, --passphrase-file or $"+passphraseEnv+")")
+	cmd.Flags().String("key-file", "",
+		"File holding a 256-bit key used to encrypt, and to decrypt encrypted sources")
+	cmd.Flags().String("passphrase-file", "",
+		"File holding a passphrase used instead of a key file")
+	cmd.Flags().String("sse", "",
+		"Server-side encryption of the copies: managed (SSE-S3), kms (SSE-KMS, GCS CMEK) "+
+			"or customer (SSE-C, GCS CSEK) (default: bucket settings)")
+	cmd.Flags().String("sse-kms-key", "",
+		"KMS key for --sse kms: an AWS KMS key ARN or a Cloud KMS key name")
+	cmd.Flags().String("sse-customer-key-file", "",
+		"File holding the 256-bit key for --sse customer")
+	cmd.Flags().String("source-sse-customer-key-file", "",
+		"File holding the 256-bit key of SSE-C or CSEK encrypted source objects")
+	cmd.Flags().Bool("report", false,
+		"Print every copied object with the encryption the destination applied")
+	cmd.Flags().String("metadata-directive", "",
+		"keep the headers and metadata of the source, replace them with the given ones, "+
+			"or add the given ones (default: keep, or add when some are given)")
+	cmd.Flags().StringArray("metadata", nil, "User metadata of the copies as key=value (repeatable)")
+	for _, h := range metadataHeaders {
+		cmd.Flags().String(h.flag, "", h.name+" of the copies")
+	}
+	addFilterFlags(cmd)
+	cmd.Flags().Int("max-attempts", 5,
+		"Attempts per object on throttling and transient errors (1 disables retries)")
+	cmd.Flags().Duration("retry-deadline", 0,
+		"Stop retrying an object after this long, e.g. 10m (default: no limit)")
+}
 
-	if !validGCPBucketPath.MatchString(src) && !validS3Path.MatchString(src) {
-		return fmt.Errorf("Invalid Source: %s", source)
+// passphraseEnv may hold the encryption passphrase instead of a file.
+const passphraseEnv = "STORAGE_SYNK_PASSPHRASE"
+
+// keyring loads the key for client-side encryption from --key-file,
+// --passphrase-file or the environment. It returns nil if none is given.
+func keyring(cmd *cobra.Command) (*encrypt.Keyring, error) {
+	keyFile, err := cmd.Flags().GetString("key-file")
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing key-file: %v", err)
+	}
+	if keyFile != "" {
+		return encrypt.LoadKeyFile(keyFile)
 	}
 
-	if !validGCPBucketPath.MatchString(dst) && !validS3Path.MatchString(dst) {
-		return fmt.Errorf("Invalid Destination: %s", dst)
+	passphraseFile, err := cmd.Flags().GetString("passphrase-file")
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing passphrase-file: %v", err)
+	}
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading passphrase file: %v", err)
+		}
+		return encrypt.FromPassphrase(strings.TrimRight(string(data), "\r\n"))
 	}
 
-	return nil
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		return encrypt.FromPassphrase(passphrase)
+	}
+	return nil, nil
 }
 
-func TransferFromGcpToAWS(
-	ctx context.Context,
-	profile,
-	source, destination, tmpPath string) error {
-	err := gcp.GcsDownload(ctx, source, tmpPath)
+// serverSideEncryption reads the --sse flags. --sse may be left out when
+// --sse-kms-key or --sse-customer-key-file imply the mode.
+func serverSideEncryption(cmd *cobra.Command) (store.Encryption, error) {
+	var enc store.Encryption
+	mode, err := cmd.Flags().GetString("sse")
 	if err != nil {
-		return err
+		return enc, fmt.Errorf("Error parsing sse: %v", err)
+	}
+	enc.KMSKey, err = cmd.Flags().GetString("sse-kms-key")
+	if err != nil {
+		return enc, fmt.Errorf("Error parsing sse-kms-key: %v", err)
+	}
+	keyFile, err := cmd.Flags().GetString("sse-customer-key-file")
+	if err != nil {
+		return enc, fmt.Errorf("Error parsing sse-customer-key-file: %v", err)
+	}
+
+	enc.Mode = store.EncryptionMode(strings.ToLower(mode))
+	if enc.Mode == store.EncryptionDefault {
+		if enc.KMSKey != "" {
+			enc.Mode = store.EncryptionKMS
+		} else if keyFile != "" {
+			enc.Mode = store.EncryptionCustomer
+		}
+	}
+	switch enc.Mode {
+	case store.EncryptionDefault, store.EncryptionManaged, store.EncryptionKMS, store.EncryptionCustomer:
+	default:
+		return enc, fmt.Errorf("Invalid --sse %q: expected managed, kms or customer", mode)
+	}
+	if enc.KMSKey != "" && enc.Mode != store.EncryptionKMS {
+		return enc, fmt.Errorf("--sse-kms-key needs --sse kms")
+	}
+	if keyFile != "" && enc.Mode != store.EncryptionCustomer {
+		return enc, fmt.Errorf("--sse-customer-key-file needs --sse customer")
+	}
+	if enc.Mode == store.EncryptionCustomer {
+		if keyFile == "" {
+			return enc, fmt.Errorf("--sse customer needs --sse-customer-key-file")
+		}
+		enc.CustomerKey, err = encrypt.ReadKeyFile(keyFile)
+		if err != nil {
+			return enc, err
+		}
 	}
+	return enc, nil
+}
+
+// metadataHeaders are the system headers the copies can be given.
+var metadataHeaders = []struct {
+	flag, name string
+	field      func(*store.Headers) *string
+}{
+	{"content-type", "Content-Type", func(h *store.Headers) *string { return &h.ContentType }},
+	{"cache-control", "Cache-Control", func(h *store.Headers) *string { return &h.CacheControl }},
+	{"content-encoding", "Content-Encoding", func(h *store.Headers) *string { return &h.ContentEncoding }},
+	{"content-disposition", "Content-Disposition", func(h *store.Headers) *string { return &h.ContentDisposition }},
+}
+
+// metadataPrefixes are the header prefixes of user metadata, which
+// --metadata keys may carry.
+var metadataPrefixes = []string{"x-amz-meta-", "x-goog-meta-", "x-ms-meta-"}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(profile))
+func metadataOptions(cmd *cobra.Command, opts *transfer.Options) error {
+	directive, err := cmd.Flags().GetString("metadata-directive")
 	if err != nil {
-		return err
+		return fmt.Errorf("Error parsing metadata-directive: %v", err)
+	}
+	given := false
+	for _, h := range metadataHeaders {
+		value, err := cmd.Flags().GetString(h.flag)
+		if err != nil {
+			return fmt.Errorf("Error parsing %s: %v", h.flag, err)
+		}
+		*h.field(&opts.Headers) = value
+		given = given || value != ""
+	}
+	metadata, err := cmd.Flags().GetStringArray("metadata")
+	if err != nil {
+		return fmt.Errorf("Error parsing metadata: %v", err)
+	}
+	for _, entry := range metadata {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("Invalid --metadata %q: expected key=value", entry)
+		}
+		for _, prefix := range metadataPrefixes {
+			if len(key) > len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+				key = key[len(prefix):]
+			}
+		}
+		if opts.Metadata == nil {
+			opts.Metadata = map[string]string{}
+		}
+		opts.Metadata[key] = value
+		given = true
 	}
 
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 10) // TODO: allow user to configure the concurrency limit?
+	switch {
+	case directive != "":
+		opts.MetadataDirective, err = transfer.ParseMetadataDirective(strings.ToLower(directive))
+		if err != nil {
+			return err
+		}
+	case given:
+		opts.MetadataDirective = transfer.MetadataAdd
+	default:
+		opts.MetadataDirective = transfer.MetadataKeep
+	}
+	if opts.MetadataDirective == transfer.MetadataKeep && given {
+		return fmt.Errorf("--metadata and the header flags need --metadata-directive replace or add")
+	}
+	return nil
+}
 
-	err = aws.S3FolderUpload(ctx, cfg.Region, destination, tmpPath, &wg, sem)
+// limitBandwidth attaches the --bwlimit limiter to ctx. All workers of the
+// transfer share it.
+func limitBandwidth(ctx context.Context, cmd *cobra.Command) (context.Context, error) {
+	value, err := cmd.Flags().GetString("bwlimit")
 	if err != nil {
-		return err
+		return ctx, fmt.Errorf("Error parsing bwlimit: %v", err)
 	}
+	schedule, err := bwlimit.ParseSchedule(value)
+	if err != nil {
+		return ctx, err
+	}
+	if !schedule.Limited() {
+		return ctx, nil
+	}
+	return bwlimit.WithLimiter(ctx, bwlimit.NewLimiter(schedule)), nil
+}
+
+// startProgress attaches a progress tracker to ctx and displays it on
+// stderr until the returned function is called. --no-progress disables it.
+func startProgress(ctx context.Context, cmd *cobra.Command) (context.Context, func(), error) {
+	noProgress, err := cmd.Flags().GetBool("no-progress")
+	if err != nil {
+		return ctx, nil, fmt.Errorf("Error parsing no-progress: %v", err)
+	}
+	if noProgress {
+		return ctx, func() {}, nil
+	}
+	tracker := progress.NewTracker()
+	display := progress.Start(cmd.ErrOrStderr(), tracker)
+	return progress.WithTracker(ctx, tracker), display.Stop, nil
+}
 
-	// Wait for all uploads to finish
-	wg.Wait()
-	fmt.Println("Folder upload completed successfully!")
+// transferOptions reads the flags defined by addTransferFlags.
+func transferOptions(cmd *cobra.Command) (transfer.Options, error) {
+	var opts transfer.Options
+	var err error
 
-	return nil
+	opts.Concurrency, err = cmd.Flags().GetInt("concurrency")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing concurrency: %v", err)
+	}
+	opts.StagingDir, err = cmd.Flags().GetString("download-location")
+	if err != nil {
+		return opts, fmt.Errorf("Error loading temp path: %v", err)
+	}
+	opts.PartSize, err = sizeFlag(cmd, "part-size")
+	if err != nil {
+		return opts, err
+	}
+	opts.PartConcurrency, err = cmd.Flags().GetInt("part-concurrency")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing part-concurrency: %v", err)
+	}
+	opts.SliceSize, err = sizeFlag(cmd, "slice-size")
+	if err != nil {
+		return opts, err
+	}
+	opts.SliceConcurrency, err = cmd.Flags().GetInt("slice-concurrency")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing slice-concurrency: %v", err)
+	}
+	if threshold, _ := cmd.Flags().GetString("slice-threshold"); threshold == "off" {
+		opts.SliceThreshold = -1
+	} else if opts.SliceThreshold, err = sizeFlag(cmd, "slice-threshold"); err != nil {
+		return opts, err
+	}
+	opts.FailFast, err = cmd.Flags().GetBool("fail-fast")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing fail-fast: %v", err)
+	}
+	opts.Keyring, err = keyring(cmd)
+	if err != nil {
+		return opts, err
+	}
+	opts.Encrypt, err = cmd.Flags().GetBool("encrypt")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing encrypt: %v", err)
+	}
+	if opts.Encrypt && opts.Keyring == nil {
+		return opts, fmt.Errorf("--encrypt needs --key-file, --passphrase-file or $%s", passphraseEnv)
+	}
+	opts.Encryption, err = serverSideEncryption(cmd)
+	if err != nil {
+		return opts, err
+	}
+	sourceKeyFile, err := cmd.Flags().GetString("source-sse-customer-key-file")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing source-sse-customer-key-file: %v", err)
+	}
+	if sourceKeyFile != "" {
+		opts.SourceKey, err = encrypt.ReadKeyFile(sourceKeyFile)
+		if err != nil {
+			return opts, err
+		}
+	}
+	report, err := cmd.Flags().GetBool("report")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing report: %v", err)
+	}
+	if report {
+		opts.Report = transfer.NewReport()
+	}
+	opts.Filter, err = sourceFilter(cmd)
+	if err != nil {
+		return opts, err
+	}
+	if err := metadataOptions(cmd, &opts); err != nil {
+		return opts, err
+	}
+	opts.Retry.MaxAttempts, err = cmd.Flags().GetInt("max-attempts")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing max-attempts: %v", err)
+	}
+	opts.Retry.Deadline, err = cmd.Flags().GetDuration("retry-deadline")
+	if err != nil {
+		return opts, fmt.Errorf("Error parsing retry-deadline: %v", err)
+	}
+	return opts, nil
 }
 
-func TransferFromLocalToAWS(
+// openEndpoints opens the stores for source and destination, sharing one
+// store when both live in the same backend and account and use the same
+// settings.
+func openEndpoints(
 	ctx context.Context,
-	profile, source, destination string) error {
-	_, err := os.Stat(source)
+	cmd *cobra.Command, cfg *config.Config,
+	source, destination uri.URI) (transfer.Endpoint, transfer.Endpoint, error) {
+
+	srcSettings := storeSettings(cmd, cfg, source)
+	dstSettings := storeSettings(cmd, cfg, destination)
+	srcStore, err := newStore(ctx, source, srcSettings)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("[path-%s-NotFound]", source)
+		return transfer.Endpoint{}, transfer.Endpoint{}, err
+	}
+	dstStore := srcStore
+	if destination.Scheme != source.Scheme || destination.Account != source.Account || dstSettings != srcSettings {
+		dstStore, err = newStore(ctx, destination, dstSettings)
+		if err != nil {
+			return transfer.Endpoint{}, transfer.Endpoint{}, err
 		}
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(profile))
+	src, err := transfer.NewEndpoint(srcStore, source)
 	if err != nil {
-		return err
+		return transfer.Endpoint{}, transfer.Endpoint{}, err
 	}
+	dst, err := transfer.NewEndpoint(dstStore, destination)
+	if err != nil {
+		return transfer.Endpoint{}, transfer.Endpoint{}, err
+	}
+	return src, dst, nil
+}
 
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 10) // TODO: allow user to configure the concurrency limit?
-	err = aws.S3FolderUpload(ctx, cfg.Region, destination, source, &wg, sem)
+// transferBetweenStores copies source to destination through the generic
+// store.ObjectStore path, so any pair of backends works the same way.
+func transferBetweenStores(
+	ctx context.Context,
+	cmd *cobra.Command, cfg *config.Config,
+	source, destination uri.URI, opts transfer.Options) error {
+
+	src, dst, err := openEndpoints(ctx, cmd, cfg, source, destination)
 	if err != nil {
 		return err
 	}
 
-	// Wait for all uploads to finish
-	wg.Wait()
-	fmt.Println("Folder upload completed successfully!")
+	return transfer.Copy(ctx, src, dst, opts)
+}
+
+// newStore is a variable so that tests can swap the cloud backends for fakes.
+var newStore = openStore
 
-	return nil
+func openStore(ctx context.Context, u uri.URI, settings config.Remote) (store.ObjectStore, error) {
+	switch u.Scheme {
+	case uri.SchemeS3:
+		return aws.NewS3Store(ctx, aws.S3Options{
+			Profile:  settings.Profile,
+			Region:   settings.Region,
+			Endpoint: settings.Endpoint,
+		})
+	case uri.SchemeGCS:
+		return gcp.NewGCSStore(ctx, gcp.Options{
+			Project:         settings.Project,
+			CredentialsFile: settings.CredentialsFile,
+		})
+	case uri.SchemeAzure:
+		creds := azure.CredentialsFromEnv()
+		creds.Endpoint = settings.Endpoint
+		return azure.NewBlobStore(u.Account, creds)
+	case uri.SchemeFile:
+		return local.NewFileStore(), nil
+	}
+	return nil, fmt.Errorf("Unsupported storage scheme: %s", u.Scheme)
 }
 
-func TransferFromLocalToGCP(
-	ctx context.Context,
-	profile, source, destination string) error {
-	_, err := os.Stat(source)
+// sizeFlag reads a flag holding a size such as "64MiB".
+func sizeFlag(cmd *cobra.Command, name string) (int64, error) {
+	value, err := cmd.Flags().GetString(name)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("[path-%s-NotFound]", source)
-		}
+		return 0, fmt.Errorf("Error parsing %s: %v", name, err)
 	}
-
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithSharedConfigProfile(profile))
+	size, err := utils.ParseSize(value)
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("Error parsing %s: %v", name, err)
 	}
+	return size, nil
+}
 
-	var wg sync.WaitGroup
-	sem := make(chan struct{}, 10) // TODO: allow user to configure the concurrency limit?
-	err = aws.S3FolderUpload(ctx, cfg.Region, destination, source, &wg, sem)
+func parseSrcDst(cfg *config.Config, src, dst string) (uri.URI, uri.URI, error) {
+	source, err := cfg.Parse(src)
 	if err != nil {
-		return err
+		return uri.URI{}, uri.URI{}, fmt.Errorf("Invalid Source: %v", err)
 	}
 
-	// Wait for all uploads to finish
-	wg.Wait()
-	fmt.Println("Folder upload completed successfully!")
+	destination, err := cfg.Parse(dst)
+	if err != nil {
+		return uri.URI{}, uri.URI{}, fmt.Errorf("Invalid Destination: %v", err)
+	}
+	if destination.HasWildcard() {
+		return uri.URI{}, uri.URI{}, fmt.Errorf("Invalid Destination: wildcards are not allowed in %s", dst)
+	}
+	if destination.VersionID != "" {
+		return uri.URI{}, uri.URI{}, fmt.Errorf("Invalid Destination: a version cannot be written in %s", dst)
+	}
 
-	return nil
+	return source, destination, nil
 }
@@ -0,0 +1,400 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/config"
+	"github.com/RA-Balaji/storage-synk/journal"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/store/storetest"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+// useFakeStores replaces the S3 and GCS backends with in-memory stores for
+// the duration of the test; local paths still use the real filesystem.
+func useFakeStores(t *testing.T) map[string]*storetest.MemStore {
+	fakes := map[string]*storetest.MemStore{
+		uri.SchemeS3:  storetest.NewMemStore(),
+		uri.SchemeGCS: storetest.NewMemStore(),
+	}
+	orig := newStore
+	newStore = func(ctx context.Context, u uri.URI, settings config.Remote) (store.ObjectStore, error) {
+		if fake, ok := fakes[u.Scheme]; ok {
+			return fake, nil
+		}
+		return openStore(ctx, u, settings)
+	}
+	t.Cleanup(func() { newStore = orig })
+	// Keep job journals out of the real home directory.
+	t.Setenv("HOME", t.TempDir())
+	return fakes
+}
+
+func runCommand(t *testing.T, args ...string) error {
+	// Flag values persist between executions of the same command tree.
+	reset := func(f *pflag.Flag) {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			v.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	rootCmd.PersistentFlags().VisitAll(reset)
+	for _, c := range rootCmd.Commands() {
+		c.Flags().VisitAll(reset)
+	}
+	rootCmd.SetArgs(args)
+	return rootCmd.Execute()
+}
+
+var testFiles = map[string]string{
+	"file1.txt":        "one",
+	"subdir/file2.txt": "two",
+}
+
+func TestCpMatrix(t *testing.T) {
+	schemes := []string{uri.SchemeFile, uri.SchemeS3, uri.SchemeGCS}
+
+	for _, srcScheme := range schemes {
+		for _, dstScheme := range schemes {
+			if srcScheme == uri.SchemeFile && dstScheme == uri.SchemeFile {
+				continue
+			}
+			t.Run(srcScheme+"-to-"+dstScheme, func(t *testing.T) {
+				fakes := useFakeStores(t)
+				tmpDir := filepath.ToSlash(t.TempDir())
+
+				var source, destination string
+				switch srcScheme {
+				case uri.SchemeFile:
+					for name, content := range testFiles {
+						path := filepath.Join(tmpDir, "src", filepath.FromSlash(name))
+						assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+						assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+					}
+					source = tmpDir + "/src"
+				default:
+					for name, content := range testFiles {
+						fakes[srcScheme].Put("src-bucket", "data/"+name, []byte(content))
+					}
+					source = srcScheme + "://src-bucket/data/"
+				}
+
+				switch dstScheme {
+				case uri.SchemeFile:
+					destination = "file://" + tmpDir + "/dst"
+				default:
+					destination = dstScheme + "://dst-bucket/backup/"
+				}
+
+				err := runCommand(t, "cp", "-s", source, "-d", destination)
+				assert.NoError(t, err)
+
+				for name, content := range testFiles {
+					var got []byte
+					var ok bool
+					if dstScheme == uri.SchemeFile {
+						got, err = os.ReadFile(filepath.Join(tmpDir, "dst", filepath.FromSlash(name)))
+						ok = err == nil
+					} else {
+						got, ok = fakes[dstScheme].Get("dst-bucket", "backup/"+name)
+					}
+					if assert.True(t, ok, name) {
+						assert.Equal(t, content, string(got), name)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCpSingleObject(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeGCS].Put("src-bucket", "reports/q1.csv", []byte("a,b"))
+
+	err := runCommand(t, "cp", "-s", "gs://src-bucket/reports/q1.csv", "-d", "s3://dst-bucket/in/")
+	assert.NoError(t, err)
+
+	got, ok := fakes[uri.SchemeS3].Get("dst-bucket", "in/q1.csv")
+	assert.True(t, ok)
+	assert.Equal(t, "a,b", string(got))
+}
+
+func TestCpInvalidSource(t *testing.T) {
+	useFakeStores(t)
+	err := runCommand(t, "cp", "-s", "ftp://host/file", "-d", "s3://dst-bucket/")
+	assert.ErrorContains(t, err, "scheme")
+}
+
+func TestCpResume(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeGCS].Put("src-bucket", "data/a.txt", []byte("a"))
+	fakes[uri.SchemeGCS].Put("src-bucket", "data/b.txt", []byte("b"))
+
+	dir, err := journal.DefaultDir()
+	assert.NoError(t, err)
+	job, err := journal.Create(dir, "gs://src-bucket/data/", "s3://dst-bucket/in/")
+	assert.NoError(t, err)
+	a, err := fakes[uri.SchemeGCS].Stat(context.Background(), "src-bucket", "data/a.txt")
+	assert.NoError(t, err)
+	assert.NoError(t, job.MarkDone("in/a.txt", a))
+	job.Close()
+
+	err = runCommand(t, "cp", "--resume", job.ID, "-d", "s3://other-bucket/")
+	assert.ErrorContains(t, err, "copies gs://src-bucket/data/ to s3://dst-bucket/in/")
+
+	err = runCommand(t, "cp", "--resume", job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"in/b.txt"}, fakes[uri.SchemeS3].Keys("dst-bucket"))
+
+	// The journal is removed once the job has completed.
+	_, err = journal.Open(dir, job.ID)
+	assert.Error(t, err)
+}
+
+// failingStore fails every write.
+type failingStore struct {
+	*storetest.MemStore
+}
+
+func (failingStore) Write(ctx context.Context, bucket, key string, r io.Reader, opts store.WriteOptions) error {
+	return errors.New("write failed")
+}
+
+func TestCpJournalsOnlyResumableJobs(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeGCS].Put("src-bucket", "data/a.txt", []byte("a"))
+	orig := newStore
+	newStore = func(ctx context.Context, u uri.URI, settings config.Remote) (store.ObjectStore, error) {
+		if u.Scheme == uri.SchemeS3 {
+			return failingStore{fakes[uri.SchemeS3]}, nil
+		}
+		return orig(ctx, u, settings)
+	}
+	t.Cleanup(func() { newStore = orig })
+	dir, err := journal.DefaultDir()
+	assert.NoError(t, err)
+
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--max-attempts", "1")
+	assert.ErrorContains(t, err, "write failed")
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--max-attempts", "1",
+		"--resumable")
+	assert.ErrorContains(t, err, "write failed")
+	entries, _ = os.ReadDir(dir)
+	assert.Len(t, entries, 1)
+}
+
+func TestSyncCopiesOnlyChanges(t *testing.T) {
+	fakes := useFakeStores(t)
+	tmpDir := filepath.ToSlash(t.TempDir())
+	for name, content := range testFiles {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	err := runCommand(t, "sync", "-s", tmpDir+"/", "-d", "s3://dst-bucket/backup/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"backup/file1.txt", "backup/subdir/file2.txt"}, fakes[uri.SchemeS3].Keys("dst-bucket"))
+
+	// Only the resized file is copied again with size-only comparison.
+	fakes[uri.SchemeS3].Put("dst-bucket", "backup/file1.txt", []byte("changed"))
+	fakes[uri.SchemeS3].Put("dst-bucket", "backup/subdir/file2.txt", []byte("TWO"))
+	err = runCommand(t, "sync", "-s", tmpDir+"/", "-d", "s3://dst-bucket/backup/", "--compare", "size-only")
+	assert.NoError(t, err)
+	got, _ := fakes[uri.SchemeS3].Get("dst-bucket", "backup/file1.txt")
+	assert.Equal(t, "one", string(got))
+	got, _ = fakes[uri.SchemeS3].Get("dst-bucket", "backup/subdir/file2.txt")
+	assert.Equal(t, "TWO", string(got))
+
+	err = runCommand(t, "sync", "-s", tmpDir+"/", "-d", "s3://dst-bucket/", "--compare", "fast")
+	assert.ErrorContains(t, err, "Invalid comparison")
+}
+
+func TestDryRunPrintsPlan(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeGCS].Put("src-bucket", "data/a.txt", []byte("aaa"))
+	fakes[uri.SchemeGCS].Put("src-bucket", "data/b.txt", []byte("bb"))
+	fakes[uri.SchemeS3].Put("dst-bucket", "in/b.txt", []byte("bb"))
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+
+	err := runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--dry-run")
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "copy              3 B  gs://src-bucket/data/a.txt -> s3://dst-bucket/in/a.txt")
+	assert.Contains(t, out.String(), "overwrite         2 B  gs://src-bucket/data/b.txt -> s3://dst-bucket/in/b.txt")
+	assert.Equal(t, []string{"in/b.txt"}, fakes[uri.SchemeS3].Keys("dst-bucket"))
+
+	// A dry run is not a job, so no journal is left behind.
+	dir, _ := journal.DefaultDir()
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+
+	out.Reset()
+	err = runCommand(t, "sync", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--dry-run",
+		"--compare", "size-only")
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "skip              2 B  gs://src-bucket/data/b.txt -> s3://dst-bucket/in/b.txt")
+	assert.Contains(t, out.String(), "Dry run: 1 to transfer (3 bytes, 3 B), 1 skipped")
+	assert.Equal(t, []string{"in/b.txt"}, fakes[uri.SchemeS3].Keys("dst-bucket"))
+}
+
+func TestCpBandwidthLimit(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeGCS].Put("src-bucket", "data/a.txt", []byte("aaa"))
+
+	err := runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--bwlimit", "fast")
+	assert.ErrorContains(t, err, "Invalid bandwidth")
+
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/",
+		"--bwlimit", "00:00,1MiB/s 12:00,2MiB/s", "--concurrency", "2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"in/a.txt"}, fakes[uri.SchemeS3].Keys("dst-bucket"))
+}
+
+func TestCpServerSideEncryption(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeGCS].Put("src-bucket", "data/a.txt", []byte("aaa"))
+	keyFile := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(keyFile, bytes.Repeat([]byte{1}, 32), 0600))
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+
+	err := runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/",
+		"--sse-customer-key-file", keyFile, "--report")
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "gs://src-bucket/data/a.txt -> s3://dst-bucket/in/a.txt  [customer key]")
+	assert.Contains(t, out.String(), "Encryption: 1 customer key")
+
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--sse", "customer")
+	assert.ErrorContains(t, err, "--sse customer needs --sse-customer-key-file")
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/",
+		"--sse", "managed", "--sse-kms-key", "arn")
+	assert.ErrorContains(t, err, "--sse-kms-key needs --sse kms")
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--sse", "aes")
+	assert.ErrorContains(t, err, "Invalid --sse")
+}
+
+func TestCpFilters(t *testing.T) {
+	fakes := useFakeStores(t)
+	gcs := fakes[uri.SchemeGCS]
+	gcs.Put("src-bucket", "data/a.txt", []byte("aaa"))
+	gcs.Put("src-bucket", "data/b.log", []byte("bbb"))
+	gcs.Put("src-bucket", "data/big.txt", []byte("0123456789"))
+	gcs.Put("src-bucket", "data/sub/c.txt", []byte("ccc"))
+
+	err := runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/",
+		"--include", "*.txt", "--exclude", "sub/", "--max-size", "5", "--newer-than", "1d")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"in/a.txt"}, fakes[uri.SchemeS3].Keys("dst-bucket"))
+
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--older-than", "soon")
+	assert.ErrorContains(t, err, "Error parsing older-than")
+}
+
+func TestCpMetadata(t *testing.T) {
+	fakes := useFakeStores(t)
+	err := fakes[uri.SchemeGCS].Write(context.Background(), "src-bucket", "data/a.json", strings.NewReader("{}"),
+		store.WriteOptions{
+			Headers:  store.Headers{ContentType: "application/json"},
+			Metadata: map[string]string{"owner": "web"},
+		})
+	assert.NoError(t, err)
+
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/",
+		"--cache-control", "no-cache", "--metadata", "x-amz-meta-team=data")
+	assert.NoError(t, err)
+	info, err := fakes[uri.SchemeS3].Stat(context.Background(), "dst-bucket", "in/a.json")
+	assert.NoError(t, err)
+	assert.Equal(t, store.Headers{ContentType: "application/json", CacheControl: "no-cache"}, info.Headers)
+	assert.Equal(t, map[string]string{"owner": "web", "team": "data"}, info.Metadata)
+
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/",
+		"--metadata-directive", "replace", "--content-type", "text/plain")
+	assert.NoError(t, err)
+	info, err = fakes[uri.SchemeS3].Stat(context.Background(), "dst-bucket", "in/a.json")
+	assert.NoError(t, err)
+	assert.Equal(t, store.Headers{ContentType: "text/plain"}, info.Headers)
+	assert.Empty(t, info.Metadata)
+
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/",
+		"--metadata-directive", "keep", "--metadata", "team=data")
+	assert.ErrorContains(t, err, "need --metadata-directive replace or add")
+	err = runCommand(t, "cp", "-s", "gs://src-bucket/data/", "-d", "s3://dst-bucket/in/", "--metadata", "team")
+	assert.ErrorContains(t, err, "Invalid --metadata")
+}
+
+func TestCpNamedRemotes(t *testing.T) {
+	fakes := useFakeStores(t)
+	fakes[uri.SchemeGCS].Put("archive", "data/a.txt", []byte("aaa"))
+	keyFile := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(keyFile, bytes.Repeat([]byte{1}, 32), 0600))
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(configFile, []byte(`
+remotes:
+  prod-s3:
+    type: s3
+    profile: prod
+    region: eu-west-1
+    sse_customer_key_file: `+keyFile+`
+  archive-gcs:
+    type: gcs
+    project: archive-project
+`), 0600))
+	t.Setenv(config.EnvPath, configFile)
+	t.Setenv("AWS_REGION", "us-east-2")
+
+	settings := map[string]config.Remote{}
+	fake := newStore
+	newStore = func(ctx context.Context, u uri.URI, s config.Remote) (store.ObjectStore, error) {
+		settings[u.Scheme] = s
+		return fake(ctx, u, s)
+	}
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() { rootCmd.SetOut(nil) })
+
+	err := runCommand(t, "cp", "archive-gcs:archive/data/", "prod-s3:backup/in/", "--aws-profile", "admin", "--report")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"in/a.txt"}, fakes[uri.SchemeS3].Keys("backup"))
+	assert.Contains(t, out.String(), "gs://archive/data/a.txt -> s3://backup/in/a.txt  [customer key]")
+	// Flags override the environment, which overrides the config file.
+	assert.Equal(t, "admin", settings[uri.SchemeS3].Profile)
+	assert.Equal(t, "us-east-2", settings[uri.SchemeS3].Region)
+	assert.Equal(t, "archive-project", settings[uri.SchemeGCS].Project)
+
+	err = runCommand(t, "cp", "dev-s3:bucket/", "./out")
+	assert.ErrorContains(t, err, `Unknown remote "dev-s3"`)
+	err = runCommand(t, "cp", "archive-gcs:archive/data/", "-d", "prod-s3:backup/")
+	assert.ErrorContains(t, err, "either as arguments or with --source and --destination")
+	err = runCommand(t, "cp", "archive-gcs:archive/data/")
+	assert.ErrorContains(t, err, "Expected a source and a destination")
+}
+
+func TestSliceThresholdOff(t *testing.T) {
+	t.Cleanup(func() { cpCmd.Flags().Set("slice-threshold", "0") })
+	for value, want := range map[string]int64{"off": -1, "0": 0, "64MiB": 64 << 20} {
+		assert.NoError(t, cpCmd.Flags().Set("slice-threshold", value))
+		opts, err := transferOptions(cpCmd)
+		assert.NoError(t, err)
+		assert.Equal(t, want, opts.SliceThreshold, value)
+	}
+}
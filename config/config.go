@@ -0,0 +1,313 @@
+// Package config reads the storage-synk configuration file. It defines
+// named remotes that can be used in place of a URI, as in
+// "prod-s3:bucket/path":
+//
+//	remotes:
+//	  prod-s3:
+//	    type: s3
+//	    profile: prod
+//	    region: eu-west-1
+//	  archive-gcs:
+//	    type: gcs
+//	    project: my-project
+//	    credentials_file: ~/keys/archive.json
+//	    sse: kms
+//	    sse_kms_key: projects/p/locations/eu/keyRings/r/cryptoKeys/k
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/RA-Balaji/storage-synk/encrypt"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPath names the environment variable that overrides the location of
+// the configuration file.
+const EnvPath = "STORAGE_SYNK_CONFIG"
+
+// Remote types.
+const (
+	TypeS3    = "s3"
+	TypeGCS   = "gcs"
+	TypeAzure = "azure"
+	TypeLocal = "local"
+)
+
+type Config struct {
+	Remotes map[string]Remote `yaml:"remotes"`
+}
+
+// Remote holds the settings of one named remote. Fields that do not apply
+// to its type are ignored.
+type Remote struct {
+	Type string `yaml:"type"`
+	// Profile and Region select the AWS shared config profile and region.
+	Profile string `yaml:"profile"`
+	Region  string `yaml:"region"`
+	// Endpoint overrides the S3 or Azure blob service URL, e.g. for MinIO
+	// or Azurite.
+	Endpoint string `yaml:"endpoint"`
+	// Project and CredentialsFile configure the GCS client.
+	Project         string `yaml:"project"`
+	CredentialsFile string `yaml:"credentials_file"`
+	// Account is the Azure storage account.
+	Account string `yaml:"account"`
+	// Path is the local directory a local remote points at.
+	Path string `yaml:"path"`
+	// SSE, SSEKMSKey and SSECustomerKeyFile choose the server-side
+	// encryption of objects written to the remote, as the --sse flags do.
+	// The customer key is also used to read objects from the remote.
+	SSE                string `yaml:"sse"`
+	SSEKMSKey          string `yaml:"sse_kms_key"`
+	SSECustomerKeyFile string `yaml:"sse_customer_key_file"`
+}
+
+// DefaultPath returns $STORAGE_SYNK_CONFIG, or
+// ~/.config/storage-synk/config.yaml.
+func DefaultPath() (string, error) {
+	if path := os.Getenv(EnvPath); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("Error locating home directory: %v", err)
+	}
+	return filepath.Join(home, ".config", "storage-synk", "config.yaml"), nil
+}
+
+// LoadDefault reads the file at DefaultPath. A missing file yields an
+// empty configuration.
+func LoadDefault() (*Config, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	return cfg, err
+}
+
+// Load reads and validates the configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading config file: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("Error parsing config file %s: %v", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("Error in config file %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+var remoteName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]+$`)
+
+func (c *Config) validate() error {
+	names := make([]string, 0, len(c.Remotes))
+	for name := range c.Remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		r := c.Remotes[name]
+		if !remoteName.MatchString(name) {
+			return fmt.Errorf("remote name %q must be at least two letters, digits, '.', '_' or '-'", name)
+		}
+		switch r.Type {
+		case TypeS3, TypeGCS, TypeLocal:
+		case TypeAzure:
+			if r.Account == "" {
+				return fmt.Errorf("remote %q: azure remotes need an account", name)
+			}
+		default:
+			return fmt.Errorf("remote %q: unknown type %q, expected s3, gcs, azure or local", name, r.Type)
+		}
+		if _, err := r.encryptionMode(); err != nil {
+			return fmt.Errorf("remote %q: %v", name, err)
+		}
+		r.CredentialsFile = expandHome(r.CredentialsFile)
+		r.SSECustomerKeyFile = expandHome(r.SSECustomerKeyFile)
+		r.Path = expandHome(r.Path)
+		c.Remotes[name] = r
+	}
+	return nil
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+var remoteRef = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_.-]+):(.*)$`)
+
+// Parse parses a location given as "remote:bucket/path" or as a URI
+// understood by uri.Parse. Remote locations get the remote's name in
+// URI.Remote. A name that is not configured is an error; local paths
+// containing ":" can be written as "./name:file".
+func (c *Config) Parse(raw string) (uri.URI, error) {
+	m := remoteRef.FindStringSubmatch(raw)
+	if m == nil || strings.HasPrefix(m[2], "//") {
+		return uri.Parse(raw)
+	}
+	name, rest := m[1], m[2]
+	r, ok := c.Remote(name)
+	if !ok {
+		return uri.URI{}, fmt.Errorf("Unknown remote %q in %q", name, raw)
+	}
+
+	var u uri.URI
+	var err error
+	switch r.Type {
+	case TypeS3:
+		u, err = uri.Parse(uri.SchemeS3 + "://" + rest)
+	case TypeGCS:
+		u, err = uri.Parse(uri.SchemeGCS + "://" + rest)
+	case TypeAzure:
+		u, err = uri.Parse(uri.SchemeAzure + "://" + r.Account + "/" + rest)
+	case TypeLocal:
+		path := rest
+		if r.Path != "" {
+			path = strings.TrimSuffix(r.Path, "/") + "/" + rest
+		}
+		u, err = uri.Parse(path)
+	}
+	if err != nil {
+		return uri.URI{}, err
+	}
+	u.Remote = name
+	return u, nil
+}
+
+// ParseRoot parses a location naming a whole provider or Azure account,
+// either as a URI accepted by uri.ParseRoot or as "remote:". It reports
+// false for any other location.
+func (c *Config) ParseRoot(raw string) (uri.URI, bool) {
+	m := remoteRef.FindStringSubmatch(raw)
+	if m == nil || strings.HasPrefix(m[2], "//") {
+		return uri.ParseRoot(raw)
+	}
+	r, ok := c.Remote(m[1])
+	if !ok || strings.TrimSuffix(m[2], "/") != "" {
+		return uri.URI{}, false
+	}
+	u := uri.URI{Remote: m[1]}
+	switch r.Type {
+	case TypeS3:
+		u.Scheme = uri.SchemeS3
+	case TypeGCS:
+		u.Scheme = uri.SchemeGCS
+	case TypeAzure:
+		u.Scheme, u.Account = uri.SchemeAzure, r.Account
+	default:
+		return uri.URI{}, false
+	}
+	return u, true
+}
+
+// Remote returns the settings of the named remote.
+func (c *Config) Remote(name string) (Remote, bool) {
+	if c == nil {
+		return Remote{}, false
+	}
+	r, ok := c.Remotes[name]
+	return r, ok
+}
+
+// WithEnv returns r with the settings that getenv provides replacing those
+// of the file: AWS_PROFILE, AWS_REGION (or AWS_DEFAULT_REGION),
+// AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL) for S3, GOOGLE_CLOUD_PROJECT
+// and GOOGLE_APPLICATION_CREDENTIALS for GCS and AZURE_STORAGE_ENDPOINT
+// for Azure.
+func (r Remote) WithEnv(scheme string, getenv func(string) string) Remote {
+	override := func(field *string, names ...string) {
+		for _, name := range names {
+			if value := getenv(name); value != "" {
+				*field = value
+				return
+			}
+		}
+	}
+	switch scheme {
+	case uri.SchemeS3:
+		override(&r.Profile, "AWS_PROFILE")
+		override(&r.Region, "AWS_REGION", "AWS_DEFAULT_REGION")
+		override(&r.Endpoint, "AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL")
+	case uri.SchemeGCS:
+		override(&r.Project, "GOOGLE_CLOUD_PROJECT")
+		override(&r.CredentialsFile, "GOOGLE_APPLICATION_CREDENTIALS")
+	case uri.SchemeAzure:
+		override(&r.Endpoint, "AZURE_STORAGE_ENDPOINT")
+	}
+	return r
+}
+
+// Encryption returns the server-side encryption configured for objects
+// written to the remote.
+func (r Remote) Encryption() (store.Encryption, error) {
+	mode, err := r.encryptionMode()
+	if err != nil {
+		return store.Encryption{}, err
+	}
+	enc := store.Encryption{Mode: mode, KMSKey: r.SSEKMSKey}
+	if mode == store.EncryptionCustomer {
+		enc.CustomerKey, err = encrypt.ReadKeyFile(r.SSECustomerKeyFile)
+		if err != nil {
+			return store.Encryption{}, err
+		}
+	}
+	return enc, nil
+}
+
+// CustomerKey returns the key of objects stored with SSE-C or CSEK in the
+// remote, or nil.
+func (r Remote) CustomerKey() ([]byte, error) {
+	if r.SSECustomerKeyFile == "" {
+		return nil, nil
+	}
+	return encrypt.ReadKeyFile(r.SSECustomerKeyFile)
+}
+
+func (r Remote) encryptionMode() (store.EncryptionMode, error) {
+	mode := store.EncryptionMode(strings.ToLower(r.SSE))
+	if mode == store.EncryptionDefault {
+		if r.SSEKMSKey != "" {
+			mode = store.EncryptionKMS
+		} else if r.SSECustomerKeyFile != "" {
+			mode = store.EncryptionCustomer
+		}
+	}
+	switch mode {
+	case store.EncryptionDefault, store.EncryptionManaged, store.EncryptionKMS, store.EncryptionCustomer:
+	default:
+		return "", fmt.Errorf("invalid sse %q: expected managed, kms or customer", r.SSE)
+	}
+	if r.SSEKMSKey != "" && mode != store.EncryptionKMS {
+		return "", fmt.Errorf("sse_kms_key needs sse: kms")
+	}
+	if mode == store.EncryptionCustomer && r.SSECustomerKeyFile == "" {
+		return "", fmt.Errorf("sse: customer needs sse_customer_key_file")
+	}
+	return mode, nil
+}
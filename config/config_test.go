@@ -0,0 +1,122 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadAndParse(t *testing.T) {
+	t.Setenv("HOME", "/home/synk")
+	cfg, err := Load(writeConfig(t, `
+remotes:
+  prod-s3:
+    type: s3
+    profile: prod
+    region: eu-west-1
+    endpoint: http://localhost:9000
+  archive-gcs:
+    type: gcs
+    project: archive
+    credentials_file: ~/keys/archive.json
+  blobs:
+    type: azure
+    account: myaccount
+  scratch:
+    type: local
+    path: /tmp/scratch
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, Remote{Type: TypeS3, Profile: "prod", Region: "eu-west-1", Endpoint: "http://localhost:9000"},
+		cfg.Remotes["prod-s3"])
+	assert.Equal(t, "/home/synk/keys/archive.json", cfg.Remotes["archive-gcs"].CredentialsFile)
+
+	for raw, want := range map[string]uri.URI{
+		"prod-s3:bucket/path/":         {Scheme: uri.SchemeS3, Bucket: "bucket", Key: "path/", Remote: "prod-s3"},
+		"archive-gcs:bucket":           {Scheme: uri.SchemeGCS, Bucket: "bucket", Remote: "archive-gcs"},
+		"blobs:container/a.txt":        {Scheme: uri.SchemeAzure, Account: "myaccount", Bucket: "container", Key: "a.txt", Remote: "blobs"},
+		"scratch:out/":                 {Scheme: uri.SchemeFile, Key: "/tmp/scratch/out/", Remote: "scratch"},
+		"s3://bucket/key":              {Scheme: uri.SchemeS3, Bucket: "bucket", Key: "key"},
+		"./notes:v2.txt":               {Scheme: uri.SchemeFile, Key: "./notes:v2.txt"},
+		`C:\data`:                      {Scheme: uri.SchemeFile, Key: filepath.ToSlash(`C:\data`)},
+		"prod-s3:bucket/k?versionId=3": {Scheme: uri.SchemeS3, Bucket: "bucket", Key: "k", VersionID: "3", Remote: "prod-s3"},
+	} {
+		u, err := cfg.Parse(raw)
+		assert.NoError(t, err, raw)
+		assert.Equal(t, want, u, raw)
+	}
+
+	_, err = cfg.Parse("dev-s3:bucket/")
+	assert.ErrorContains(t, err, `Unknown remote "dev-s3"`)
+	_, err = cfg.Parse("prod-s3:Bad_Bucket/")
+	assert.ErrorContains(t, err, "Invalid bucket")
+}
+
+func TestLoadErrors(t *testing.T) {
+	for content, want := range map[string]string{
+		"remotes: [":                                            "Error parsing config file",
+		"remotes:\n  x:\n    type: s3\n":                        "remote name \"x\"",
+		"remotes:\n  ftp-box:\n    type: ftp\n":                 "unknown type \"ftp\"",
+		"remotes:\n  blobs:\n    type: azure\n":                 "azure remotes need an account",
+		"remotes:\n  vault:\n    type: s3\n    sse: aes\n":      "invalid sse \"aes\"",
+		"remotes:\n  vault:\n    type: s3\n    sse: customer\n": "sse: customer needs sse_customer_key_file",
+	} {
+		_, err := Load(writeConfig(t, content))
+		assert.ErrorContains(t, err, want, content)
+	}
+
+	t.Setenv(EnvPath, filepath.Join(t.TempDir(), "missing.yaml"))
+	cfg, err := LoadDefault()
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Remotes)
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "Error reading config file")
+}
+
+func TestWithEnv(t *testing.T) {
+	env := map[string]string{"AWS_PROFILE": "ci", "AWS_DEFAULT_REGION": "us-east-2", "GOOGLE_CLOUD_PROJECT": "other"}
+	r := Remote{Type: TypeS3, Profile: "prod", Region: "eu-west-1", Endpoint: "http://minio", Project: "p"}
+
+	s3 := r.WithEnv(uri.SchemeS3, func(name string) string { return env[name] })
+	assert.Equal(t, "ci", s3.Profile)
+	assert.Equal(t, "us-east-2", s3.Region)
+	assert.Equal(t, "http://minio", s3.Endpoint)
+	assert.Equal(t, "p", s3.Project)
+
+	gcs := r.WithEnv(uri.SchemeGCS, func(name string) string { return env[name] })
+	assert.Equal(t, "other", gcs.Project)
+	assert.Equal(t, "prod", gcs.Profile)
+}
+
+func TestRemoteEncryption(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	key := bytes.Repeat([]byte{7}, 32)
+	assert.NoError(t, os.WriteFile(keyFile, key, 0600))
+
+	enc, err := Remote{SSEKMSKey: "arn:aws:kms:key"}.Encryption()
+	assert.NoError(t, err)
+	assert.Equal(t, store.Encryption{Mode: store.EncryptionKMS, KMSKey: "arn:aws:kms:key"}, enc)
+
+	r := Remote{SSECustomerKeyFile: keyFile}
+	enc, err = r.Encryption()
+	assert.NoError(t, err)
+	assert.Equal(t, store.Encryption{Mode: store.EncryptionCustomer, CustomerKey: key}, enc)
+	got, err := r.CustomerKey()
+	assert.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	enc, err = Remote{}.Encryption()
+	assert.NoError(t, err)
+	assert.Equal(t, store.Encryption{}, enc)
+}
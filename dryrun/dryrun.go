@@ -0,0 +1,117 @@
+// Package dryrun collects the actions a command would take instead of
+// performing them. The Plan travels in the context, so code deep in the
+// call chain, such as the provisioning helpers, can report what it would
+// do and return without calling the cloud APIs.
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/RA-Balaji/storage-synk/utils"
+)
+
+type Op string
+
+const (
+	Copy      Op = "copy"
+	Overwrite Op = "overwrite"
+	Skip      Op = "skip"
+	Delete    Op = "delete"
+	Create    Op = "create"
+	Update    Op = "update"
+)
+
+// Action is one step of a plan. Size is -1 for actions that move no data,
+// such as provisioning calls.
+type Action struct {
+	Op     Op
+	Source string
+	Target string
+	Size   int64
+}
+
+// Plan is the list of actions of a dry run. It is safe for concurrent use.
+type Plan struct {
+	mu      sync.Mutex
+	actions []Action
+}
+
+type planKey struct{}
+
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// WithPlan returns a context that makes everything using it a dry run
+// recording into plan.
+func WithPlan(ctx context.Context, plan *Plan) context.Context {
+	return context.WithValue(ctx, planKey{}, plan)
+}
+
+// FromContext returns the plan of a dry run, or nil when ctx is not one.
+func FromContext(ctx context.Context) *Plan {
+	plan, _ := ctx.Value(planKey{}).(*Plan)
+	return plan
+}
+
+// Report records a provisioning action when ctx is a dry run and returns
+// true, in which case the caller must not perform the action.
+func Report(ctx context.Context, op Op, target string) bool {
+	plan := FromContext(ctx)
+	if plan == nil {
+		return false
+	}
+	plan.Add(Action{Op: op, Target: target, Size: -1})
+	return true
+}
+
+func (p *Plan) Add(action Action) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.actions = append(p.actions, action)
+}
+
+// Actions returns the recorded actions in the order they were added.
+func (p *Plan) Actions() []Action {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Action(nil), p.actions...)
+}
+
+// Print writes one line per action followed by the totals.
+func (p *Plan) Print(w io.Writer) {
+	var (
+		transfers, skipped, deleted int
+		transferBytes, deleteBytes  int64
+	)
+	for _, a := range p.Actions() {
+		size := ""
+		if a.Size >= 0 {
+			size = utils.FormatSize(a.Size)
+		}
+		target := a.Target
+		if a.Source != "" {
+			target = a.Source + " -> " + a.Target
+		}
+		fmt.Fprintf(w, "%-10s %10s  %s\n", a.Op, size, target)
+
+		switch a.Op {
+		case Copy, Overwrite:
+			transfers++
+			transferBytes += a.Size
+		case Skip:
+			skipped++
+		case Delete:
+			deleted++
+			if a.Size > 0 {
+				deleteBytes += a.Size
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "Dry run: %d to transfer (%d bytes, %s), %d skipped, %d to delete (%d bytes)\n",
+		transfers, transferBytes, utils.FormatSize(transferBytes), skipped, deleted, deleteBytes)
+}
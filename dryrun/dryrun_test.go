@@ -0,0 +1,32 @@
+package dryrun
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportOnlyInDryRun(t *testing.T) {
+	assert.False(t, Report(context.Background(), Create, "S3 bucket b"))
+
+	plan := NewPlan()
+	ctx := WithPlan(context.Background(), plan)
+	assert.True(t, Report(ctx, Create, "S3 bucket b"))
+	assert.Equal(t, []Action{{Op: Create, Target: "S3 bucket b", Size: -1}}, plan.Actions())
+}
+
+func TestPrintTotals(t *testing.T) {
+	plan := NewPlan()
+	plan.Add(Action{Op: Copy, Source: "a/x", Target: "b/x", Size: 1024})
+	plan.Add(Action{Op: Overwrite, Source: "a/y", Target: "b/y", Size: 2048})
+	plan.Add(Action{Op: Skip, Source: "a/z", Target: "b/z", Size: 10})
+	plan.Add(Action{Op: Create, Target: "VPC v", Size: -1})
+
+	var out bytes.Buffer
+	plan.Print(&out)
+	assert.Contains(t, out.String(), "copy          1.0 KiB  a/x -> b/x\n")
+	assert.Contains(t, out.String(), "create                 VPC v\n")
+	assert.Contains(t, out.String(), "Dry run: 2 to transfer (3072 bytes, 3.0 KiB), 1 skipped, 0 to delete (0 bytes)\n")
+}
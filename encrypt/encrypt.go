@@ -0,0 +1,266 @@
+// Package encrypt implements client-side encryption of objects. Every
+// object gets its own random AES-256 data key; the content is sealed with
+// AES-256-GCM in 64 KiB chunks, and the data key is stored with the object,
+// wrapped by a key from a key file or derived from a passphrase. The
+// parameters needed to decrypt travel in the object metadata.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Metadata keys. Underscores keep them valid on Azure, whose metadata
+// names must be identifiers.
+const (
+	MetaAlgorithm  = "synk_encryption"
+	MetaWrappedKey = "synk_wrapped_key"
+	MetaKDF        = "synk_kdf"
+	MetaSalt       = "synk_salt"
+	MetaPlainSize  = "synk_plain_size"
+)
+
+const (
+	algorithm = "AES256-GCM-64K"
+	kdfKey    = "key-file"
+	kdfScrypt = "scrypt"
+	keySize   = 32
+)
+
+// scrypt cost parameters for passphrases.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Keyring holds the key that wraps the data keys of objects: either a key
+// read from a file, or a passphrase from which keys are derived.
+type Keyring struct {
+	key        []byte
+	passphrase []byte
+	// salt is used to derive the key for objects encrypted by this
+	// process, so that the passphrase is stretched only once.
+	salt []byte
+
+	mu      sync.Mutex
+	derived map[string][]byte
+}
+
+// LoadKeyFile returns a keyring for the key in a file read by ReadKeyFile.
+func LoadKeyFile(path string) (*Keyring, error) {
+	key, err := ReadKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Keyring{key: key}, nil
+}
+
+// ReadKeyFile reads a 256-bit key stored as 32 raw bytes, or as hex or
+// base64 text.
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading key file: %v", err)
+	}
+	if len(data) == keySize {
+		return data, nil
+	}
+	text := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("Key file %s must hold a 256-bit key as 32 raw bytes, hex or base64", path)
+}
+
+// FromPassphrase returns a keyring whose keys are derived from passphrase
+// with scrypt.
+func FromPassphrase(passphrase string) (*Keyring, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("Empty passphrase")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &Keyring{passphrase: []byte(passphrase), salt: salt, derived: map[string][]byte{}}, nil
+}
+
+// IsEncrypted reports whether metadata describes an encrypted object.
+func IsEncrypted(metadata map[string]string) bool {
+	return lookup(metadata, MetaAlgorithm) != ""
+}
+
+// Encrypt returns a reader of the encrypted content of r, its size (-1 if
+// size is unknown), and the metadata to store with the object.
+func (k *Keyring) Encrypt(r io.Reader, size int64) (io.Reader, int64, map[string]string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, 0, nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	metadata := map[string]string{MetaAlgorithm: algorithm}
+	wrapKey := k.key
+	if wrapKey != nil {
+		metadata[MetaKDF] = kdfKey
+	} else {
+		wrapKey, err = k.derive(k.salt)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		metadata[MetaKDF] = kdfScrypt
+		metadata[MetaSalt] = base64.StdEncoding.EncodeToString(k.salt)
+	}
+	wrapped, err := wrap(wrapKey, dataKey)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	metadata[MetaWrappedKey] = wrapped
+
+	encSize := int64(-1)
+	if size >= 0 {
+		encSize = CiphertextSize(size)
+		metadata[MetaPlainSize] = strconv.FormatInt(size, 10)
+	}
+	return newEncryptReader(r, aead, size), encSize, metadata, nil
+}
+
+// Decrypt returns a reader of the plaintext of r, which holds the content
+// of an object with the given metadata.
+func (k *Keyring) Decrypt(r io.Reader, metadata map[string]string) (io.Reader, error) {
+	if alg := lookup(metadata, MetaAlgorithm); alg != algorithm {
+		return nil, fmt.Errorf("Unsupported encryption %q", alg)
+	}
+
+	var wrapKey []byte
+	switch kdf := lookup(metadata, MetaKDF); kdf {
+	case kdfKey:
+		if k.key == nil {
+			return nil, fmt.Errorf("Object was encrypted with a key file, not a passphrase")
+		}
+		wrapKey = k.key
+	case kdfScrypt:
+		if k.passphrase == nil {
+			return nil, fmt.Errorf("Object was encrypted with a passphrase, not a key file")
+		}
+		salt, err := base64.StdEncoding.DecodeString(lookup(metadata, MetaSalt))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid encryption salt: %v", err)
+		}
+		wrapKey, err = k.derive(salt)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported key derivation %q", kdf)
+	}
+
+	dataKey, err := unwrap(wrapKey, lookup(metadata, MetaWrappedKey))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return newDecryptReader(r, aead), nil
+}
+
+// PlainSize returns the plaintext size recorded in metadata, or computes
+// it from the encrypted size.
+func PlainSize(metadata map[string]string, encSize int64) int64 {
+	if size, err := strconv.ParseInt(lookup(metadata, MetaPlainSize), 10, 64); err == nil {
+		return size
+	}
+	return PlaintextSize(encSize)
+}
+
+// derive stretches the passphrase with salt, caching the result: objects
+// encrypted in one run share their salt.
+func (k *Keyring) derive(salt []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if key, ok := k.derived[string(salt)]; ok {
+		return key, nil
+	}
+	key, err := scrypt.Key(k.passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("Error deriving key from passphrase: %v", err)
+	}
+	k.derived[string(salt)] = key
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// wrap seals dataKey with wrapKey and returns nonce and ciphertext as
+// base64.
+func wrap(wrapKey, dataKey []byte) (string, error) {
+	aead, err := newAEAD(wrapKey)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, dataKey, nil)), nil
+}
+
+func unwrap(wrapKey []byte, wrapped string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid wrapped key: %v", err)
+	}
+	aead, err := newAEAD(wrapKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("Invalid wrapped key")
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Wrong key or passphrase for this object")
+	}
+	return dataKey, nil
+}
+
+// lookup finds key in metadata regardless of case; some backends return
+// metadata names capitalized.
+func lookup(metadata map[string]string, key string) string {
+	if v, ok := metadata[key]; ok {
+		return v
+	}
+	for k, v := range metadata {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
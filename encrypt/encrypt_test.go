@@ -0,0 +1,136 @@
+package encrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testKeyring(t *testing.T) *Keyring {
+	path := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(path, bytes.Repeat([]byte{7}, keySize), 0600))
+	k, err := LoadKeyFile(path)
+	assert.NoError(t, err)
+	return k
+}
+
+func content(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func encryptAll(t *testing.T, k *Keyring, plain []byte) ([]byte, map[string]string) {
+	r, size, metadata, err := k.Encrypt(bytes.NewReader(plain), int64(len(plain)))
+	assert.NoError(t, err)
+	sealed, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, size, int64(len(sealed)))
+	return sealed, metadata
+}
+
+func TestRoundTrip(t *testing.T) {
+	k := testKeyring(t)
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 5} {
+		plain := content(size)
+		sealed, metadata := encryptAll(t, k, plain)
+		assert.Equal(t, CiphertextSize(int64(size)), int64(len(sealed)), size)
+		assert.Equal(t, int64(size), PlaintextSize(int64(len(sealed))), size)
+		assert.Equal(t, int64(size), PlainSize(metadata, int64(len(sealed))), size)
+		assert.True(t, IsEncrypted(metadata))
+
+		r, err := k.Decrypt(bytes.NewReader(sealed), metadata)
+		assert.NoError(t, err)
+		got, err := io.ReadAll(r)
+		assert.NoError(t, err, size)
+		assert.Equal(t, plain, got, size)
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	k, err := FromPassphrase("correct horse")
+	assert.NoError(t, err)
+	plain := content(1000)
+	sealed, metadata := encryptAll(t, k, plain)
+	assert.Equal(t, kdfScrypt, metadata[MetaKDF])
+
+	// A new process derives the key again from the stored salt.
+	other, _ := FromPassphrase("correct horse")
+	r, err := other.Decrypt(bytes.NewReader(sealed), metadata)
+	assert.NoError(t, err)
+	got, _ := io.ReadAll(r)
+	assert.Equal(t, plain, got)
+
+	wrong, _ := FromPassphrase("battery staple")
+	_, err = wrong.Decrypt(bytes.NewReader(sealed), metadata)
+	assert.ErrorContains(t, err, "Wrong key or passphrase")
+
+	_, err = testKeyring(t).Decrypt(bytes.NewReader(sealed), metadata)
+	assert.ErrorContains(t, err, "encrypted with a passphrase")
+}
+
+func TestDetectsTampering(t *testing.T) {
+	k := testKeyring(t)
+	sealed, metadata := encryptAll(t, k, content(2*chunkSize+10))
+
+	decrypt := func(data []byte) error {
+		r, err := k.Decrypt(bytes.NewReader(data), metadata)
+		assert.NoError(t, err)
+		_, err = io.ReadAll(r)
+		return err
+	}
+
+	flipped := append([]byte(nil), sealed...)
+	flipped[chunkSize+100] ^= 1
+	assert.ErrorIs(t, decrypt(flipped), ErrCorrupt)
+
+	// Dropping the last chunk leaves a stream without a final chunk.
+	assert.ErrorIs(t, decrypt(sealed[:2*(chunkSize+tagSize)]), ErrCorrupt)
+
+	// Swapping two full chunks breaks their numbering.
+	swapped := append([]byte(nil), sealed[chunkSize+tagSize:2*(chunkSize+tagSize)]...)
+	swapped = append(swapped, sealed[:chunkSize+tagSize]...)
+	swapped = append(swapped, sealed[2*(chunkSize+tagSize):]...)
+	assert.ErrorIs(t, decrypt(swapped), ErrCorrupt)
+}
+
+func TestEncryptChecksSize(t *testing.T) {
+	k := testKeyring(t)
+	r, _, _, err := k.Encrypt(bytes.NewReader(content(10)), 20)
+	assert.NoError(t, err)
+	_, err = io.ReadAll(r)
+	assert.ErrorContains(t, err, "read 10 bytes, expected 20")
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	key := content(keySize)
+	for name, data := range map[string]string{
+		"hex":    hex.EncodeToString(key) + "\n",
+		"base64": base64.StdEncoding.EncodeToString(key),
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		k, err := LoadKeyFile(path)
+		assert.NoError(t, err, name)
+		assert.Equal(t, key, k.key, name)
+	}
+
+	path := filepath.Join(dir, "short")
+	assert.NoError(t, os.WriteFile(path, []byte("secret"), 0600))
+	_, err := LoadKeyFile(path)
+	assert.ErrorContains(t, err, "256-bit key")
+}
+
+func TestLookupIgnoresCase(t *testing.T) {
+	assert.True(t, IsEncrypted(map[string]string{"Synk_encryption": algorithm}))
+	assert.False(t, IsEncrypted(map[string]string{"other": "x"}))
+}
@@ -0,0 +1,186 @@
+package encrypt
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// chunkSize is the plaintext size of every chunk but the last. Each chunk
+// is sealed on its own, so memory use stays at one chunk per stream.
+const chunkSize = 64 * 1024
+
+// ErrCorrupt is returned when ciphertext fails authentication: it was
+// modified, truncated, or encrypted with another key.
+var ErrCorrupt = errors.New("encrypted content is corrupt or was encrypted with another key")
+
+// CiphertextSize returns the encrypted size of plaintext of the given size.
+func CiphertextSize(plain int64) int64 {
+	chunks := (plain + chunkSize - 1) / chunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return plain + chunks*tagSize
+}
+
+// PlaintextSize is the inverse of CiphertextSize.
+func PlaintextSize(cipher int64) int64 {
+	chunks := (cipher + chunkSize + tagSize - 1) / (chunkSize + tagSize)
+	if chunks == 0 {
+		chunks = 1
+	}
+	return cipher - chunks*tagSize
+}
+
+const tagSize = 16
+
+// chunkNonce numbers the chunks and marks the last one, so that chunks
+// cannot be reordered, dropped or cut off at the end without failing
+// authentication. Data keys are never reused, so no random part is needed.
+func chunkNonce(counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[7:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// encryptReader seals the plaintext read from src chunk by chunk.
+type encryptReader struct {
+	src  io.Reader
+	aead cipher.AEAD
+	// size is the expected plaintext size, or -1.
+	size int64
+	read int64
+
+	// plain holds one chunk plus one byte of lookahead, needed to tell
+	// whether the chunk is the last one.
+	plain   []byte
+	have    int
+	out     []byte
+	pending []byte
+	counter uint32
+	done    bool
+}
+
+func newEncryptReader(src io.Reader, aead cipher.AEAD, size int64) *encryptReader {
+	return &encryptReader{
+		src:   src,
+		aead:  aead,
+		size:  size,
+		plain: make([]byte, chunkSize+1),
+		out:   make([]byte, 0, chunkSize+tagSize),
+	}
+}
+
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.pending) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+func (e *encryptReader) sealNext() error {
+	n, err := io.ReadFull(e.src, e.plain[e.have:])
+	total := e.have + n
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	last := total <= chunkSize
+	length := total
+	if !last {
+		length = chunkSize
+	}
+
+	e.read += int64(length)
+	if last && e.size >= 0 && e.read != e.size {
+		return fmt.Errorf("Error encrypting: read %d bytes, expected %d", e.read, e.size)
+	}
+	if e.counter == ^uint32(0) {
+		return errors.New("Error encrypting: content too large")
+	}
+
+	e.pending = e.aead.Seal(e.out[:0], chunkNonce(e.counter, last), e.plain[:length], nil)
+	e.counter++
+	e.done = last
+	if !last {
+		e.plain[0] = e.plain[chunkSize]
+		e.have = 1
+	}
+	return nil
+}
+
+// decryptReader opens the chunks read from src.
+type decryptReader struct {
+	src  io.Reader
+	aead cipher.AEAD
+
+	sealed  []byte
+	have    int
+	out     []byte
+	pending []byte
+	counter uint32
+	done    bool
+}
+
+func newDecryptReader(src io.Reader, aead cipher.AEAD) *decryptReader {
+	return &decryptReader{
+		src:    src,
+		aead:   aead,
+		sealed: make([]byte, chunkSize+tagSize+1),
+		out:    make([]byte, 0, chunkSize),
+	}
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.openNext(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+func (d *decryptReader) openNext() error {
+	n, err := io.ReadFull(d.src, d.sealed[d.have:])
+	total := d.have + n
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	last := total <= chunkSize+tagSize
+	length := total
+	if !last {
+		length = chunkSize + tagSize
+	}
+	if length < tagSize {
+		return ErrCorrupt
+	}
+
+	plain, err := d.aead.Open(d.out[:0], chunkNonce(d.counter, last), d.sealed[:length], nil)
+	if err != nil {
+		return ErrCorrupt
+	}
+	d.pending = plain
+	d.counter++
+	d.done = last
+	if !last {
+		d.sealed[0] = d.sealed[chunkSize+tagSize]
+		d.have = 1
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+// Package filter selects the objects of a transfer by key, size and
+// modification time. Keys are matched relative to the source prefix, the
+// same way for local walks and remote listings.
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/uri"
+)
+
+type Options struct {
+	// Include and Exclude are globs. A glob without "/" matches the base
+	// name at any depth, a leading "/" anchors it at the source prefix and
+	// a trailing "/" selects everything below a directory.
+	Include []string
+	Exclude []string
+	// IncludeRegex and ExcludeRegex match the whole relative key.
+	IncludeRegex []string
+	ExcludeRegex []string
+	// IncludeFrom and ExcludeFrom are files with one glob per line. Blank
+	// lines and lines starting with "#" are ignored.
+	IncludeFrom []string
+	ExcludeFrom []string
+	// MinSize and MaxSize bound the object size in bytes, inclusive. Zero
+	// means no bound.
+	MinSize int64
+	MaxSize int64
+	// Objects modified before NewerThan or after OlderThan are skipped.
+	NewerThan time.Time
+	OlderThan time.Time
+}
+
+// Filter decides which objects are transferred. A nil Filter selects
+// everything.
+type Filter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	// prefixes are the listing prefixes the includes are confined to, or
+	// nil when an include can match anywhere.
+	prefixes []string
+
+	minSize, maxSize     int64
+	newerThan, olderThan time.Time
+}
+
+// New compiles opts. It returns nil if opts select every object.
+func New(opts Options) (*Filter, error) {
+	include, exclude := opts.Include, opts.Exclude
+	for _, name := range opts.IncludeFrom {
+		globs, err := readFile(name)
+		if err != nil {
+			return nil, err
+		}
+		include = append(include, globs...)
+	}
+	for _, name := range opts.ExcludeFrom {
+		globs, err := readFile(name)
+		if err != nil {
+			return nil, err
+		}
+		exclude = append(exclude, globs...)
+	}
+	if opts.MaxSize > 0 && opts.MinSize > opts.MaxSize {
+		return nil, fmt.Errorf("Invalid size range: minimum %d is above maximum %d", opts.MinSize, opts.MaxSize)
+	}
+
+	f := &Filter{
+		minSize:   opts.MinSize,
+		maxSize:   opts.MaxSize,
+		newerThan: opts.NewerThan,
+		olderThan: opts.OlderThan,
+	}
+	pushdown := len(opts.IncludeRegex) == 0
+	for _, glob := range include {
+		re, prefix, err := compileGlob(glob)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, re)
+		if prefix == "" {
+			pushdown = false
+		}
+		f.prefixes = append(f.prefixes, prefix)
+	}
+	if !pushdown {
+		f.prefixes = nil
+	}
+	f.prefixes = outermost(f.prefixes)
+
+	for _, glob := range exclude {
+		re, _, err := compileGlob(glob)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, re)
+	}
+	for _, expr := range opts.IncludeRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid include regex %q: %v", expr, err)
+		}
+		f.include = append(f.include, re)
+	}
+	for _, expr := range opts.ExcludeRegex {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid exclude regex %q: %v", expr, err)
+		}
+		f.exclude = append(f.exclude, re)
+	}
+
+	if len(f.include) == 0 && len(f.exclude) == 0 && f.minSize == 0 && f.maxSize == 0 &&
+		f.newerThan.IsZero() && f.olderThan.IsZero() {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// compileGlob compiles a filter glob and returns the key prefix every
+// match starts with.
+func compileGlob(glob string) (*regexp.Regexp, string, error) {
+	pattern := strings.TrimPrefix(glob, "/")
+	anchored := pattern != glob
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return nil, "", fmt.Errorf("Invalid filter pattern %q", glob)
+	}
+	if !anchored && !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	if dir {
+		pattern += "/**"
+	}
+
+	re, err := uri.CompileGlob(pattern)
+	if err != nil {
+		return nil, "", fmt.Errorf("Invalid filter pattern %q: %v", glob, err)
+	}
+	head := pattern
+	if i := strings.IndexAny(pattern, "*?["); i >= 0 {
+		head = pattern[:i]
+	}
+	return re, head[:strings.LastIndex(head, "/")+1], nil
+}
+
+// outermost drops the prefixes that lie below another one.
+func outermost(prefixes []string) []string {
+	if prefixes == nil {
+		return nil
+	}
+	sort.Strings(prefixes)
+	var out []string
+	for _, p := range prefixes {
+		if len(out) > 0 && strings.HasPrefix(p, out[len(out)-1]) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func readFile(name string) ([]string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading filter file: %v", err)
+	}
+	defer file.Close()
+
+	var globs []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		globs = append(globs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Error reading filter file %s: %v", name, err)
+	}
+	return globs, nil
+}
+
+// Match reports whether obj, whose key relative to the source prefix is
+// rel, is selected: it matches no exclude, at least one include if there
+// are any, and the size and time bounds.
+func (f *Filter) Match(rel string, obj store.ObjectInfo) bool {
+	if f == nil {
+		return true
+	}
+	if obj.Size < f.minSize || (f.maxSize > 0 && obj.Size > f.maxSize) {
+		return false
+	}
+	if !f.newerThan.IsZero() && obj.ModTime.Before(f.newerThan) {
+		return false
+	}
+	if !f.olderThan.IsZero() && obj.ModTime.After(f.olderThan) {
+		return false
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(rel) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Prefixes returns the prefixes, relative to the source prefix, that hold
+// every object the includes can match, so that only they need to be
+// listed. It returns nil when the whole source has to be listed.
+func (f *Filter) Prefixes() []string {
+	if f == nil {
+		return nil
+	}
+	return f.prefixes
+}
+
+var ageUnits = map[string]time.Duration{
+	"s": time.Second, "m": time.Minute, "h": time.Hour,
+	"d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+}
+
+// ParseTime parses the argument of --newer-than and --older-than: an age
+// such as "90m", "7d" or "2w" counted back from now, a date such as
+// "2024-05-01" or an RFC 3339 time.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if len(s) > 1 {
+		if unit, ok := ageUnits[s[len(s)-1:]]; ok {
+			n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+			if err == nil && n >= 0 {
+				return now.Add(-time.Duration(n * float64(unit))), nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid time %q: expected an age such as 7d or a date such as 2024-05-01", s)
+}
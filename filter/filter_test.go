@@ -0,0 +1,127 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchGlobs(t *testing.T) {
+	f, err := New(Options{
+		Include: []string{"*.log", "/docs/", "src/**/*.go"},
+		Exclude: []string{"tmp/", "*_test.go"},
+	})
+	assert.NoError(t, err)
+
+	for rel, want := range map[string]bool{
+		"app.log":            true,
+		"deep/dir/app.log":   true,
+		"tmp/app.log":        false,
+		"a/tmp/app.log":      false,
+		"docs/a/readme.md":   true,
+		"sub/docs/readme.md": false,
+		"src/main.go":        true,
+		"src/pkg/util.go":    true,
+		"src/pkg/x_test.go":  false,
+		"main.go":            false,
+		"app.logs":           false,
+	} {
+		assert.Equal(t, want, f.Match(rel, store.ObjectInfo{Key: rel}), rel)
+	}
+	// "*.log" can match anywhere, so the whole source has to be listed.
+	assert.Nil(t, f.Prefixes())
+}
+
+func TestMatchRegexSizeAndTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	f, err := New(Options{
+		IncludeRegex: []string{`^logs/\d{4}/`},
+		ExcludeRegex: []string{`\.tmp$`},
+		MinSize:      10,
+		MaxSize:      100,
+		NewerThan:    now.Add(-48 * time.Hour),
+		OlderThan:    now.Add(-time.Hour),
+	})
+	assert.NoError(t, err)
+
+	obj := store.ObjectInfo{Size: 50, ModTime: now.Add(-24 * time.Hour)}
+	assert.True(t, f.Match("logs/2024/a.gz", obj))
+	assert.False(t, f.Match("logs/2024/a.tmp", obj))
+	assert.False(t, f.Match("logs/latest/a.gz", obj))
+
+	small, large := obj, obj
+	small.Size, large.Size = 9, 101
+	assert.False(t, f.Match("logs/2024/a.gz", small))
+	assert.False(t, f.Match("logs/2024/a.gz", large))
+
+	old, recent := obj, obj
+	old.ModTime, recent.ModTime = now.Add(-72*time.Hour), now.Add(-time.Minute)
+	assert.False(t, f.Match("logs/2024/a.gz", old))
+	assert.False(t, f.Match("logs/2024/a.gz", recent))
+}
+
+func TestPrefixes(t *testing.T) {
+	f, err := New(Options{Include: []string{"logs/2024/**", "logs/**/*.gz", "/images/*.png", "logs/2024/05/"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"images/", "logs/"}, f.Prefixes())
+
+	f, err = New(Options{Include: []string{"logs/**"}, IncludeRegex: []string{"^x"}})
+	assert.NoError(t, err)
+	assert.Nil(t, f.Prefixes())
+
+	f, err = New(Options{})
+	assert.NoError(t, err)
+	assert.Nil(t, f)
+	assert.True(t, f.Match("anything", store.ObjectInfo{}))
+}
+
+func TestFilterFiles(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "exclude.txt")
+	assert.NoError(t, os.WriteFile(name, []byte("# build output\n\nbin/\n*.o\n"), 0644))
+
+	f, err := New(Options{ExcludeFrom: []string{name}})
+	assert.NoError(t, err)
+	assert.False(t, f.Match("bin/app", store.ObjectInfo{}))
+	assert.False(t, f.Match("src/main.o", store.ObjectInfo{}))
+	assert.True(t, f.Match("src/main.c", store.ObjectInfo{}))
+
+	_, err = New(Options{IncludeFrom: []string{filepath.Join(t.TempDir(), "missing")}})
+	assert.ErrorContains(t, err, "Error reading filter file")
+}
+
+func TestInvalidOptions(t *testing.T) {
+	_, err := New(Options{Include: []string{"[abc"}})
+	assert.ErrorContains(t, err, "Invalid filter pattern")
+	_, err = New(Options{ExcludeRegex: []string{"("}})
+	assert.ErrorContains(t, err, "Invalid exclude regex")
+	_, err = New(Options{MinSize: 10, MaxSize: 5})
+	assert.ErrorContains(t, err, "Invalid size range")
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	for s, want := range map[string]time.Time{
+		"7d":                   now.Add(-7 * 24 * time.Hour),
+		"2w":                   now.Add(-14 * 24 * time.Hour),
+		"90m":                  now.Add(-90 * time.Minute),
+		"1.5h":                 now.Add(-90 * time.Minute),
+		"2024-05-01T08:00:00Z": time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+	} {
+		got, err := ParseTime(s, now)
+		assert.NoError(t, err, s)
+		assert.True(t, want.Equal(got), s)
+	}
+
+	got, err := ParseTime("2024-05-01", now)
+	assert.NoError(t, err)
+	assert.True(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local).Equal(got))
+
+	for _, s := range []string{"", "7", "soon", "-3d"} {
+		_, err := ParseTime(s, now)
+		assert.Error(t, err, s)
+	}
+}
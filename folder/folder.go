@@ -0,0 +1,71 @@
+// Package folder copies whole local folders to and from buckets. It sits
+// above transfer so that the backend packages do not depend on the
+// transfer engine.
+package folder
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/RA-Balaji/storage-synk/aws"
+	"github.com/RA-Balaji/storage-synk/gcp"
+	"github.com/RA-Balaji/storage-synk/local"
+	"github.com/RA-Balaji/storage-synk/transfer"
+)
+
+// GcsDownload downloads every object of the bucket into
+// destinationPath/bucketName. Large objects are fetched as concurrent
+// ranges written at their offsets.
+func GcsDownload(ctx context.Context, bucketName, destinationPath string) error {
+	gcsStore, err := gcp.NewGCSStore(ctx, gcp.Options{})
+	if err != nil {
+		return err
+	}
+	defer gcsStore.Close()
+
+	localFolder := filepath.ToSlash(filepath.Join(destinationPath, bucketName))
+	return transfer.Copy(ctx,
+		transfer.Endpoint{Store: gcsStore, Bucket: bucketName},
+		transfer.Endpoint{Store: local.NewFileStore(), Prefix: localFolder},
+		transfer.Options{})
+}
+
+// GcsUpload uploads every file below folderName to the bucket, keyed by
+// its path relative to folderName. All files are attempted; the error lists
+// each one that failed.
+func GcsUpload(ctx context.Context, bucketName, folderName string, concurrency int) error {
+	gcsStore, err := gcp.NewGCSStore(ctx, gcp.Options{})
+	if err != nil {
+		return err
+	}
+	defer gcsStore.Close()
+
+	return transfer.Copy(ctx,
+		transfer.Endpoint{Store: local.NewFileStore(), Prefix: filepath.ToSlash(folderName) + "/"},
+		transfer.Endpoint{Store: gcsStore, Bucket: bucketName},
+		transfer.Options{Concurrency: concurrency})
+}
+
+// S3Upload uploads every file below folderName to the bucket, keyed by its
+// path relative to folderName. All files are attempted; the error lists
+// each one that failed.
+func S3Upload(
+	ctx context.Context,
+	profile, bucketName, folderName string, concurrency int) error {
+
+	s3Store, err := aws.NewS3Store(ctx, aws.S3Options{Profile: profile})
+	if err != nil {
+		return err
+	}
+
+	err = transfer.Copy(ctx,
+		transfer.Endpoint{Store: local.NewFileStore(), Prefix: filepath.ToSlash(folderName) + "/"},
+		transfer.Endpoint{Store: s3Store, Bucket: bucketName},
+		transfer.Options{Concurrency: concurrency})
+	if err != nil {
+		return fmt.Errorf("Error Uploading folder [%s]: %v", folderName, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package folder
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRegion = "us-east-1"
+
+func TestS3Upload(t *testing.T) {
+	ctx := context.Background()
+
+	// Create bucket if it doesn't exist
+	err := aws.S3BucketCreate(ctx, testRegion, "balaji-tests-2")
+
+	// Create a temporary directory and some files
+	tmpDir, err := os.MkdirTemp("", "testdir")
+	assert.NoError(t, err)
+	log.Println("tmpDir:", tmpDir)
+
+	filePaths := []string{
+		filepath.Join(tmpDir, "file1.txt"),
+		filepath.Join(tmpDir, "subdir", "file2.txt"),
+		filepath.Join(tmpDir, "subdir", "file3.txt"),
+	}
+
+	// Create file and lead content
+	for _, path := range filePaths {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		f, err := os.Create(path)
+		assert.NoError(t, err)
+		f.WriteString("test content")
+		f.Close()
+	}
+
+	// Perform the upload, 10 files at a time
+	err = S3Upload(ctx, testRegion, "balaji-tests-2", tmpDir, 10)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package gcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/storage"
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/store"
+	"google.golang.org/api/iterator"
+)
+
+// deleteConcurrency is the number of object generations DeleteBucket
+// deletes at once when emptying a bucket.
+const deleteConcurrency = 16
+
+var _ store.BucketManager = (*GCSStore)(nil)
+
+// CreateBucket creates bucket in the configured project with all options
+// in one request. Google-managed encryption is the default and needs no
+// setting.
+func (g *GCSStore) CreateBucket(ctx context.Context, bucket string, opts store.BucketOptions) error {
+	if opts.Encryption.Mode == store.EncryptionCustomer {
+		return fmt.Errorf("CSEK cannot be the default encryption of a bucket")
+	}
+	if g.project == "" {
+		return fmt.Errorf("Creating GCS buckets needs a project: set GOOGLE_CLOUD_PROJECT or the project of a remote")
+	}
+	if dryrun.Report(ctx, dryrun.Create, "GCS bucket "+bucket) {
+		return nil
+	}
+
+	attrs := &storage.BucketAttrs{
+		Location:          opts.Location,
+		StorageClass:      opts.StorageClass,
+		VersioningEnabled: opts.Versioning,
+		Labels:            opts.Labels,
+	}
+	if key := kmsKeyName(opts.Encryption); key != "" {
+		attrs.Encryption = &storage.BucketEncryption{DefaultKMSKeyName: key}
+	}
+	if opts.BlockPublicAccess {
+		attrs.PublicAccessPrevention = storage.PublicAccessPreventionEnforced
+	}
+	if err := g.client.Bucket(bucket).Create(ctx, g.project, attrs); err != nil {
+		return fmt.Errorf("Error creating GCS bucket [%s]: %w", bucket, err)
+	}
+	return nil
+}
+
+// DeleteBucket deletes bucket. With force it first deletes every object
+// generation, deleteConcurrency at a time.
+func (g *GCSStore) DeleteBucket(ctx context.Context, bucket string, force bool) error {
+	if dryrun.Report(ctx, dryrun.Delete, "GCS bucket "+bucket) {
+		return nil
+	}
+	if force {
+		if err := g.emptyBucket(ctx, bucket); err != nil {
+			return err
+		}
+	}
+	if err := g.client.Bucket(bucket).Delete(ctx); err != nil {
+		return fmt.Errorf("Error deleting GCS bucket [%s]: %w", bucket, err)
+	}
+	return nil
+}
+
+func (g *GCSStore) emptyBucket(ctx context.Context, bucket string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	sem := make(chan struct{}, deleteConcurrency)
+
+	it := g.client.Bucket(bucket).Objects(ctx, &storage.Query{Versions: true})
+	for ctx.Err() == nil {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			once.Do(func() { firstErr = fmt.Errorf("Error listing objects in GCS bucket [%s]: %w", bucket, err) })
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			obj := g.client.Bucket(bucket).Object(attrs.Name).Generation(attrs.Generation)
+			if err := obj.Delete(ctx); err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("Error deleting gs://%s/%s#%d: %w", bucket, attrs.Name, attrs.Generation, err)
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,307 @@
+package gcp
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"cloud.google.com/go/storage"
+	"github.com/RA-Balaji/storage-synk/bwlimit"
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/store"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const chunkAlign = 256 * 1024
+
+// GCSStore implements store.ObjectStore on top of a single GCS client.
+type GCSStore struct {
+	client *storage.Client
+	// http and uploadURL are used for resumable uploads, which the
+	// storage client does not let us continue across processes.
+	http      *http.Client
+	uploadURL string
+	// project is needed to list and create buckets.
+	project string
+}
+
+// Options configures the GCS client. Empty fields keep the application
+// default credentials and project.
+type Options struct {
+	Project         string
+	CredentialsFile string
+}
+
+var (
+	_ store.ObjectStore    = (*GCSStore)(nil)
+	_ store.VersionedStore = (*GCSStore)(nil)
+	_ store.BucketLister   = (*GCSStore)(nil)
+)
+
+func NewGCSStore(ctx context.Context, opts Options) (*GCSStore, error) {
+	var clientOpts []option.ClientOption
+	if opts.CredentialsFile != "" {
+		clientOpts = append(clientOpts, option.WithCredentialsFile(opts.CredentialsFile))
+	}
+	client, err := storage.NewClient(ctx, clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	httpClient, _, err := htransport.NewClient(ctx,
+		append(clientOpts, option.WithScopes(storage.ScopeReadWrite))...)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+	return &GCSStore{client: client, http: httpClient, uploadURL: uploadEndpoint, project: opts.Project}, nil
+}
+
+func (g *GCSStore) Close() error {
+	return g.client.Close()
+}
+
+func (g *GCSStore) List(
+	ctx context.Context,
+	bucket, prefix string,
+	opts store.ListOptions, fn store.WalkFunc) error {
+
+	query := &storage.Query{Prefix: prefix}
+	if !opts.Recursive {
+		query.Delimiter = "/"
+	}
+
+	it := g.client.Bucket(bucket).Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Error iterating Objects: %w", err)
+		}
+
+		if attrs.Prefix != "" {
+			err = fn(store.ObjectInfo{Key: attrs.Prefix, IsPrefix: true})
+		} else {
+			err = fn(gcsObjectInfo(attrs))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListBuckets lists the buckets of the configured project, one page at a
+// time.
+func (g *GCSStore) ListBuckets(ctx context.Context, fn func(store.BucketInfo) error) error {
+	if g.project == "" {
+		return fmt.Errorf("Listing GCS buckets needs a project: set GOOGLE_CLOUD_PROJECT or the project of a remote")
+	}
+	it := g.client.Buckets(ctx, g.project)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Error listing GCS buckets: %w", err)
+		}
+		err = fn(store.BucketInfo{
+			Name:         attrs.Name,
+			Created:      attrs.Created,
+			Location:     attrs.Location,
+			StorageClass: attrs.StorageClass,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (g *GCSStore) Stat(ctx context.Context, bucket, key string) (store.ObjectInfo, error) {
+	return g.StatVersion(ctx, bucket, key, "")
+}
+
+// StatVersion looks up an object generation; version is the decimal
+// generation number.
+func (g *GCSStore) StatVersion(ctx context.Context, bucket, key, version string) (store.ObjectInfo, error) {
+	obj, err := g.object(ctx, bucket, key, version)
+	if err != nil {
+		return store.ObjectInfo{}, err
+	}
+
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return store.ObjectInfo{}, gcsError(bucket, key, err)
+	}
+	info := gcsObjectInfo(attrs)
+	info.VersionID = version
+	return info, nil
+}
+
+func (g *GCSStore) Open(
+	ctx context.Context,
+	bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	return g.OpenVersion(ctx, bucket, key, "", offset, length)
+}
+
+func (g *GCSStore) OpenVersion(
+	ctx context.Context,
+	bucket, key, version string, offset, length int64) (io.ReadCloser, error) {
+
+	obj, err := g.object(ctx, bucket, key, version)
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects stored with Content-Encoding gzip are read as stored, not
+	// decompressed, so that they match their size and checksums.
+	reader, err := obj.ReadCompressed(true).NewRangeReader(ctx, offset, length)
+	if err != nil {
+		return nil, gcsError(bucket, key, err)
+	}
+	return reader, nil
+}
+
+// object returns the handle of an object to read, with the customer key
+// of ctx, if any.
+func (g *GCSStore) object(ctx context.Context, bucket, key, version string) (*storage.ObjectHandle, error) {
+	obj := withKey(g.client.Bucket(bucket).Object(key), store.CustomerKey(ctx))
+	if version == "" {
+		return obj, nil
+	}
+
+	generation, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid generation [%s] for gs://%s/%s: %w", version, bucket, key, err)
+	}
+	return obj.Generation(generation), nil
+}
+
+func (g *GCSStore) Write(
+	ctx context.Context,
+	bucket, key string, r io.Reader, opts store.WriteOptions) error {
+
+	if opts.Checkpoint != nil {
+		return g.writeResumable(ctx, bucket, key, r, opts)
+	}
+
+	// Closing the writer commits what it got, so a failed copy cancels
+	// the upload instead.
+	uploadCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	obj := withKey(g.client.Bucket(bucket).Object(key), writeKey(opts.Encryption))
+	wc := obj.NewWriter(uploadCtx)
+	wc.ContentType = opts.ContentType
+	wc.CacheControl = opts.CacheControl
+	wc.ContentEncoding = opts.ContentEncoding
+	wc.ContentDisposition = opts.ContentDisposition
+	wc.Metadata = opts.Metadata
+	wc.KMSKeyName = kmsKeyName(opts.Encryption)
+	if opts.PartSize > 0 {
+		// Resumable upload chunks must be a multiple of 256 KiB.
+		wc.ChunkSize = int(alignChunk(opts.PartSize))
+	}
+	// GCS rejects the object if it does not match the CRC32C of the
+	// source. Without one, the CRC32C GCS computes is checked afterwards.
+	if opts.CRC32C != "" {
+		crc, err := checksum.ParseCRC32C(opts.CRC32C)
+		if err != nil {
+			return err
+		}
+		wc.CRC32C, wc.SendCRC32C = crc, true
+	}
+	sent := checksum.NewReader(r, checksum.Sums{})
+	if _, err := io.Copy(wc, progress.Reader(ctx, bwlimit.Reader(ctx, sent))); err != nil {
+		cancel()
+		return fmt.Errorf("failed to write gs://%s/%s: %w", bucket, key, err)
+	}
+
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("failed to close writer: %w", err)
+	}
+	stored := checksum.FormatCRC32C(wc.Attrs().CRC32C)
+	if err := checksum.Verify(checksum.Sums{CRC32C: stored}, sent.Sums()); err != nil {
+		return fmt.Errorf("Error verifying gs://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+func (g *GCSStore) Delete(ctx context.Context, bucket, key string) error {
+	return g.DeleteVersion(ctx, bucket, key, "")
+}
+
+// DeleteVersion deletes one generation of an object, or the live object
+// when version is empty.
+func (g *GCSStore) DeleteVersion(ctx context.Context, bucket, key, version string) error {
+	obj, err := g.object(ctx, bucket, key, version)
+	if err != nil {
+		return err
+	}
+	if err := obj.Delete(ctx); err != nil {
+		return gcsError(bucket, key, err)
+	}
+	return nil
+}
+
+// Copy reads the source with the customer key of ctx, if any, and encrypts
+// the copy as opts ask.
+func (g *GCSStore) Copy(
+	ctx context.Context,
+	srcBucket, srcKey, dstBucket, dstKey string, opts store.CopyOptions) error {
+
+	src := withKey(g.client.Bucket(srcBucket).Object(srcKey), store.CustomerKey(ctx))
+	dst := withKey(g.client.Bucket(dstBucket).Object(dstKey), writeKey(opts.Encryption))
+	copier := dst.CopierFrom(src)
+	copier.DestinationKMSKeyName = kmsKeyName(opts.Encryption)
+	// The copy keeps the metadata of the source unless the request sets
+	// the destination resource.
+	if opts.ReplaceMetadata {
+		copier.ContentType = opts.Headers.ContentType
+		copier.CacheControl = opts.Headers.CacheControl
+		copier.ContentEncoding = opts.Headers.ContentEncoding
+		copier.ContentDisposition = opts.Headers.ContentDisposition
+		copier.Metadata = opts.Metadata
+	}
+	if _, err := copier.Run(ctx); err != nil {
+		return fmt.Errorf(
+			"Error copying gs://%s/%s to gs://%s/%s: %w", srcBucket, srcKey, dstBucket, dstKey, err)
+	}
+	return nil
+}
+
+func gcsObjectInfo(attrs *storage.ObjectAttrs) store.ObjectInfo {
+	return store.ObjectInfo{
+		Key:          attrs.Name,
+		Size:         attrs.Size,
+		ModTime:      attrs.Updated,
+		ETag:         attrs.Etag,
+		MD5:          hex.EncodeToString(attrs.MD5),
+		CRC32C:       checksum.FormatCRC32C(attrs.CRC32C),
+		StorageClass: attrs.StorageClass,
+		Headers: store.Headers{
+			ContentType:        attrs.ContentType,
+			CacheControl:       attrs.CacheControl,
+			ContentEncoding:    attrs.ContentEncoding,
+			ContentDisposition: attrs.ContentDisposition,
+		},
+		Metadata:   attrs.Metadata,
+		Encryption: describeEncryption(attrs),
+	}
+}
+
+func gcsError(bucket, key string, err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("gs://%s/%s: %w", bucket, key, store.ErrNotExist)
+	}
+	return fmt.Errorf("Error accessing gs://%s/%s: %w", bucket, key, err)
+}
@@ -0,0 +1,56 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+// fakeUploads accepts JSON API uploads and counts the objects committed.
+type fakeUploads struct {
+	mu      sync.Mutex
+	objects int
+}
+
+func (f *fakeUploads) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, err := io.ReadAll(r.Body); err != nil {
+		return
+	}
+	f.mu.Lock()
+	f.objects++
+	f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, `{"bucket": "bucket", "name": "key", "crc32c": "AAAAAA=="}`)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("source failed") }
+
+func TestWriteDiscardsFailedUpload(t *testing.T) {
+	fake := &fakeUploads{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	client, err := storage.NewClient(context.Background(),
+		option.WithEndpoint(srv.URL+"/storage/v1/"), option.WithoutAuthentication())
+	assert.NoError(t, err)
+
+	g := &GCSStore{client: client}
+	r := io.MultiReader(bytes.NewReader(testContent(1000)), failingReader{})
+	err = g.Write(context.Background(), "bucket", "key", r, store.WriteOptions{})
+	assert.ErrorContains(t, err, "source failed")
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	assert.Zero(t, fake.objects)
+}
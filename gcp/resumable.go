@@ -0,0 +1,298 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/RA-Balaji/storage-synk/bwlimit"
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+const (
+	uploadEndpoint   = "https://storage.googleapis.com/upload/storage/v1"
+	defaultChunkSize = 16 * 1024 * 1024
+	// statusResumeIncomplete is returned while a session expects more data.
+	statusResumeIncomplete = 308
+)
+
+var errSessionExpired = errors.New("resumable session expired")
+
+// writeResumable uploads r through a GCS resumable upload session that is
+// saved in opts.Checkpoint. If an earlier attempt left a session behind,
+// GCS is asked how much it already has and the upload continues from there.
+// r is always read to the end, even when the session is already complete.
+func (g *GCSStore) writeResumable(
+	ctx context.Context,
+	bucket, key string, r io.Reader, opts store.WriteOptions) error {
+
+	chunkSize := int64(defaultChunkSize)
+	if opts.PartSize > 0 {
+		chunkSize = alignChunk(opts.PartSize)
+	}
+
+	// Requests of a session encrypted with a customer key carry the key.
+	header := csekHeaders(writeKey(opts.Encryption))
+
+	var (
+		session string
+		offset  int64
+	)
+	// The whole content is hashed, the part an earlier attempt uploaded
+	// included, and checked against the CRC32C of the finished object.
+	sent := checksum.NewReader(r, checksum.Sums{})
+	finish := func(object *objectResource) error {
+		if _, err := io.Copy(io.Discard, sent); err != nil {
+			return fmt.Errorf("Error reading content for gs://%s/%s: %w", bucket, key, err)
+		}
+		stored := checksum.FromBase64(object.CRC32C)
+		if err := checksum.Verify(checksum.Sums{CRC32C: stored}, sent.Sums()); err != nil {
+			// A later attempt must upload the content again instead of
+			// finding the same finished session.
+			opts.Checkpoint.Started(store.UploadState{})
+			return fmt.Errorf("Error verifying gs://%s/%s: %w", bucket, key, err)
+		}
+		return nil
+	}
+
+	if state, ok := opts.Checkpoint.Resume(); ok && state.UploadID != "" {
+		committed, object, err := g.sessionStatus(ctx, state.UploadID, opts.Size, header)
+		switch {
+		case err == nil && object != nil:
+			return finish(object)
+		case err == nil:
+			session, offset = state.UploadID, committed
+		case !errors.Is(err, errSessionExpired):
+			return err
+		}
+	}
+	if session == "" {
+		var err error
+		session, err = g.startSession(ctx, bucket, key, opts, header)
+		if err != nil {
+			return err
+		}
+		if err := opts.Checkpoint.Started(store.UploadState{UploadID: session, PartSize: chunkSize}); err != nil {
+			return err
+		}
+	}
+
+	if offset > 0 {
+		if _, err := io.CopyN(io.Discard, sent, offset); err != nil {
+			return fmt.Errorf("Error skipping uploaded content of gs://%s/%s: %w", bucket, key, err)
+		}
+	}
+
+	// buf holds the bytes from offset on that GCS has not committed yet.
+	// Non-final chunks must be a multiple of 256 KiB, so the buffer is
+	// always filled up before it is sent.
+	buf := make([]byte, chunkSize)
+	n := 0
+	eof := false
+	for {
+		if !eof {
+			m, err := io.ReadFull(sent, buf[n:])
+			n += m
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				eof = true
+			} else if err != nil {
+				return fmt.Errorf("Error reading content for gs://%s/%s: %w", bucket, key, err)
+			}
+		}
+
+		total := opts.Size
+		if eof {
+			total = offset + int64(n)
+		}
+		if err := bwlimit.Wait(ctx, n); err != nil {
+			return err
+		}
+		committed, object, err := g.putChunk(ctx, session, buf[:n], offset, total, header)
+		if err != nil {
+			return fmt.Errorf("Error uploading gs://%s/%s: %w", bucket, key, err)
+		}
+		if object != nil {
+			progress.Add(ctx, int64(n))
+			return finish(object)
+		}
+		if committed < offset || committed > offset+int64(n) || (eof && committed == offset+int64(n)) {
+			return fmt.Errorf("Error uploading gs://%s/%s: unexpected committed size %d", bucket, key, committed)
+		}
+
+		acked := int(committed - offset)
+		progress.Add(ctx, int64(acked))
+		copy(buf, buf[acked:n])
+		n -= acked
+		offset = committed
+	}
+}
+
+// startSession creates a resumable upload session and returns its URL.
+func (g *GCSStore) startSession(
+	ctx context.Context,
+	bucket, key string, opts store.WriteOptions, header http.Header) (string, error) {
+
+	u := fmt.Sprintf("%s/b/%s/o?uploadType=resumable&name=%s",
+		g.uploadURL, url.PathEscape(bucket), url.QueryEscape(key))
+	if kmsKey := kmsKeyName(opts.Encryption); kmsKey != "" {
+		u += "&kmsKeyName=" + url.QueryEscape(kmsKey)
+	}
+	// The session request carries the object resource. GCS rejects the
+	// upload if its content does not match the CRC32C.
+	resource, err := json.Marshal(struct {
+		ContentType        string            `json:"contentType,omitempty"`
+		CacheControl       string            `json:"cacheControl,omitempty"`
+		ContentEncoding    string            `json:"contentEncoding,omitempty"`
+		ContentDisposition string            `json:"contentDisposition,omitempty"`
+		Metadata           map[string]string `json:"metadata,omitempty"`
+		CRC32C             string            `json:"crc32c,omitempty"`
+	}{
+		opts.ContentType, opts.CacheControl, opts.ContentEncoding, opts.ContentDisposition,
+		opts.Metadata, checksum.ToBase64(opts.CRC32C),
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(resource))
+	if err != nil {
+		return "", err
+	}
+	setHeaders(req, header)
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	if opts.Size >= 0 {
+		req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(opts.Size, 10))
+	}
+
+	resp, err := g.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Error starting upload to gs://%s/%s: %w", bucket, key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Error starting upload to gs://%s/%s: %w", bucket, key, responseError(resp))
+	}
+
+	session := resp.Header.Get("Location")
+	if session == "" {
+		return "", fmt.Errorf("Error starting upload to gs://%s/%s: no session URL returned", bucket, key)
+	}
+	return session, nil
+}
+
+// objectResource is the part of the object resource GCS returns once an
+// upload is complete that is checked.
+type objectResource struct {
+	CRC32C string `json:"crc32c"`
+}
+
+// sessionStatus asks GCS how many bytes of the session it has committed.
+func (g *GCSStore) sessionStatus(
+	ctx context.Context,
+	session string, size int64, header http.Header) (int64, *objectResource, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	setHeaders(req, header)
+	req.Header.Set("Content-Range", "bytes */"+totalString(size))
+	return g.doChunk(req)
+}
+
+// putChunk sends data as the bytes starting at offset. total is the size
+// of the whole object, or -1 while it is not known yet.
+func (g *GCSStore) putChunk(
+	ctx context.Context,
+	session string, data []byte, offset, total int64, header http.Header) (int64, *objectResource, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, session, bytes.NewReader(data))
+	if err != nil {
+		return 0, nil, err
+	}
+	setHeaders(req, header)
+	req.ContentLength = int64(len(data))
+	if len(data) == 0 {
+		req.Header.Set("Content-Range", "bytes */"+totalString(total))
+	} else {
+		req.Header.Set("Content-Range",
+			fmt.Sprintf("bytes %d-%d/%s", offset, offset+int64(len(data))-1, totalString(total)))
+	}
+	return g.doChunk(req)
+}
+
+// doChunk sends a session request and returns the committed size, or the
+// object once the upload is complete.
+func (g *GCSStore) doChunk(req *http.Request) (int64, *objectResource, error) {
+	resp, err := g.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		var object objectResource
+		if err := json.NewDecoder(resp.Body).Decode(&object); err != nil {
+			return 0, nil, fmt.Errorf("Error reading the uploaded object: %w", err)
+		}
+		return 0, &object, nil
+	case statusResumeIncomplete:
+		return committedSize(resp.Header.Get("Range")), nil, nil
+	case http.StatusNotFound, http.StatusGone:
+		return 0, nil, errSessionExpired
+	}
+	return 0, nil, responseError(resp)
+}
+
+// committedSize parses a Range header such as "bytes=0-1048575".
+func committedSize(rangeHeader string) int64 {
+	var start, end int64
+	if _, err := fmt.Sscanf(rangeHeader, "bytes=%d-%d", &start, &end); err != nil {
+		return 0
+	}
+	return end + 1
+}
+
+func setHeaders(req *http.Request, header http.Header) {
+	for name, values := range header {
+		req.Header[name] = values
+	}
+}
+
+func totalString(size int64) string {
+	if size < 0 {
+		return "*"
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+func alignChunk(size int64) int64 {
+	return (size + chunkAlign - 1) / chunkAlign * chunkAlign
+}
+
+// statusError is a failed session request. Its HTTPStatusCode method is
+// the one the SDK errors have, so retries treat them alike.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string       { return e.msg }
+func (e *statusError) HTTPStatusCode() int { return e.code }
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &statusError{
+		code: resp.StatusCode,
+		msg:  fmt.Sprintf("%s: %s", resp.Status, strings.TrimSpace(string(body))),
+	}
+}
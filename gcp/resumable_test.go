@@ -0,0 +1,235 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSessions implements the resumable upload protocol for one object.
+type fakeSessions struct {
+	mu        sync.Mutex
+	data      []byte
+	complete  bool
+	sessions  int
+	chunks    int
+	failChunk int
+	// maxCommit limits how many bytes of a chunk are committed, to
+	// exercise partial commits.
+	maxCommit int
+	// corrupt makes the finished object report a wrong CRC32C.
+	corrupt  bool
+	requests []*http.Request
+}
+
+func newFakeGCSStore(t *testing.T) (*GCSStore, *fakeSessions) {
+	fake := &fakeSessions{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return &GCSStore{http: srv.Client(), uploadURL: srv.URL}, fake
+}
+
+func (f *fakeSessions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	f.requests = append(f.requests, r)
+
+	if r.Method == http.MethodPost {
+		f.sessions++
+		f.data = nil
+		w.Header().Set("Location", fmt.Sprintf("http://%s/session/%d", r.Host, f.sessions))
+		return
+	}
+	if r.URL.Path != fmt.Sprintf("/session/%d", f.sessions) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var start, end int64
+	var total string
+	contentRange := r.Header.Get("Content-Range")
+	if strings.HasPrefix(contentRange, "bytes */") {
+		total = strings.TrimPrefix(contentRange, "bytes */")
+	} else {
+		f.chunks++
+		if f.chunks == f.failChunk {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Sscanf(contentRange, "bytes %d-%d/%s", &start, &end, &total)
+		if start != int64(len(f.data)) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if f.maxCommit > 0 && len(body) > f.maxCommit && total == "*" {
+			body = body[:f.maxCommit]
+		}
+		f.data = append(f.data, body...)
+	}
+
+	if total != "*" && total == fmt.Sprint(len(f.data)) {
+		f.complete = true
+		crc := checksum.CRC32C(f.data)
+		if f.corrupt {
+			crc = "00000000"
+		}
+		fmt.Fprintf(w, `{"crc32c": %q}`, checksum.ToBase64(crc))
+		return
+	}
+	if len(f.data) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(f.data)-1))
+	}
+	w.WriteHeader(statusResumeIncomplete)
+}
+
+type memCheckpoint struct {
+	state *store.UploadState
+}
+
+func (c *memCheckpoint) Resume() (store.UploadState, bool) {
+	if c.state == nil {
+		return store.UploadState{}, false
+	}
+	return *c.state, true
+}
+
+func (c *memCheckpoint) Started(state store.UploadState) error {
+	c.state = &state
+	return nil
+}
+
+func (c *memCheckpoint) PartDone(part store.Part) error { return nil }
+
+// onlyReader hides io.ReaderAt, like a network stream.
+type onlyReader struct{ io.Reader }
+
+func testContent(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+func TestWriteResumableStreamWithPartialCommits(t *testing.T) {
+	g, fake := newFakeGCSStore(t)
+	fake.maxCommit = 100 * 1024
+	content := testContent(3*chunkAlign + 17)
+
+	err := g.Write(context.Background(), "bucket", "obj", onlyReader{bytes.NewReader(content)},
+		store.WriteOptions{Size: -1, PartSize: chunkAlign, Checkpoint: &memCheckpoint{}})
+	assert.NoError(t, err)
+	assert.True(t, fake.complete)
+	assert.Equal(t, content, fake.data)
+}
+
+func TestWriteResumableContinuesSession(t *testing.T) {
+	for name, reader := range map[string]func([]byte) io.Reader{
+		"ReaderAt": func(b []byte) io.Reader { return bytes.NewReader(b) },
+		"Stream":   func(b []byte) io.Reader { return onlyReader{bytes.NewReader(b)} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			g, fake := newFakeGCSStore(t)
+			fake.failChunk = 3
+			content := testContent(4*chunkAlign + 5)
+			checkpoint := &memCheckpoint{}
+			opts := store.WriteOptions{Size: int64(len(content)), PartSize: chunkAlign, Checkpoint: checkpoint}
+
+			err := g.Write(context.Background(), "bucket", "obj", reader(content), opts)
+			assert.Error(t, err)
+			assert.Len(t, fake.data, 2*chunkAlign)
+
+			err = g.Write(context.Background(), "bucket", "obj", reader(content), opts)
+			assert.NoError(t, err)
+			assert.Equal(t, 1, fake.sessions)
+			assert.Equal(t, 6, fake.chunks)
+			assert.Equal(t, content, fake.data)
+		})
+	}
+}
+
+func TestWriteResumableFinishedSession(t *testing.T) {
+	g, fake := newFakeGCSStore(t)
+	content := testContent(chunkAlign + 1)
+	checkpoint := &memCheckpoint{}
+	opts := store.WriteOptions{Size: int64(len(content)), Checkpoint: checkpoint}
+	assert.NoError(t, g.Write(context.Background(), "bucket", "obj", bytes.NewReader(content), opts))
+
+	// A session finished before the job recorded it is not uploaded again,
+	// but the source is still read to the end and checked.
+	source := checksum.NewReader(onlyReader{bytes.NewReader(content)}, checksum.Sums{})
+	assert.NoError(t, g.Write(context.Background(), "bucket", "obj", source, opts))
+	assert.Equal(t, int64(len(content)), source.Size())
+	assert.Equal(t, 1, fake.sessions)
+	assert.Equal(t, 1, fake.chunks)
+
+	err := g.Write(context.Background(), "bucket", "obj", bytes.NewReader(testContent(chunkAlign)), opts)
+	assert.ErrorIs(t, err, checksum.ErrMismatch)
+	_, ok := checkpoint.Resume()
+	assert.True(t, ok)
+	assert.Empty(t, checkpoint.state.UploadID)
+
+	// The next attempt starts a new session.
+	assert.NoError(t, g.Write(context.Background(), "bucket", "obj", bytes.NewReader(content), opts))
+	assert.Equal(t, 2, fake.sessions)
+}
+
+func TestWriteResumableVerifiesContent(t *testing.T) {
+	g, fake := newFakeGCSStore(t)
+	fake.corrupt = true
+	err := g.Write(context.Background(), "bucket", "obj", bytes.NewReader(testContent(10)),
+		store.WriteOptions{Size: 10, Checkpoint: &memCheckpoint{}})
+	assert.ErrorIs(t, err, checksum.ErrMismatch)
+}
+
+func TestWriteResumableRestartsExpiredSession(t *testing.T) {
+	g, fake := newFakeGCSStore(t)
+	content := testContent(chunkAlign + 1)
+	checkpoint := &memCheckpoint{state: &store.UploadState{UploadID: g.uploadURL + "/session/99"}}
+
+	err := g.Write(context.Background(), "bucket", "obj", bytes.NewReader(content),
+		store.WriteOptions{Size: int64(len(content)), Checkpoint: checkpoint})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, fake.sessions)
+	assert.Equal(t, content, fake.data)
+}
+
+func TestWriteResumableEncryption(t *testing.T) {
+	g, fake := newFakeGCSStore(t)
+	key := bytes.Repeat([]byte{1}, 32)
+	err := g.Write(context.Background(), "bucket", "obj", bytes.NewReader(testContent(10)),
+		store.WriteOptions{
+			Size:       10,
+			Checkpoint: &memCheckpoint{},
+			Encryption: store.Encryption{Mode: store.EncryptionCustomer, CustomerKey: key},
+		})
+	assert.NoError(t, err)
+	assert.Len(t, fake.requests, 2)
+	for _, r := range fake.requests {
+		assert.Equal(t, "AES256", r.Header.Get("X-Goog-Encryption-Algorithm"))
+		assert.NotEmpty(t, r.Header.Get("X-Goog-Encryption-Key-Sha256"))
+	}
+
+	g, fake = newFakeGCSStore(t)
+	kmsKey := "projects/p/locations/l/keyRings/r/cryptoKeys/k"
+	err = g.Write(context.Background(), "bucket", "obj", bytes.NewReader(testContent(10)),
+		store.WriteOptions{
+			Size:       10,
+			Checkpoint: &memCheckpoint{},
+			Encryption: store.Encryption{Mode: store.EncryptionKMS, KMSKey: kmsKey},
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, kmsKey, fake.requests[0].URL.Query().Get("kmsKeyName"))
+	assert.Empty(t, fake.requests[1].Header.Get("X-Goog-Encryption-Key"))
+}
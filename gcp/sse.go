@@ -0,0 +1,62 @@
+package gcp
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+
+	"cloud.google.com/go/storage"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+// GCS encrypts every object with Google-managed keys; the other modes of
+// store.Encryption map onto a Cloud KMS key (CMEK) or a customer-supplied
+// key (CSEK). Objects in a bucket with a default CMEK key get that key.
+
+// kmsKeyName returns the CMEK key of e, or "".
+func kmsKeyName(e store.Encryption) string {
+	if e.Mode != store.EncryptionKMS {
+		return ""
+	}
+	return e.KMSKey
+}
+
+// writeKey returns the CSEK key of e, or nil.
+func writeKey(e store.Encryption) []byte {
+	if e.Mode != store.EncryptionCustomer {
+		return nil
+	}
+	return e.CustomerKey
+}
+
+// withKey makes obj use the customer key, if any.
+func withKey(obj *storage.ObjectHandle, key []byte) *storage.ObjectHandle {
+	if key == nil {
+		return obj
+	}
+	return obj.Key(key)
+}
+
+// csekHeaders returns the headers that supply a customer key to the JSON
+// API, or nil without a key.
+func csekHeaders(key []byte) http.Header {
+	if key == nil {
+		return nil
+	}
+	sum := sha256.Sum256(key)
+	return http.Header{
+		"X-Goog-Encryption-Algorithm":  {"AES256"},
+		"X-Goog-Encryption-Key":        {base64.StdEncoding.EncodeToString(key)},
+		"X-Goog-Encryption-Key-Sha256": {base64.StdEncoding.EncodeToString(sum[:])},
+	}
+}
+
+func describeEncryption(attrs *storage.ObjectAttrs) string {
+	switch {
+	case attrs.CustomerKeySHA256 != "":
+		return "CSEK"
+	case attrs.KMSKeyName != "":
+		return "CMEK " + attrs.KMSKeyName
+	}
+	return "Google-managed"
+}
@@ -0,0 +1,261 @@
+// Package journal keeps the on-disk state of a transfer job so that an
+// interrupted job can be resumed. Each job is a file of JSON lines that is
+// only ever appended to; replaying it rebuilds the set of completed objects
+// and the multipart uploads that were in flight.
+package journal
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+const (
+	opJob    = "job"
+	opUpload = "upload"
+	opPart   = "part"
+	opDone   = "done"
+)
+
+type entry struct {
+	Op          string             `json:"op"`
+	Key         string             `json:"key,omitempty"`
+	Source      string             `json:"source,omitempty"`
+	Destination string             `json:"destination,omitempty"`
+	Object      *sourceObject      `json:"object,omitempty"`
+	Upload      *store.UploadState `json:"upload,omitempty"`
+	Part        *store.Part        `json:"part,omitempty"`
+}
+
+// sourceObject identifies the source object of a copy, so that a resumed
+// job copies objects that changed since again.
+type sourceObject struct {
+	Size    int64     `json:"size"`
+	ETag    string    `json:"etag,omitempty"`
+	ModTime time.Time `json:"mtime"`
+}
+
+func identify(obj store.ObjectInfo) *sourceObject {
+	return &sourceObject{Size: obj.Size, ETag: obj.ETag, ModTime: obj.ModTime}
+}
+
+// matches reports whether obj is the object s was recorded for. Entries
+// written before sources were recorded match nothing.
+func (s *sourceObject) matches(obj store.ObjectInfo) bool {
+	return s != nil && s.Size == obj.Size && s.ETag == obj.ETag && s.ModTime.Equal(obj.ModTime)
+}
+
+// upload is a multipart upload in flight and the object it copies.
+type upload struct {
+	state  store.UploadState
+	source *sourceObject
+}
+
+// Journal records the progress of one job. It is safe for concurrent use.
+type Journal struct {
+	ID          string
+	Source      string
+	Destination string
+
+	path    string
+	mu      sync.Mutex
+	file    *os.File
+	done    map[string]*sourceObject
+	uploads map[string]*upload
+}
+
+// DefaultDir is where job journals are kept unless configured otherwise.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("Error locating home directory: %v", err)
+	}
+	return filepath.Join(home, ".config", "storage-synk", "jobs"), nil
+}
+
+// Create starts the journal of a new job copying source to destination.
+func Create(dir, source, destination string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("Error creating journal directory [%s]: %v", dir, err)
+	}
+
+	j := &Journal{
+		ID:          newID(),
+		Source:      source,
+		Destination: destination,
+		done:        map[string]*sourceObject{},
+		uploads:     map[string]*upload{},
+	}
+	j.path = filepath.Join(dir, j.ID+".jsonl")
+
+	file, err := os.OpenFile(j.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating journal [%s]: %v", j.path, err)
+	}
+	j.file = file
+
+	if err := j.append(entry{Op: opJob, Source: source, Destination: destination}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// Open replays the journal of an existing job and reopens it for appending.
+func Open(dir, id string) (*Journal, error) {
+	j := &Journal{
+		ID:      id,
+		path:    filepath.Join(dir, id+".jsonl"),
+		done:    map[string]*sourceObject{},
+		uploads: map[string]*upload{},
+	}
+
+	file, err := os.OpenFile(j.path, os.O_RDWR|os.O_APPEND, 0600)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("No job %q found in [%s]", id, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error opening journal [%s]: %v", j.path, err)
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A crash can leave a truncated last line behind.
+			continue
+		}
+		j.apply(e)
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Error reading journal [%s]: %v", j.path, err)
+	}
+	if j.Source == "" {
+		file.Close()
+		return nil, fmt.Errorf("Journal [%s] has no job header", j.path)
+	}
+
+	// Terminate a truncated last line so that new entries start cleanly.
+	if info, err := file.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			file.Write([]byte{'\n'})
+		}
+	}
+
+	j.file = file
+	return j, nil
+}
+
+func (j *Journal) apply(e entry) {
+	switch e.Op {
+	case opJob:
+		j.Source, j.Destination = e.Source, e.Destination
+	case opUpload:
+		if e.Upload != nil {
+			j.uploads[e.Key] = &upload{state: *e.Upload, source: e.Object}
+		}
+	case opPart:
+		if upload, ok := j.uploads[e.Key]; ok && e.Part != nil {
+			upload.state.Parts = append(upload.state.Parts, *e.Part)
+		}
+	case opDone:
+		j.done[e.Key] = e.Object
+		delete(j.uploads, e.Key)
+	}
+}
+
+// append writes e to the journal file and applies it to the in-memory
+// state. Callers must not hold j.mu.
+func (j *Journal) append(e entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("Error writing journal [%s]: %v", j.path, err)
+	}
+	j.apply(e)
+	return nil
+}
+
+// Done reports whether src was copied to key in an earlier run. It is
+// false if src changed since.
+func (j *Journal) Done(key string, src store.ObjectInfo) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.done[key].matches(src)
+}
+
+// MarkDone records that src is completely copied to key.
+func (j *Journal) MarkDone(key string, src store.ObjectInfo) error {
+	return j.append(entry{Op: opDone, Key: key, Object: identify(src)})
+}
+
+// Checkpoint returns the checkpoint of the upload of src to key.
+func (j *Journal) Checkpoint(key string, src store.ObjectInfo) store.Checkpoint {
+	return &checkpoint{journal: j, key: key, source: src}
+}
+
+// Close closes the journal file, keeping it for a later resume.
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// Remove closes and deletes the journal once the job has completed.
+func (j *Journal) Remove() error {
+	j.file.Close()
+	if err := os.Remove(j.path); err != nil {
+		return fmt.Errorf("Error removing journal [%s]: %v", j.path, err)
+	}
+	return nil
+}
+
+type checkpoint struct {
+	journal *Journal
+	key     string
+	source  store.ObjectInfo
+}
+
+// Resume returns the upload in flight, unless its parts were read from a
+// source that has changed since.
+func (c *checkpoint) Resume() (store.UploadState, bool) {
+	c.journal.mu.Lock()
+	defer c.journal.mu.Unlock()
+	upload, ok := c.journal.uploads[c.key]
+	if !ok || !upload.source.matches(c.source) {
+		return store.UploadState{}, false
+	}
+	state := upload.state
+	state.Parts = append([]store.Part(nil), upload.state.Parts...)
+	return state, true
+}
+
+func (c *checkpoint) Started(state store.UploadState) error {
+	state.Parts = nil
+	return c.journal.append(entry{Op: opUpload, Key: c.key, Object: identify(c.source), Upload: &state})
+}
+
+func (c *checkpoint) PartDone(part store.Part) error {
+	return c.journal.append(entry{Op: opPart, Key: c.key, Part: &part})
+}
+
+// newID returns a job ID that sorts by creation time.
+func newID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
@@ -0,0 +1,101 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	small = store.ObjectInfo{Key: "data/a.txt", Size: 3, ETag: "etag-a", ModTime: time.Unix(1700000000, 5)}
+	big   = store.ObjectInfo{Key: "data/big.bin", Size: 10 << 20, ETag: "etag-big"}
+)
+
+func TestJournalReplay(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Create(dir, "s3://src/data/", "gs://dst/backup/")
+	assert.NoError(t, err)
+
+	assert.NoError(t, j.MarkDone("backup/a.txt", small))
+	cp := j.Checkpoint("backup/big.bin", big)
+	assert.NoError(t, cp.Started(store.UploadState{UploadID: "upload-1", PartSize: 5 << 20}))
+	assert.NoError(t, cp.PartDone(store.Part{Number: 1, ETag: "etag-1", Size: 5 << 20}))
+	assert.NoError(t, j.Close())
+
+	j, err = Open(dir, j.ID)
+	assert.NoError(t, err)
+	defer j.Close()
+
+	assert.Equal(t, "s3://src/data/", j.Source)
+	assert.Equal(t, "gs://dst/backup/", j.Destination)
+	assert.True(t, j.Done("backup/a.txt", small))
+	assert.False(t, j.Done("backup/big.bin", big))
+
+	state, ok := j.Checkpoint("backup/big.bin", big).Resume()
+	assert.True(t, ok)
+	assert.Equal(t, "upload-1", state.UploadID)
+	assert.Equal(t, []store.Part{{Number: 1, ETag: "etag-1", Size: 5 << 20}}, state.Parts)
+
+	// Completing the object forgets its upload.
+	assert.NoError(t, j.MarkDone("backup/big.bin", big))
+	_, ok = j.Checkpoint("backup/big.bin", big).Resume()
+	assert.False(t, ok)
+}
+
+func TestJournalForgetsChangedSources(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Create(dir, "s3://src/data/", "gs://dst/backup/")
+	assert.NoError(t, err)
+	assert.NoError(t, j.MarkDone("backup/a.txt", small))
+	assert.NoError(t, j.Checkpoint("backup/big.bin", big).Started(store.UploadState{UploadID: "upload-1"}))
+	assert.NoError(t, j.Close())
+
+	j, err = Open(dir, j.ID)
+	assert.NoError(t, err)
+	defer j.Close()
+
+	changed := small
+	changed.ModTime = changed.ModTime.Add(time.Second)
+	assert.False(t, j.Done("backup/a.txt", changed))
+	changed = small
+	changed.ETag = "etag-b"
+	assert.False(t, j.Done("backup/a.txt", changed))
+
+	grown := big
+	grown.Size++
+	_, ok := j.Checkpoint("backup/big.bin", grown).Resume()
+	assert.False(t, ok)
+	_, ok = j.Checkpoint("backup/big.bin", big).Resume()
+	assert.True(t, ok)
+}
+
+func TestJournalIgnoresTruncatedLine(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Create(dir, "a", "b")
+	assert.NoError(t, err)
+	assert.NoError(t, j.MarkDone("x", small))
+	j.Close()
+
+	f, err := os.OpenFile(filepath.Join(dir, j.ID+".jsonl"), os.O_APPEND|os.O_WRONLY, 0600)
+	assert.NoError(t, err)
+	f.WriteString(`{"op":"done","ke`)
+	f.Close()
+
+	j, err = Open(dir, j.ID)
+	assert.NoError(t, err)
+	assert.True(t, j.Done("x", small))
+	assert.NoError(t, j.MarkDone("y", small))
+	j.Close()
+
+	j, err = Open(dir, j.ID)
+	assert.NoError(t, err)
+	assert.True(t, j.Done("y", small))
+	assert.NoError(t, j.Remove())
+
+	_, err = Open(dir, j.ID)
+	assert.ErrorContains(t, err, "No job")
+}
@@ -0,0 +1,268 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/RA-Balaji/storage-synk/bwlimit"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+// FileStore implements store.ObjectStore on the local filesystem. Keys are
+// slash separated paths; a non-empty bucket is used as the root directory
+// the keys are relative to.
+type FileStore struct{}
+
+var _ store.ObjectStore = (*FileStore)(nil)
+
+func NewFileStore() *FileStore {
+	return &FileStore{}
+}
+
+func (f *FileStore) List(
+	ctx context.Context,
+	bucket, prefix string,
+	opts store.ListOptions, fn store.WalkFunc) error {
+
+	// Everything up to the last "/" is a directory to walk, the rest is a
+	// name prefix the entries have to match.
+	dir := ""
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir = prefix[:i+1]
+	}
+	root := filePath(bucket, dir)
+	if dir == "" && bucket == "" {
+		root = "."
+	}
+
+	if _, err := os.Stat(root); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		key := dir + filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if !strings.HasPrefix(key+"/", prefix) {
+				return filepath.SkipDir
+			}
+			if !opts.Recursive {
+				if strings.HasPrefix(key, prefix) {
+					if err := fn(store.ObjectInfo{Key: key + "/", IsPrefix: true}); err != nil {
+						return err
+					}
+				}
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !strings.HasPrefix(key, prefix) || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(fileObjectInfo(key, info))
+	})
+	if err != nil {
+		return fmt.Errorf("Error walking directory [%s]: %w", root, err)
+	}
+
+	return nil
+}
+
+// Stat reports directories as missing objects, the same way an object store
+// has no object for a "directory" prefix.
+func (f *FileStore) Stat(ctx context.Context, bucket, key string) (store.ObjectInfo, error) {
+	path := filePath(bucket, key)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return store.ObjectInfo{}, fmt.Errorf("%s: %w", path, store.ErrNotExist)
+	}
+	if err != nil {
+		return store.ObjectInfo{}, err
+	}
+	return fileObjectInfo(key, info), nil
+}
+
+func (f *FileStore) Open(
+	ctx context.Context,
+	bucket, key string, offset, length int64) (io.ReadCloser, error) {
+
+	path := filePath(bucket, key)
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", path, store.ErrNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error opening file %s: %w", path, err)
+	}
+
+	if offset == 0 && length < 0 {
+		return file, nil
+	}
+
+	// Ranges are served through a SectionReader so that ReadAt and Seek
+	// stay relative to the start of the range.
+	if length < 0 {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("Error opening file %s: %w", path, err)
+		}
+		length = info.Size() - offset
+	}
+	return &sectionFile{SectionReader: io.NewSectionReader(file, offset, length), file: file}, nil
+}
+
+// Write writes to a temporary file next to the target and renames it into
+// place, so readers never see a partially written file.
+func (f *FileStore) Write(
+	ctx context.Context,
+	bucket, key string, r io.Reader, opts store.WriteOptions) error {
+
+	return writeFile(filePath(bucket, key), opts, func(tmp *os.File) error {
+		_, err := io.Copy(tmp, progress.Reader(ctx, bwlimit.Reader(ctx, r)))
+		return err
+	})
+}
+
+// WriteSliced creates the file for key with the given size and lets fill
+// write its content at arbitrary offsets, e.g. from concurrent ranged
+// downloads. Like Write, the file only appears once fill has succeeded.
+func (f *FileStore) WriteSliced(
+	ctx context.Context,
+	bucket, key string, opts store.WriteOptions, fill func(w io.WriterAt) error) error {
+
+	return writeFile(filePath(bucket, key), opts, func(tmp *os.File) error {
+		if opts.Size > 0 {
+			if err := tmp.Truncate(opts.Size); err != nil {
+				return err
+			}
+		}
+		return fill(progress.WriterAt(ctx, bwlimit.WriterAt(ctx, tmp)))
+	})
+}
+
+func writeFile(path string, opts store.WriteOptions, fill func(tmp *os.File) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("Error creating directory for [%s]: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".synk-*")
+	if err != nil {
+		return fmt.Errorf("Error creating file [%s], Err:[%v]", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := fill(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Error writing file [%s]: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Error writing file [%s]: %w", path, err)
+	}
+
+	mode := opts.Mode.Perm()
+	if mode == 0 {
+		mode = 0644
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("Error setting permissions of [%s]: %w", path, err)
+	}
+	if !opts.ModTime.IsZero() {
+		if err := os.Chtimes(tmp.Name(), opts.ModTime, opts.ModTime); err != nil {
+			return fmt.Errorf("Error setting modification time of [%s]: %w", path, err)
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("Error moving file into place [%s]: %w", path, err)
+	}
+	return nil
+}
+
+func (f *FileStore) Delete(ctx context.Context, bucket, key string) error {
+	path := filePath(bucket, key)
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", path, store.ErrNotExist)
+	}
+	if err != nil {
+		return fmt.Errorf("Error deleting file [%s]: %w", path, err)
+	}
+	return nil
+}
+
+func (f *FileStore) Copy(
+	ctx context.Context,
+	srcBucket, srcKey, dstBucket, dstKey string, opts store.CopyOptions) error {
+
+	info, err := f.Stat(ctx, srcBucket, srcKey)
+	if err != nil {
+		return err
+	}
+	r, err := f.Open(ctx, srcBucket, srcKey, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return f.Write(ctx, dstBucket, dstKey, r, store.WriteOptions{
+		Size:    info.Size,
+		ModTime: info.ModTime,
+		Mode:    info.Mode,
+	})
+}
+
+func filePath(bucket, key string) string {
+	if bucket == "" {
+		return filepath.FromSlash(key)
+	}
+	return filepath.Join(bucket, filepath.FromSlash(key))
+}
+
+func fileObjectInfo(key string, info fs.FileInfo) store.ObjectInfo {
+	return store.ObjectInfo{
+		Key:     key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Mode:    info.Mode().Perm(),
+		// Files have no content type; it is guessed from the extension
+		// so that uploads get one.
+		Headers: store.Headers{ContentType: mime.TypeByExtension(filepath.Ext(key))},
+	}
+}
+
+type sectionFile struct {
+	*io.SectionReader
+	file *os.File
+}
+
+func (s *sectionFile) Close() error {
+	return s.file.Close()
+}
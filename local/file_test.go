@@ -0,0 +1,106 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func createTestTree(t *testing.T) string {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"file1.txt":         "one",
+		"subdir/file2.txt":  "two",
+		"subdir/file3.txt":  "three",
+		"subdir2/file4.txt": "four",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return filepath.ToSlash(tmpDir)
+}
+
+func listKeys(t *testing.T, fs *FileStore, prefix string, recursive bool) []string {
+	var keys []string
+	err := fs.List(context.Background(), "", prefix, store.ListOptions{Recursive: recursive},
+		func(obj store.ObjectInfo) error {
+			keys = append(keys, obj.Key)
+			return nil
+		})
+	assert.NoError(t, err)
+	return keys
+}
+
+func TestFileStoreList(t *testing.T) {
+	dir := createTestTree(t)
+	fs := NewFileStore()
+
+	assert.Equal(t, []string{
+		dir + "/file1.txt",
+		dir + "/subdir/file2.txt",
+		dir + "/subdir/file3.txt",
+		dir + "/subdir2/file4.txt",
+	}, listKeys(t, fs, dir+"/", true))
+
+	assert.Equal(t, []string{
+		dir + "/file1.txt",
+		dir + "/subdir/",
+		dir + "/subdir2/",
+	}, listKeys(t, fs, dir+"/", false))
+
+	assert.Equal(t, []string{
+		dir + "/subdir/file2.txt",
+		dir + "/subdir/file3.txt",
+	}, listKeys(t, fs, dir+"/subdir/", true))
+
+	assert.Empty(t, listKeys(t, fs, dir+"/missing/", true))
+}
+
+func TestFileStoreStatDirectory(t *testing.T) {
+	dir := createTestTree(t)
+	_, err := NewFileStore().Stat(context.Background(), "", dir+"/subdir")
+	assert.True(t, store.IsNotExist(err))
+}
+
+func TestFileStoreOpenRange(t *testing.T) {
+	dir := createTestTree(t)
+	r, err := NewFileStore().Open(context.Background(), "", dir+"/subdir/file3.txt", 1, 3)
+	assert.NoError(t, err)
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "hre", string(data))
+
+	// ReadAt offsets are relative to the start of the range.
+	buf := make([]byte, 2)
+	n, _ := r.(io.ReaderAt).ReadAt(buf, 1)
+	assert.Equal(t, "re", string(buf[:n]))
+}
+
+func TestFileStoreWritePreservesModeAndMtime(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	err := NewFileStore().Write(context.Background(), dir, "a/b/c.txt", bytes.NewReader([]byte("data")),
+		store.WriteOptions{Size: 4, ModTime: mtime, Mode: 0600})
+	assert.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dir, "a", "b", "c.txt"))
+	assert.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(mtime))
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// No temporary files are left behind.
+	entries, _ := os.ReadDir(filepath.Join(dir, "a", "b"))
+	assert.Len(t, entries, 1)
+}
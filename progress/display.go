@@ -0,0 +1,154 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/utils"
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	ttyInterval   = 200 * time.Millisecond
+	plainInterval = 10 * time.Second
+	// maxActiveLines limits the active files shown on a terminal.
+	maxActiveLines = 5
+)
+
+// Display renders a Tracker until it is stopped: redrawn in place on a
+// terminal, or as a plain line every few seconds otherwise (logs, CI).
+type Display struct {
+	w        io.Writer
+	tracker  *Tracker
+	tty      bool
+	interval time.Duration
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+	// lines is how many lines the last terminal frame used.
+	lines int
+}
+
+// Start renders t to w in the background. Terminal output is used when w
+// is a terminal.
+func Start(w io.Writer, t *Tracker) *Display {
+	tty := false
+	if f, ok := w.(*os.File); ok {
+		tty = isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+	}
+	interval := plainInterval
+	if tty {
+		interval = ttyInterval
+	}
+	return start(w, t, tty, interval)
+}
+
+func start(w io.Writer, t *Tracker, tty bool, interval time.Duration) *Display {
+	d := &Display{
+		w:        w,
+		tracker:  t,
+		tty:      tty,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+func (d *Display) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.render(false)
+		case <-d.stop:
+			d.render(true)
+			return
+		}
+	}
+}
+
+// Stop renders the final state and waits for the display to finish.
+func (d *Display) Stop() {
+	d.stopOnce.Do(func() { close(d.stop) })
+	<-d.done
+}
+
+func (d *Display) render(final bool) {
+	s := d.tracker.Snapshot()
+	if !d.tty {
+		fmt.Fprintln(d.w, plainLine(s, final))
+		return
+	}
+
+	var b strings.Builder
+	// Move back to the start of the previous frame and clear it.
+	if d.lines > 1 {
+		fmt.Fprintf(&b, "\033[%dA", d.lines-1)
+	}
+	b.WriteString("\r\033[J")
+
+	b.WriteString(summary(s))
+	lines := 1
+	if !final {
+		for i, f := range s.Active {
+			if i == maxActiveLines {
+				fmt.Fprintf(&b, "\n  ... %d more", len(s.Active)-maxActiveLines)
+				lines++
+				break
+			}
+			fmt.Fprintf(&b, "\n  %s  %s", fileProgress(f), f.Name)
+			lines++
+		}
+	} else {
+		b.WriteString("\n")
+	}
+	d.lines = lines
+	io.WriteString(d.w, b.String())
+}
+
+func summary(s Snapshot) string {
+	line := fmt.Sprintf("%s / %s", utils.FormatSize(s.BytesDone), utils.FormatSize(s.BytesTotal))
+	if s.BytesTotal > 0 {
+		line += fmt.Sprintf(" (%d%%)", s.BytesDone*100/s.BytesTotal)
+	}
+	line += fmt.Sprintf(", %d/%d objects", s.ObjectsDone, s.ObjectsTotal)
+	if s.ObjectsFailed > 0 {
+		line += fmt.Sprintf(" (%d failed)", s.ObjectsFailed)
+	}
+	line += fmt.Sprintf(", %s/s", utils.FormatSize(int64(s.Throughput)))
+	if s.ETA > 0 {
+		line += ", ETA " + s.ETA.Round(time.Second).String()
+	}
+	return line
+}
+
+func plainLine(s Snapshot, final bool) string {
+	if final {
+		return fmt.Sprintf("Transferred %s in %s", summary(s), s.Elapsed.Round(time.Second))
+	}
+	line := "Progress: " + summary(s)
+	if len(s.Active) > 0 {
+		names := make([]string, 0, len(s.Active))
+		for _, f := range s.Active {
+			names = append(names, f.Name)
+		}
+		line += ", active: " + strings.Join(names, ", ")
+	}
+	return line
+}
+
+func fileProgress(f FileStatus) string {
+	if f.Size <= 0 {
+		return fmt.Sprintf("%10s", utils.FormatSize(f.Done))
+	}
+	return fmt.Sprintf("%10s / %-10s", utils.FormatSize(f.Done), utils.FormatSize(f.Size))
+}
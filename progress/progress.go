@@ -0,0 +1,266 @@
+// Package progress tracks the bytes and objects of a running transfer.
+// A Tracker travels in the context; the transfer code registers each
+// object as a File, and the backends count the bytes they send through the
+// readers and writers returned by Reader, ReadSeeker and WriterAt.
+package progress
+
+import (
+	"context"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// throughputWindow is how far back the current throughput looks.
+const throughputWindow = 10 * time.Second
+
+// Tracker aggregates the progress of all objects of a transfer.
+type Tracker struct {
+	start time.Time
+
+	bytesTotal    atomic.Int64
+	bytesDone     atomic.Int64
+	objectsTotal  atomic.Int64
+	objectsDone   atomic.Int64
+	objectsFailed atomic.Int64
+
+	mu      sync.Mutex
+	active  map[*File]struct{}
+	samples []sample
+}
+
+type sample struct {
+	at    time.Time
+	bytes int64
+}
+
+// File is one object being transferred.
+type File struct {
+	Name string
+	Size int64
+
+	tracker *Tracker
+	started time.Time
+	done    atomic.Int64
+}
+
+// FileStatus is the progress of an active file.
+type FileStatus struct {
+	Name string
+	Size int64
+	Done int64
+}
+
+// Snapshot is the state of a Tracker at one point in time.
+type Snapshot struct {
+	BytesDone     int64
+	BytesTotal    int64
+	ObjectsDone   int64
+	ObjectsFailed int64
+	ObjectsTotal  int64
+	Elapsed       time.Duration
+	// Throughput is in bytes per second over the last few seconds.
+	Throughput float64
+	// ETA is zero when it cannot be estimated yet.
+	ETA    time.Duration
+	Active []FileStatus
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{start: time.Now(), active: map[*File]struct{}{}}
+}
+
+// AddTotal grows the expected totals as objects are discovered.
+func (t *Tracker) AddTotal(objects int, bytes int64) {
+	t.objectsTotal.Add(int64(objects))
+	t.bytesTotal.Add(bytes)
+}
+
+// StartFile marks an object as active.
+func (t *Tracker) StartFile(name string, size int64) *File {
+	f := &File{Name: name, Size: size, tracker: t, started: time.Now()}
+	t.mu.Lock()
+	t.active[f] = struct{}{}
+	t.mu.Unlock()
+	return f
+}
+
+// Add counts n more bytes of the file as transferred.
+func (f *File) Add(n int64) {
+	f.done.Add(n)
+	f.tracker.bytesDone.Add(n)
+}
+
+// Reset takes the bytes counted so far back out, before the file is
+// transferred again from the start.
+func (f *File) Reset() {
+	f.tracker.bytesDone.Add(-f.done.Swap(0))
+}
+
+// Finish marks the file as no longer active. On success any bytes the
+// backend did not count, e.g. for server-side copies, are added; on
+// failure the bytes counted so far are taken back out of the total done.
+func (f *File) Finish(err error) {
+	t := f.tracker
+	t.mu.Lock()
+	delete(t.active, f)
+	t.mu.Unlock()
+
+	if err != nil {
+		t.bytesDone.Add(-f.done.Load())
+		t.objectsFailed.Add(1)
+		return
+	}
+	if rest := f.Size - f.done.Load(); rest > 0 {
+		f.Add(rest)
+	}
+	t.objectsDone.Add(1)
+}
+
+// Snapshot returns the current totals, throughput and active files.
+func (t *Tracker) Snapshot() Snapshot {
+	now := time.Now()
+	s := Snapshot{
+		BytesDone:     t.bytesDone.Load(),
+		BytesTotal:    t.bytesTotal.Load(),
+		ObjectsDone:   t.objectsDone.Load(),
+		ObjectsFailed: t.objectsFailed.Load(),
+		ObjectsTotal:  t.objectsTotal.Load(),
+		Elapsed:       now.Sub(t.start),
+	}
+
+	t.mu.Lock()
+	t.samples = append(t.samples, sample{at: now, bytes: s.BytesDone})
+	// Keep one sample at least throughputWindow old to measure against.
+	for len(t.samples) > 1 && now.Sub(t.samples[1].at) >= throughputWindow {
+		t.samples = t.samples[1:]
+	}
+	oldest := sample{at: t.start}
+	if len(t.samples) > 1 {
+		oldest = t.samples[0]
+	}
+	for f := range t.active {
+		s.Active = append(s.Active, FileStatus{Name: f.Name, Size: f.Size, Done: f.done.Load()})
+	}
+	t.mu.Unlock()
+
+	sort.Slice(s.Active, func(i, j int) bool { return s.Active[i].Name < s.Active[j].Name })
+
+	if elapsed := now.Sub(oldest.at).Seconds(); elapsed > 0 {
+		s.Throughput = float64(s.BytesDone-oldest.bytes) / elapsed
+	}
+	if s.Throughput > 0 && s.BytesTotal > s.BytesDone {
+		s.ETA = time.Duration(float64(s.BytesTotal-s.BytesDone) / s.Throughput * float64(time.Second))
+	}
+	return s
+}
+
+type trackerKey struct{}
+type fileKey struct{}
+
+func WithTracker(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, t)
+}
+
+// FromContext returns the tracker of ctx, or nil.
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return t
+}
+
+// WithFile returns a context whose byte counts go to f.
+func WithFile(ctx context.Context, f *File) context.Context {
+	return context.WithValue(ctx, fileKey{}, f)
+}
+
+func fileFrom(ctx context.Context) *File {
+	f, _ := ctx.Value(fileKey{}).(*File)
+	return f
+}
+
+// Add counts n bytes for the file of ctx, if any. Backends use it when
+// they learn how much was stored from the server, e.g. a committed offset.
+func Add(ctx context.Context, n int64) {
+	if f := fileFrom(ctx); f != nil && n > 0 {
+		f.Add(n)
+	}
+}
+
+// Reader counts the bytes read from r for the file of ctx. It returns r
+// itself when ctx has no file.
+func Reader(ctx context.Context, r io.Reader) io.Reader {
+	f := fileFrom(ctx)
+	if f == nil {
+		return r
+	}
+	return &reader{r: r, file: f}
+}
+
+// ReadSeeker is like Reader for seekable bodies. Bytes are counted once
+// even when the body is rewound and read again, as HTTP clients do to sign
+// or retry a request.
+func ReadSeeker(ctx context.Context, r io.ReadSeeker) io.ReadSeeker {
+	f := fileFrom(ctx)
+	if f == nil {
+		return r
+	}
+	return &seekReader{r: r, file: f}
+}
+
+// WriterAt counts the bytes written through w for the file of ctx.
+func WriterAt(ctx context.Context, w io.WriterAt) io.WriterAt {
+	f := fileFrom(ctx)
+	if f == nil {
+		return w
+	}
+	return &writerAt{w: w, file: f}
+}
+
+type reader struct {
+	r    io.Reader
+	file *File
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.file.Add(int64(n))
+	return n, err
+}
+
+type seekReader struct {
+	r    io.ReadSeeker
+	file *File
+	// pos is the current offset and high the furthest offset counted.
+	pos, high int64
+}
+
+func (r *seekReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.pos += int64(n)
+	if r.pos > r.high {
+		r.file.Add(r.pos - r.high)
+		r.high = r.pos
+	}
+	return n, err
+}
+
+func (r *seekReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.r.Seek(offset, whence)
+	if err == nil {
+		r.pos = pos
+	}
+	return pos, err
+}
+
+type writerAt struct {
+	w    io.WriterAt
+	file *File
+}
+
+func (w *writerAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.w.WriteAt(p, off)
+	w.file.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,88 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinishAccounting(t *testing.T) {
+	tracker := NewTracker()
+	tracker.AddTotal(3, 300)
+
+	ok := tracker.StartFile("a", 100)
+	ok.Add(40)
+	failed := tracker.StartFile("b", 100)
+	failed.Add(70)
+	active := tracker.StartFile("c", 100)
+	active.Add(10)
+
+	ok.Finish(nil)
+	failed.Finish(errors.New("boom"))
+
+	s := tracker.Snapshot()
+	assert.Equal(t, int64(110), s.BytesDone)
+	assert.Equal(t, int64(300), s.BytesTotal)
+	assert.Equal(t, int64(1), s.ObjectsDone)
+	assert.Equal(t, int64(1), s.ObjectsFailed)
+	assert.Equal(t, []FileStatus{{Name: "c", Size: 100, Done: 10}}, s.Active)
+}
+
+func TestReadSeekerCountsRereadsOnce(t *testing.T) {
+	tracker := NewTracker()
+	file := tracker.StartFile("obj", 10)
+	ctx := WithFile(context.Background(), file)
+
+	r := ReadSeeker(ctx, bytes.NewReader([]byte("0123456789")))
+	buf := make([]byte, 6)
+	io.ReadFull(r, buf)
+	r.Seek(0, io.SeekStart)
+	io.ReadAll(r)
+
+	assert.Equal(t, int64(10), tracker.Snapshot().BytesDone)
+}
+
+func TestWrappersWithoutFile(t *testing.T) {
+	r := bytes.NewReader(nil)
+	assert.Same(t, r, Reader(context.Background(), r))
+	assert.Same(t, r, ReadSeeker(context.Background(), r))
+	Add(context.Background(), 10)
+}
+
+func TestSnapshotETA(t *testing.T) {
+	tracker := NewTracker()
+	tracker.start = time.Now().Add(-2 * time.Second)
+	tracker.AddTotal(1, 300)
+	tracker.StartFile("obj", 300).Add(100)
+
+	s := tracker.Snapshot()
+	assert.InDelta(t, 50, s.Throughput, 5)
+	assert.InDelta(t, 4*time.Second, s.ETA, float64(time.Second/2))
+}
+
+func TestPlainDisplay(t *testing.T) {
+	tracker := NewTracker()
+	tracker.AddTotal(2, 2048)
+	file := tracker.StartFile("dir/a", 1024)
+	file.Add(512)
+
+	var out bytes.Buffer
+	display := start(&out, tracker, false, time.Hour)
+	display.Stop()
+	file.Finish(nil)
+
+	line := out.String()
+	assert.True(t, strings.HasPrefix(line, "Transferred 512 B / 2.0 KiB (25%), 0/2 objects"), line)
+	assert.Equal(t, 1, strings.Count(line, "\n"))
+
+	assert.Equal(t,
+		"Progress: 512 B / 1.0 KiB (50%), 1/2 objects, 0 B/s, active: dir/a",
+		plainLine(Snapshot{BytesDone: 512, BytesTotal: 1024, ObjectsDone: 1, ObjectsTotal: 2,
+			Active: []FileStatus{{Name: "dir/a"}}}, false))
+}
@@ -0,0 +1,129 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/aws/smithy-go"
+	"google.golang.org/api/googleapi"
+)
+
+// Class is the kind of failure an error represents.
+type Class int
+
+const (
+	// Permanent errors fail the same way when repeated.
+	Permanent Class = iota
+	// Throttled means the provider asked us to slow down (429, SlowDown).
+	Throttled
+	// Transient errors are server errors, timeouts and dropped connections.
+	Transient
+	// Auth errors are missing or insufficient credentials.
+	Auth
+	// NotFound means the bucket or object does not exist.
+	NotFound
+)
+
+func (c Class) String() string {
+	switch c {
+	case Throttled:
+		return "throttled"
+	case Transient:
+		return "transient"
+	case Auth:
+		return "auth"
+	case NotFound:
+		return "not found"
+	}
+	return "permanent"
+}
+
+// Retriable reports whether an error of this class may succeed when the
+// operation is repeated.
+func (c Class) Retriable() bool {
+	return c == Throttled || c == Transient
+}
+
+// Classify inspects the S3, GCS and Azure error types as well as network
+// errors found in err's chain.
+func Classify(err error) Class {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return Permanent
+	}
+	if store.IsNotExist(err) {
+		return NotFound
+	}
+	// Content that arrived damaged is sent again.
+	if errors.Is(err, checksum.ErrMismatch) {
+		return Transient
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		if class, ok := classifyCode(apiErr.ErrorCode()); ok {
+			return class
+		}
+	}
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		return classifyStatus(gErr.Code)
+	}
+	var azErr *azcore.ResponseError
+	if errors.As(err, &azErr) {
+		return classifyStatus(azErr.StatusCode)
+	}
+	// S3 response errors, and the GCS resumable upload errors.
+	var statusErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &statusErr) {
+		return classifyStatus(statusErr.HTTPStatusCode())
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr),
+		errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EPIPE):
+		return Transient
+	}
+	return Permanent
+}
+
+func classifyCode(code string) (Class, bool) {
+	switch code {
+	case "SlowDown", "Throttling", "ThrottlingException", "RequestThrottled",
+		"RequestLimitExceeded", "TooManyRequestsException", "RequestThrottledException":
+		return Throttled, true
+	case "InternalError", "ServiceUnavailable", "RequestTimeout", "BadDigest":
+		return Transient, true
+	case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch",
+		"ExpiredToken", "InvalidToken", "AuthFailure":
+		return Auth, true
+	case "NoSuchKey", "NoSuchBucket", "NotFound":
+		return NotFound, true
+	}
+	return Permanent, false
+}
+
+func classifyStatus(status int) Class {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return Throttled
+	case status == http.StatusUnauthorized, status == http.StatusForbidden:
+		return Auth
+	case status == http.StatusNotFound:
+		return NotFound
+	case status == http.StatusRequestTimeout, status >= 500:
+		return Transient
+	}
+	return Permanent
+}
@@ -0,0 +1,101 @@
+// Package retry repeats object operations that fail with throttling or
+// transient errors, backing off exponentially with jitter.
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultBaseDelay   = 500 * time.Millisecond
+	defaultMaxDelay    = 30 * time.Second
+)
+
+// Policy configures Do. The zero value retries up to 5 attempts with no
+// overall deadline.
+type Policy struct {
+	// MaxAttempts counts the first attempt; 1 disables retries.
+	MaxAttempts int
+	// Deadline bounds the time spent on all attempts of one operation.
+	// No retry is started that would end after it. Zero means no limit.
+	Deadline time.Duration
+	// BaseDelay is the backoff before the first retry, doubled for each
+	// further retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Log receives one line per retry; by default it is printed on stderr.
+	Log func(msg string)
+}
+
+// Do calls fn until it succeeds, fails with an error that is not
+// retriable, or the policy's attempts or deadline are used up. what names
+// the operation in the retry log.
+func Do(ctx context.Context, p Policy, what string, fn func(ctx context.Context) error) error {
+	p = p.withDefaults()
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		class := Classify(err)
+		if !class.Retriable() {
+			return err
+		}
+		if attempt == p.MaxAttempts {
+			return fmt.Errorf("%w (gave up after %d attempts)", err, attempt)
+		}
+		delay := p.backoff(attempt, class)
+		if p.Deadline > 0 && time.Since(start)+delay > p.Deadline {
+			return fmt.Errorf("%w (gave up after %d attempts in %s)", err, attempt, time.Since(start).Round(time.Second))
+		}
+
+		p.Log(fmt.Sprintf("Retrying %s in %s (attempt %d of %d, %s): %v",
+			what, delay.Round(time.Millisecond), attempt+1, p.MaxAttempts, class, err))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultMaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultBaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultMaxDelay
+	}
+	if p.Log == nil {
+		p.Log = func(msg string) { fmt.Fprintln(os.Stderr, msg) }
+	}
+	return p
+}
+
+// backoff returns the delay before the retry following attempt: half the
+// exponential delay plus a random part of up to the other half, so that
+// workers failing together do not retry together. Throttling backs off
+// twice as long.
+func (p Policy) backoff(attempt int, class Class) time.Duration {
+	delay := p.BaseDelay
+	if class == Throttled {
+		delay *= 2
+	}
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,122 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Class
+	}{
+		{&smithy.GenericAPIError{Code: "SlowDown"}, Throttled},
+		{&smithy.GenericAPIError{Code: "InternalError"}, Transient},
+		{&smithy.GenericAPIError{Code: "AccessDenied"}, Auth},
+		{&smithy.GenericAPIError{Code: "NoSuchBucket"}, NotFound},
+		{&smithy.GenericAPIError{Code: "InvalidArgument"}, Permanent},
+		{&smithyhttp.ResponseError{Response: &smithyhttp.Response{Response: &http.Response{StatusCode: 503}}}, Transient},
+		{&googleapi.Error{Code: 429}, Throttled},
+		{&googleapi.Error{Code: 403}, Auth},
+		{&googleapi.Error{Code: 400}, Permanent},
+		{&azcore.ResponseError{StatusCode: 500}, Transient},
+		{&azcore.ResponseError{StatusCode: 401}, Auth},
+		{fmt.Errorf("s3://b/k: %w", store.ErrNotExist), NotFound},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), Transient},
+		{fmt.Errorf("Error verifying [a]: %w", checksum.ErrMismatch), Transient},
+		{context.Canceled, Permanent},
+		{errors.New("invalid pattern"), Permanent},
+	}
+	for _, tt := range tests {
+		wrapped := fmt.Errorf("Error copying [key]: %w", tt.err)
+		assert.Equal(t, tt.want, Classify(wrapped), "%v", tt.err)
+	}
+}
+
+func fastPolicy(log *[]string) Policy {
+	return Policy{
+		BaseDelay: time.Millisecond,
+		MaxDelay:  2 * time.Millisecond,
+		Log:       func(msg string) { *log = append(*log, msg) },
+	}
+}
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	var log []string
+	calls := 0
+	err := Do(context.Background(), fastPolicy(&log), "gs://b/k", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &googleapi.Error{Code: 429, Message: "rate limited"}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Len(t, log, 2)
+	assert.Contains(t, log[0], "Retrying gs://b/k in ")
+	assert.Contains(t, log[0], "(attempt 2 of 5, throttled)")
+}
+
+func TestDoStopsOnPermanentErrors(t *testing.T) {
+	var log []string
+	calls := 0
+	err := Do(context.Background(), fastPolicy(&log), "s3://b/k", func(ctx context.Context) error {
+		calls++
+		return &smithy.GenericAPIError{Code: "AccessDenied"}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, log)
+}
+
+func TestDoGivesUpAfterMaxAttempts(t *testing.T) {
+	var log []string
+	policy := fastPolicy(&log)
+	policy.MaxAttempts = 3
+	calls := 0
+	err := Do(context.Background(), policy, "s3://b/k", func(ctx context.Context) error {
+		calls++
+		return &smithy.GenericAPIError{Code: "ServiceUnavailable"}
+	})
+	assert.ErrorContains(t, err, "gave up after 3 attempts")
+	assert.Equal(t, 3, calls)
+}
+
+func TestDoRespectsDeadline(t *testing.T) {
+	var log []string
+	policy := fastPolicy(&log)
+	policy.BaseDelay = time.Hour
+	policy.MaxDelay = time.Hour
+	policy.Deadline = time.Minute
+	err := Do(context.Background(), policy, "s3://b/k", func(ctx context.Context) error {
+		return &smithy.GenericAPIError{Code: "InternalError"}
+	})
+	assert.ErrorContains(t, err, "gave up after 1 attempts")
+	assert.Empty(t, log)
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for i := 0; i < 20; i++ {
+		d := p.backoff(1, Transient)
+		assert.True(t, d >= 50*time.Millisecond && d <= 100*time.Millisecond, d)
+		d = p.backoff(2, Throttled)
+		assert.True(t, d >= 200*time.Millisecond && d <= 400*time.Millisecond, d)
+		d = p.backoff(10, Transient)
+		assert.True(t, d >= 500*time.Millisecond && d <= time.Second, d)
+	}
+}
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// ErrNotExist is returned (wrapped) by Stat and Open when the object is missing.
+var ErrNotExist = errors.New("object does not exist")
+
+// ObjectInfo describes an object, or a common prefix when IsPrefix is set.
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+	ETag    string
+	// MD5 is the hex MD5 of the content when the backend reports it; it is
+	// empty for local files and S3 multipart objects.
+	MD5 string
+	// CRC32C and SHA256 are the hex checksums of the content when the
+	// backend stores them (GCS CRC32C, S3 additional checksums).
+	CRC32C       string
+	SHA256       string
+	StorageClass string
+	// Headers and Metadata are left out by listings that do not return
+	// them, such as S3 ListObjectsV2, which set Partial; Stat fills them.
+	Headers
+	Metadata map[string]string
+	Partial  bool
+	// Encryption describes how the backend encrypts the object at rest, as
+	// reported by Stat. It is empty when not known.
+	Encryption string
+	// VersionID is set when the object was looked up by version.
+	VersionID string
+	// Mode holds the permission bits of local files; it is zero for objects.
+	Mode     fs.FileMode
+	IsPrefix bool
+}
+
+type ListOptions struct {
+	// Recursive lists every object under the prefix instead of stopping at
+	// the next "/" and reporting common prefixes.
+	Recursive bool
+}
+
+type WriteOptions struct {
+	// Size is the length of the content, or -1 when it is not known upfront.
+	Size int64
+	// ModTime and Mode are applied by backends that can preserve them, such
+	// as the local filesystem.
+	ModTime time.Time
+	Mode    fs.FileMode
+	// PartSize and PartConcurrency tune chunked uploads (S3 multipart
+	// parts, Azure blocks, GCS chunks). Zero selects the backend default.
+	PartSize        int64
+	PartConcurrency int
+	// Headers and Metadata are stored with the object as system and user
+	// metadata. The local filesystem ignores them.
+	Headers
+	Metadata map[string]string
+	// CRC32C, when set, is the hex CRC32C of the content. S3 and GCS
+	// reject the upload if the content they receive does not match it.
+	CRC32C string
+	// Encryption selects how the object is encrypted at rest. The local
+	// filesystem ignores it.
+	Encryption Encryption
+	// Checkpoint, when set, lets the store save the state of a chunked
+	// upload and continue it on a later attempt. Uploads with a checkpoint
+	// are left in place on failure instead of being aborted.
+	Checkpoint Checkpoint
+}
+
+type CopyOptions struct {
+	// Encryption selects how the copy is encrypted at rest.
+	Encryption Encryption
+	// Size is the size of the source object, when known. S3 copies
+	// objects over 5 GiB in parts of PartSize bytes, PartConcurrency at a
+	// time; zero selects the backend default.
+	Size            int64
+	PartSize        int64
+	PartConcurrency int
+	// ReplaceMetadata gives the copy Headers and Metadata instead of the
+	// system and user metadata of the source.
+	ReplaceMetadata bool
+	Headers         Headers
+	Metadata        map[string]string
+}
+
+// Headers are the system metadata that S3, GCS and Azure keep with an
+// object and send back as HTTP headers when it is read.
+type Headers struct {
+	ContentType        string
+	CacheControl       string
+	ContentEncoding    string
+	ContentDisposition string
+}
+
+type EncryptionMode string
+
+const (
+	// EncryptionDefault leaves encryption to the bucket settings.
+	EncryptionDefault EncryptionMode = ""
+	// EncryptionManaged uses keys managed by the provider (S3 SSE-S3).
+	EncryptionManaged EncryptionMode = "managed"
+	// EncryptionKMS uses a key from the provider's key management service
+	// (S3 SSE-KMS, GCS CMEK).
+	EncryptionKMS EncryptionMode = "kms"
+	// EncryptionCustomer uses a key supplied with every request, which the
+	// provider does not keep (S3 SSE-C, GCS CSEK).
+	EncryptionCustomer EncryptionMode = "customer"
+)
+
+// Encryption is a server-side encryption setting.
+type Encryption struct {
+	Mode EncryptionMode
+	// KMSKey is an AWS KMS key ARN or a Cloud KMS key name. SSE-KMS falls
+	// back to the AWS managed key when it is empty.
+	KMSKey string
+	// CustomerKey is the 256-bit key of EncryptionCustomer.
+	CustomerKey []byte
+}
+
+func (e Encryption) String() string {
+	switch e.Mode {
+	case EncryptionManaged:
+		return "provider-managed"
+	case EncryptionKMS:
+		if e.KMSKey != "" {
+			return "KMS " + e.KMSKey
+		}
+		return "KMS"
+	case EncryptionCustomer:
+		return "customer key"
+	}
+	return "bucket default"
+}
+
+type customerKeyKey struct{}
+
+// WithCustomerKey returns a context whose requests read objects encrypted
+// with the customer key (SSE-C, CSEK). Writes take their key from
+// WriteOptions instead.
+func WithCustomerKey(ctx context.Context, key []byte) context.Context {
+	return context.WithValue(ctx, customerKeyKey{}, key)
+}
+
+// CustomerKey returns the key set by WithCustomerKey, or nil.
+func CustomerKey(ctx context.Context) []byte {
+	key, _ := ctx.Value(customerKeyKey{}).([]byte)
+	return key
+}
+
+// UploadState identifies an in-flight chunked upload: an S3 multipart
+// upload ID or a GCS resumable session URL, and the part size it uses.
+type UploadState struct {
+	UploadID string `json:"upload_id"`
+	PartSize int64  `json:"part_size,omitempty"`
+	Parts    []Part `json:"parts,omitempty"`
+}
+
+// Part is a completed part of a multipart upload.
+type Part struct {
+	Number int32  `json:"number"`
+	ETag   string `json:"etag"`
+	Size   int64  `json:"size"`
+}
+
+// Checkpoint persists the progress of one chunked upload.
+type Checkpoint interface {
+	// Resume returns the upload started by an earlier attempt, if any.
+	Resume() (UploadState, bool)
+	// Started records a newly started upload, replacing any earlier one.
+	Started(state UploadState) error
+	// PartDone records a part that has been uploaded.
+	PartDone(part Part) error
+}
+
+// WalkFunc is called for every entry returned by List. Returning an error
+// stops the listing and List returns that error.
+type WalkFunc func(obj ObjectInfo) error
+
+// ObjectStore is implemented by every storage backend so that the transfer
+// code can work on any pair of providers.
+type ObjectStore interface {
+	List(ctx context.Context, bucket, prefix string, opts ListOptions, fn WalkFunc) error
+	Stat(ctx context.Context, bucket, key string) (ObjectInfo, error)
+	// Open reads length bytes starting at offset. A negative length reads
+	// until the end of the object.
+	Open(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error)
+	Write(ctx context.Context, bucket, key string, r io.Reader, opts WriteOptions) error
+	Delete(ctx context.Context, bucket, key string) error
+	// Copy copies an object within the same store without moving the data
+	// through the client.
+	Copy(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, opts CopyOptions) error
+}
+
+// VersionedStore is implemented by stores that can read a specific object
+// version (S3 version IDs, GCS generations, Azure blob versions).
+type VersionedStore interface {
+	StatVersion(ctx context.Context, bucket, key, version string) (ObjectInfo, error)
+	OpenVersion(ctx context.Context, bucket, key, version string, offset, length int64) (io.ReadCloser, error)
+}
+
+// VersionDeleter is implemented by stores that can delete a specific object
+// version instead of the current object.
+type VersionDeleter interface {
+	DeleteVersion(ctx context.Context, bucket, key, version string) error
+}
+
+// BucketInfo describes a bucket, or an Azure container.
+type BucketInfo struct {
+	Name    string
+	Created time.Time
+	// Location and StorageClass are empty when the backend does not report
+	// them in its bucket listing.
+	Location     string
+	StorageClass string
+}
+
+// BucketLister is implemented by stores that can list their buckets.
+type BucketLister interface {
+	ListBuckets(ctx context.Context, fn func(BucketInfo) error) error
+}
+
+// BucketOptions configures a new bucket. Empty fields keep the provider
+// defaults.
+type BucketOptions struct {
+	// Location is the S3 region or the GCS location, e.g. "EU".
+	Location     string
+	StorageClass string
+	Versioning   bool
+	// Encryption is the default encryption of new objects; customer keys
+	// cannot be a bucket default.
+	Encryption Encryption
+	// BlockPublicAccess turns on the S3 public access block or GCS public
+	// access prevention.
+	BlockPublicAccess bool
+	// Labels are set as S3 bucket tags or GCS labels.
+	Labels map[string]string
+}
+
+// BucketManager is implemented by stores that can create and delete
+// buckets.
+type BucketManager interface {
+	CreateBucket(ctx context.Context, bucket string, opts BucketOptions) error
+	// DeleteBucket deletes an empty bucket. With force, every object in it
+	// is deleted first, noncurrent versions and delete markers included.
+	DeleteBucket(ctx context.Context, bucket string, force bool) error
+}
+
+// BatchDeleter is implemented by stores that delete many objects with one
+// request, such as S3 with DeleteObjects.
+type BatchDeleter interface {
+	// DeleteBatch deletes keys and returns the error of every key that was
+	// not deleted. Keys missing from the map were deleted.
+	DeleteBatch(ctx context.Context, bucket string, keys []string) map[string]error
+}
+
+func IsNotExist(err error) bool {
+	return errors.Is(err, ErrNotExist)
+}
@@ -0,0 +1,269 @@
+// Package storetest provides an in-memory store.ObjectStore for tests.
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+type object struct {
+	data []byte
+	info store.ObjectInfo
+}
+
+// MemStore keeps objects in memory, keyed by bucket and key.
+type MemStore struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]*object
+	// created holds the options of buckets made with CreateBucket.
+	created map[string]store.BucketOptions
+}
+
+var (
+	_ store.ObjectStore   = (*MemStore)(nil)
+	_ store.BucketLister  = (*MemStore)(nil)
+	_ store.BucketManager = (*MemStore)(nil)
+)
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		buckets: map[string]map[string]*object{},
+		created: map[string]store.BucketOptions{},
+	}
+}
+
+// BucketOptions returns the options bucket was created with.
+func (m *MemStore) BucketOptions(bucket string) (store.BucketOptions, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	opts, ok := m.created[bucket]
+	return opts, ok
+}
+
+// Put stores data under bucket/key, creating the bucket if needed.
+func (m *MemStore) Put(bucket, key string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(bucket, key, data)
+}
+
+// Get returns the content of bucket/key and whether it exists.
+func (m *MemStore) Get(bucket, key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	obj, ok := m.buckets[bucket][key]
+	if !ok {
+		return nil, false
+	}
+	return obj.data, true
+}
+
+// Keys returns the sorted keys stored in bucket.
+func (m *MemStore) Keys(bucket string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.buckets[bucket]))
+	for k := range m.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Touch sets the modification time reported for bucket/key.
+func (m *MemStore) Touch(bucket, key string, modTime time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if obj, ok := m.buckets[bucket][key]; ok {
+		obj.info.ModTime = modTime
+	}
+}
+
+func (m *MemStore) put(bucket, key string, data []byte) *object {
+	if m.buckets[bucket] == nil {
+		m.buckets[bucket] = map[string]*object{}
+	}
+	obj := &object{
+		data: data,
+		info: store.ObjectInfo{
+			Key:     key,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+			MD5:     fmt.Sprintf("%x", md5.Sum(data)),
+			CRC32C:  checksum.CRC32C(data),
+		},
+	}
+	m.buckets[bucket][key] = obj
+	return obj
+}
+
+func (m *MemStore) List(
+	ctx context.Context,
+	bucket, prefix string,
+	opts store.ListOptions, fn store.WalkFunc) error {
+
+	var entries []store.ObjectInfo
+	seen := map[string]bool{}
+	for _, key := range m.Keys(bucket) {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if !opts.Recursive {
+			if i := strings.Index(key[len(prefix):], "/"); i >= 0 {
+				p := key[:len(prefix)+i+1]
+				if !seen[p] {
+					seen[p] = true
+					entries = append(entries, store.ObjectInfo{Key: p, IsPrefix: true})
+				}
+				continue
+			}
+		}
+		info, err := m.Stat(ctx, bucket, key)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, info)
+	}
+
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListBuckets lists the buckets that have been written to, by name.
+func (m *MemStore) ListBuckets(ctx context.Context, fn func(store.BucketInfo) error) error {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.buckets))
+	for name := range m.buckets {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := fn(store.BucketInfo{Name: name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemStore) CreateBucket(ctx context.Context, bucket string, opts store.BucketOptions) error {
+	if dryrun.Report(ctx, dryrun.Create, "mem bucket "+bucket) {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.buckets[bucket]; ok {
+		return fmt.Errorf("mem://%s already exists", bucket)
+	}
+	m.buckets[bucket] = map[string]*object{}
+	m.created[bucket] = opts
+	return nil
+}
+
+func (m *MemStore) DeleteBucket(ctx context.Context, bucket string, force bool) error {
+	if dryrun.Report(ctx, dryrun.Delete, "mem bucket "+bucket) {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	objects, ok := m.buckets[bucket]
+	if !ok {
+		return fmt.Errorf("mem://%s: %w", bucket, store.ErrNotExist)
+	}
+	if len(objects) > 0 && !force {
+		return fmt.Errorf("mem://%s is not empty", bucket)
+	}
+	delete(m.buckets, bucket)
+	delete(m.created, bucket)
+	return nil
+}
+
+func (m *MemStore) Stat(ctx context.Context, bucket, key string) (store.ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	obj, ok := m.buckets[bucket][key]
+	if !ok {
+		return store.ObjectInfo{}, fmt.Errorf("mem://%s/%s: %w", bucket, key, store.ErrNotExist)
+	}
+	return obj.info, nil
+}
+
+func (m *MemStore) Open(
+	ctx context.Context,
+	bucket, key string, offset, length int64) (io.ReadCloser, error) {
+
+	data, ok := m.Get(bucket, key)
+	if !ok {
+		return nil, fmt.Errorf("mem://%s/%s: %w", bucket, key, store.ErrNotExist)
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	end := int64(len(data))
+	if length >= 0 && offset+length < end {
+		end = offset + length
+	}
+	return io.NopCloser(bytes.NewReader(data[offset:end])), nil
+}
+
+func (m *MemStore) Write(
+	ctx context.Context,
+	bucket, key string, r io.Reader, opts store.WriteOptions) error {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if opts.CRC32C != "" && opts.CRC32C != checksum.CRC32C(data) {
+		return fmt.Errorf("mem://%s/%s: %w", bucket, key, checksum.ErrMismatch)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	obj := m.put(bucket, key, data)
+	obj.info.Headers = opts.Headers
+	obj.info.Metadata = opts.Metadata
+	obj.info.Encryption = opts.Encryption.String()
+	return nil
+}
+
+func (m *MemStore) Delete(ctx context.Context, bucket, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.buckets[bucket], key)
+	return nil
+}
+
+func (m *MemStore) Copy(
+	ctx context.Context,
+	srcBucket, srcKey, dstBucket, dstKey string, opts store.CopyOptions) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	src, ok := m.buckets[srcBucket][srcKey]
+	if !ok {
+		return fmt.Errorf("mem://%s/%s: %w", srcBucket, srcKey, store.ErrNotExist)
+	}
+	obj := m.put(dstBucket, dstKey, append([]byte(nil), src.data...))
+	obj.info.Headers, obj.info.Metadata = src.info.Headers, src.info.Metadata
+	if opts.ReplaceMetadata {
+		obj.info.Headers, obj.info.Metadata = opts.Headers, opts.Metadata
+	}
+	obj.info.Encryption = opts.Encryption.String()
+	return nil
+}
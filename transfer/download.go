@@ -0,0 +1,100 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+const (
+	defaultSliceSize        = 32 * 1024 * 1024
+	defaultSliceThreshold   = 128 * 1024 * 1024
+	defaultSliceConcurrency = 4
+)
+
+// DownloadSliced downloads obj as concurrent byte ranges of sliceSize and
+// writes each range into w at its offset. It stops at the first failed
+// range and returns its error.
+func DownloadSliced(
+	ctx context.Context,
+	src Endpoint, obj store.ObjectInfo,
+	w io.WriterAt, sliceSize int64, concurrency int) error {
+
+	if sliceSize <= 0 {
+		sliceSize = defaultSliceSize
+	}
+	if concurrency <= 0 {
+		concurrency = defaultSliceConcurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	offsets := make(chan int64)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for offset := range offsets {
+				length := sliceSize
+				if offset+length > obj.Size {
+					length = obj.Size - offset
+				}
+				if err := downloadRange(ctx, src, obj, w, offset, length); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for offset := int64(0); offset < obj.Size; offset += sliceSize {
+		select {
+		case offsets <- offset:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(offsets)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+func downloadRange(
+	ctx context.Context,
+	src Endpoint, obj store.ObjectInfo,
+	w io.WriterAt, offset, length int64) error {
+
+	reader, err := openRange(ctx, src, obj, offset, length)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	n, err := io.Copy(io.NewOffsetWriter(w, offset), reader)
+	if err != nil {
+		return fmt.Errorf("Error downloading bytes %d-%d of [%s]: %w", offset, offset+length-1, obj.Key, err)
+	}
+	if n != length {
+		return fmt.Errorf("Error downloading bytes %d-%d of [%s]: got %d bytes", offset, offset+length-1, obj.Key, n)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/RA-Balaji/storage-synk/encrypt"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+// copyEncrypted streams obj through the keyring: an encrypted source is
+// decrypted, and with opts.Encrypt the copy is encrypted with a new data
+// key. Server-side copies, staging and sliced downloads are not used.
+func copyEncrypted(
+	ctx context.Context,
+	src Endpoint, obj store.ObjectInfo,
+	dst Endpoint, dstKey string, opts Options) error {
+
+	if opts.Encrypt && isLocal(dst.Store) {
+		return fmt.Errorf("Error copying [%s]: encrypted copies need a bucket, local files cannot keep the key", obj.Key)
+	}
+
+	reader, verify, err := openVerified(ctx, src, obj)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var r io.Reader = reader
+	writeOpts := writeOptions(obj, dstKey, opts)
+	// A resumed upload would mix content sealed with different data keys,
+	// so encrypted objects are always uploaded from the start. The content
+	// changes, so the source CRC32C does not apply either.
+	writeOpts.Checkpoint = nil
+	writeOpts.CRC32C = ""
+	writeOpts.Metadata = withoutEncryption(writeOpts.Metadata)
+
+	if encrypt.IsEncrypted(obj.Metadata) {
+		r, err = opts.Keyring.Decrypt(r, obj.Metadata)
+		if err != nil {
+			return fmt.Errorf("Error decrypting [%s]: %w", obj.Key, err)
+		}
+		writeOpts.Size = encrypt.PlainSize(obj.Metadata, obj.Size)
+	}
+	if opts.Encrypt {
+		var sealed map[string]string
+		r, writeOpts.Size, sealed, err = opts.Keyring.Encrypt(r, writeOpts.Size)
+		if err != nil {
+			return fmt.Errorf("Error encrypting [%s]: %w", obj.Key, err)
+		}
+		for k, v := range sealed {
+			writeOpts.Metadata[k] = v
+		}
+		// The stored content is no longer in the encoding of the source.
+		writeOpts.ContentEncoding = ""
+	}
+
+	err = dst.Store.Write(ctx, dst.Bucket, dstKey, r, writeOpts)
+	if err != nil {
+		return fmt.Errorf("Error copying [%s]: %w", obj.Key, err)
+	}
+	return discardCorrupt(ctx, dst, dstKey, verify())
+}
@@ -0,0 +1,124 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/encrypt"
+	"github.com/RA-Balaji/storage-synk/local"
+	"github.com/RA-Balaji/storage-synk/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func testKeyring(t *testing.T) *encrypt.Keyring {
+	path := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(path, bytes.Repeat([]byte("k"), 32), 0600))
+	k, err := encrypt.LoadKeyFile(path)
+	assert.NoError(t, err)
+	return k
+}
+
+func TestCopyEncryptsAndDecrypts(t *testing.T) {
+	keyring := testKeyring(t)
+	srcDir := filepath.ToSlash(t.TempDir())
+	plain := bytes.Repeat([]byte("secret data "), 10000)
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), plain, 0644))
+	cloud := storetest.NewMemStore()
+	other := storetest.NewMemStore()
+
+	// Upload encrypted.
+	err := Copy(context.Background(),
+		Endpoint{Store: local.NewFileStore(), Prefix: srcDir + "/"},
+		Endpoint{Store: cloud, Bucket: "bucket", Prefix: "vault/"},
+		Options{Keyring: keyring, Encrypt: true, SliceThreshold: -1})
+	assert.NoError(t, err)
+	sealed, _ := cloud.Get("bucket", "vault/a.txt")
+	assert.Equal(t, encrypt.CiphertextSize(int64(len(plain))), int64(len(sealed)))
+	assert.NotContains(t, string(sealed), "secret data")
+	info, _ := cloud.Stat(context.Background(), "bucket", "vault/a.txt")
+	assert.True(t, encrypt.IsEncrypted(info.Metadata))
+
+	// Without --encrypt, encrypted sources are decrypted on the way.
+	err = Copy(context.Background(),
+		Endpoint{Store: cloud, Bucket: "bucket", Prefix: "vault/"},
+		Endpoint{Store: other, Bucket: "bucket", Prefix: "plain/"},
+		Options{Keyring: keyring})
+	assert.NoError(t, err)
+	got, _ := other.Get("bucket", "plain/a.txt")
+	assert.Equal(t, plain, got)
+
+	// Download to a local file.
+	dstDir := filepath.ToSlash(t.TempDir())
+	err = Copy(context.Background(),
+		Endpoint{Store: cloud, Bucket: "bucket", Prefix: "vault/a.txt"},
+		Endpoint{Store: local.NewFileStore(), Prefix: dstDir + "/"},
+		Options{Keyring: keyring})
+	assert.NoError(t, err)
+	got, err = os.ReadFile(filepath.Join(dstDir, "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, plain, got)
+
+	// Encrypted copies cannot go to local files.
+	err = Copy(context.Background(),
+		Endpoint{Store: other, Bucket: "bucket", Prefix: "plain/a.txt"},
+		Endpoint{Store: local.NewFileStore(), Prefix: dstDir + "/enc/"},
+		Options{Keyring: keyring, Encrypt: true})
+	assert.ErrorContains(t, err, "local files cannot keep the key")
+}
+
+func TestSyncEncryptedDestination(t *testing.T) {
+	keyring := testKeyring(t)
+	src := storetest.NewMemStore()
+	dst := storetest.NewMemStore()
+	src.Put("bucket", "data/a.txt", []byte("hello"))
+	opts := Options{Keyring: keyring, Encrypt: true}
+
+	stats, err := Sync(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "data/"},
+		Endpoint{Store: dst, Bucket: "bucket", Prefix: "data/"}, CompareSizeOnly, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Copied)
+
+	// The encrypted copy is recognised as unchanged.
+	stats, err = Sync(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "data/"},
+		Endpoint{Store: dst, Bucket: "bucket", Prefix: "data/"}, CompareSizeOnly, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Copied)
+	assert.Equal(t, 1, stats.Unchanged)
+
+	_, err = Sync(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "data/"},
+		Endpoint{Store: dst, Bucket: "bucket", Prefix: "data/"}, CompareChecksum, opts)
+	assert.ErrorContains(t, err, "Checksums cannot be compared")
+}
+
+func TestSyncDecryptedDestination(t *testing.T) {
+	keyring := testKeyring(t)
+	srcDir := filepath.ToSlash(t.TempDir())
+	assert.NoError(t, os.WriteFile(filepath.Join(srcDir, "a.txt"), bytes.Repeat([]byte("secret "), 1000), 0644))
+	cloud := storetest.NewMemStore()
+	vault := Endpoint{Store: cloud, Bucket: "bucket", Prefix: "vault/"}
+
+	stats, err := Sync(context.Background(), Endpoint{Store: local.NewFileStore(), Prefix: srcDir + "/"}, vault,
+		CompareSizeOnly, Options{Keyring: keyring, Encrypt: true, SliceThreshold: -1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Copied)
+
+	// Restoring decrypts the objects; a second run finds the plaintext
+	// copies unchanged whatever the comparison.
+	restore := Endpoint{Store: local.NewFileStore(), Prefix: filepath.ToSlash(t.TempDir()) + "/"}
+	opts := Options{Keyring: keyring, SliceThreshold: -1}
+	stats, err = Sync(context.Background(), vault, restore, CompareMtime, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Copied)
+	for _, compare := range []Compare{CompareSizeOnly, CompareMtime, CompareChecksum} {
+		stats, err = Sync(context.Background(), vault, restore, compare, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, stats.Copied, compare)
+		assert.Equal(t, 1, stats.Unchanged, compare)
+	}
+}
@@ -0,0 +1,101 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/RA-Balaji/storage-synk/encrypt"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+// MetadataDirective decides which system headers and user metadata the
+// copies get.
+type MetadataDirective string
+
+const (
+	// MetadataKeep copies the headers and metadata of the source.
+	MetadataKeep MetadataDirective = "keep"
+	// MetadataReplace drops those of the source and sets only the given
+	// ones.
+	MetadataReplace MetadataDirective = "replace"
+	// MetadataAdd copies those of the source and sets the given ones on
+	// top of them.
+	MetadataAdd MetadataDirective = "add"
+)
+
+func ParseMetadataDirective(s string) (MetadataDirective, error) {
+	switch d := MetadataDirective(s); d {
+	case MetadataKeep, MetadataReplace, MetadataAdd:
+		return d, nil
+	}
+	return "", fmt.Errorf("Invalid metadata directive %q: use %s, %s or %s",
+		s, MetadataKeep, MetadataReplace, MetadataAdd)
+}
+
+// withMetadata fills in the headers and metadata of obj, which listings of
+// some backends leave out.
+func withMetadata(ctx context.Context, src Endpoint, obj store.ObjectInfo) (store.ObjectInfo, error) {
+	if !obj.Partial {
+		return obj, nil
+	}
+
+	info, err := statObject(ctx, src, obj)
+	if err != nil {
+		return obj, err
+	}
+	obj.Headers, obj.Metadata, obj.Partial = info.Headers, info.Metadata, false
+	return obj, nil
+}
+
+// copyMetadata returns the headers and metadata the copy of obj gets under
+// the directive of opts. Both providers keep user metadata as a plain map,
+// so the x-amz-meta-* names of S3 and the custom metadata of GCS carry
+// over as they are.
+func copyMetadata(obj store.ObjectInfo, opts Options) (store.Headers, map[string]string) {
+	switch opts.MetadataDirective {
+	case MetadataReplace:
+		return opts.Headers, opts.Metadata
+	case MetadataAdd:
+		headers := obj.Headers
+		for _, h := range []struct {
+			dst   *string
+			value string
+		}{
+			{&headers.ContentType, opts.Headers.ContentType},
+			{&headers.CacheControl, opts.Headers.CacheControl},
+			{&headers.ContentEncoding, opts.Headers.ContentEncoding},
+			{&headers.ContentDisposition, opts.Headers.ContentDisposition},
+		} {
+			if h.value != "" {
+				*h.dst = h.value
+			}
+		}
+		metadata := make(map[string]string, len(obj.Metadata)+len(opts.Metadata))
+		for k, v := range obj.Metadata {
+			metadata[k] = v
+		}
+		for k, v := range opts.Metadata {
+			metadata[k] = v
+		}
+		return headers, metadata
+	}
+	return obj.Headers, obj.Metadata
+}
+
+// withoutEncryption returns metadata without the keys of client-side
+// encryption, which only describe the encrypted content.
+func withoutEncryption(metadata map[string]string) map[string]string {
+	res := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		res[k] = v
+		for _, name := range []string{
+			encrypt.MetaAlgorithm, encrypt.MetaWrappedKey, encrypt.MetaKDF, encrypt.MetaSalt, encrypt.MetaPlainSize,
+		} {
+			if strings.EqualFold(k, name) {
+				delete(res, k)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/local"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyMetadataDirective(t *testing.T) {
+	ctx := context.Background()
+	src := storetest.NewMemStore()
+	err := src.Write(ctx, "bucket", "dir/a.html", strings.NewReader("<p>"), store.WriteOptions{
+		Size:     3,
+		Headers:  store.Headers{ContentType: "text/html", CacheControl: "no-cache"},
+		Metadata: map[string]string{"owner": "web"},
+	})
+	assert.NoError(t, err)
+
+	given := Options{
+		Headers:  store.Headers{CacheControl: "max-age=3600"},
+		Metadata: map[string]string{"team": "data"},
+	}
+	for _, tc := range []struct {
+		directive MetadataDirective
+		headers   store.Headers
+		metadata  map[string]string
+	}{
+		{MetadataKeep, store.Headers{ContentType: "text/html", CacheControl: "no-cache"},
+			map[string]string{"owner": "web"}},
+		{MetadataReplace, store.Headers{CacheControl: "max-age=3600"},
+			map[string]string{"team": "data"}},
+		{MetadataAdd, store.Headers{ContentType: "text/html", CacheControl: "max-age=3600"},
+			map[string]string{"owner": "web", "team": "data"}},
+	} {
+		opts := given
+		opts.MetadataDirective = tc.directive
+		// Server-side copies and streamed copies get the same metadata.
+		for _, dst := range []*storetest.MemStore{src, storetest.NewMemStore()} {
+			err := Copy(ctx,
+				Endpoint{Store: src, Bucket: "bucket", Prefix: "dir/"},
+				Endpoint{Store: dst, Bucket: "out", Prefix: string(tc.directive) + "/"},
+				opts)
+			assert.NoError(t, err)
+
+			info, err := dst.Stat(ctx, "out", string(tc.directive)+"/a.html")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.headers, info.Headers, tc.directive)
+			assert.Equal(t, tc.metadata, info.Metadata, tc.directive)
+		}
+	}
+}
+
+func TestCopyGuessesContentType(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<p>"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes"), []byte("x"), 0o644))
+
+	dst := storetest.NewMemStore()
+	err := Copy(context.Background(),
+		Endpoint{Store: local.NewFileStore(), Prefix: dir + "/"},
+		Endpoint{Store: dst, Bucket: "out"},
+		Options{})
+	assert.NoError(t, err)
+
+	info, err := dst.Stat(context.Background(), "out", "index.html")
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", info.ContentType)
+	info, err = dst.Stat(context.Background(), "out", "notes")
+	assert.NoError(t, err)
+	assert.Empty(t, info.ContentType)
+}
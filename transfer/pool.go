@@ -0,0 +1,138 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Policy decides what a Pool does when a task fails.
+type Policy int
+
+const (
+	// ContinueOnError runs every task and reports all failures at the end.
+	ContinueOnError Policy = iota
+	// FailFast cancels the running tasks and starts no new ones after the
+	// first failure.
+	FailFast
+)
+
+// Result is the outcome of one task of a Pool.
+type Result struct {
+	Key string
+	Err error
+}
+
+// Errors is returned by Pool.Wait when tasks failed. It lists every failed
+// object.
+type Errors struct {
+	Failed []Result
+	// Total is the number of tasks that were started.
+	Total int
+}
+
+func (e *Errors) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d objects failed:", len(e.Failed), e.Total)
+	for _, r := range e.Failed {
+		fmt.Fprintf(&b, "\n  %s: %v", r.Key, r.Err)
+	}
+	return b.String()
+}
+
+func (e *Errors) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, r := range e.Failed {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
+// Pool runs one task per object on a bounded number of goroutines and
+// collects the result of each.
+type Pool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	policy Policy
+	sem    chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	results []Result
+	stopped bool
+}
+
+func NewPool(ctx context.Context, concurrency int, policy Policy) *Pool {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pool{ctx: ctx, cancel: cancel, policy: policy, sem: make(chan struct{}, concurrency)}
+}
+
+// Go runs fn for key once a worker is free. It returns false, without
+// running fn, when the pool has stopped because of a failure under
+// FailFast or because its context was cancelled.
+func (p *Pool) Go(key string, fn func(ctx context.Context) error) bool {
+	select {
+	case p.sem <- struct{}{}:
+	case <-p.ctx.Done():
+		return false
+	}
+	if p.ctx.Err() != nil {
+		<-p.sem
+		return false
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer func() {
+			p.wg.Done()
+			<-p.sem
+		}()
+		p.done(key, fn(p.ctx))
+	}()
+	return true
+}
+
+func (p *Pool) done(key string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Tasks interrupted by a fail-fast stop did not fail on their own.
+	if err != nil && p.stopped && errors.Is(err, context.Canceled) {
+		return
+	}
+	p.results = append(p.results, Result{Key: key, Err: err})
+	if err != nil && p.policy == FailFast && !p.stopped {
+		p.stopped = true
+		p.cancel()
+	}
+}
+
+// Wait waits for the started tasks and returns an *Errors listing the
+// failed ones, or the context error if the pool was cancelled before
+// anything failed.
+func (p *Pool) Wait() error {
+	p.wg.Wait()
+	defer p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	aggregated := &Errors{Total: len(p.results)}
+	for _, r := range p.results {
+		if r.Err != nil {
+			aggregated.Failed = append(aggregated.Failed, r)
+		}
+	}
+	if len(aggregated.Failed) > 0 {
+		return aggregated
+	}
+	if !p.stopped {
+		return p.ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/retry"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolContinuesAndListsEveryFailure(t *testing.T) {
+	pool := NewPool(context.Background(), 2, ContinueOnError)
+	var ran atomic.Int32
+	for i := 0; i < 6; i++ {
+		key := fmt.Sprintf("obj-%d", i)
+		started := pool.Go(key, func(ctx context.Context) error {
+			ran.Add(1)
+			if key == "obj-1" || key == "obj-4" {
+				return errors.New("boom")
+			}
+			return nil
+		})
+		assert.True(t, started)
+	}
+
+	err := pool.Wait()
+	var failures *Errors
+	assert.True(t, errors.As(err, &failures))
+	assert.Equal(t, int32(6), ran.Load())
+	assert.Equal(t, 6, failures.Total)
+	assert.ElementsMatch(t, []Result{
+		{Key: "obj-1", Err: errors.New("boom")},
+		{Key: "obj-4", Err: errors.New("boom")},
+	}, failures.Failed)
+	assert.Contains(t, err.Error(), "2 of 6 objects failed:")
+	assert.Contains(t, err.Error(), "\n  obj-4: boom")
+}
+
+func TestPoolFailFast(t *testing.T) {
+	pool := NewPool(context.Background(), 1, FailFast)
+	assert.True(t, pool.Go("bad", func(ctx context.Context) error { return errors.New("boom") }))
+
+	started := 1
+	for i := 0; i < 10 && pool.Go("good", func(ctx context.Context) error { return nil }); i++ {
+		started++
+	}
+
+	err := pool.Wait()
+	var failures *Errors
+	assert.True(t, errors.As(err, &failures))
+	assert.Equal(t, []Result{{Key: "bad", Err: errors.New("boom")}}, failures.Failed)
+	// The single worker is busy with the failing task, so at most one more
+	// task can slip in before the pool stops.
+	assert.LessOrEqual(t, started, 2)
+}
+
+func TestPoolFailFastIgnoresCancelledTasks(t *testing.T) {
+	pool := NewPool(context.Background(), 2, FailFast)
+	running := make(chan struct{})
+	pool.Go("slow", func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-running
+	pool.Go("bad", func(ctx context.Context) error { return errors.New("boom") })
+
+	var failures *Errors
+	assert.True(t, errors.As(pool.Wait(), &failures))
+	assert.Equal(t, []Result{{Key: "bad", Err: errors.New("boom")}}, failures.Failed)
+}
+
+func TestPoolSuccess(t *testing.T) {
+	pool := NewPool(context.Background(), 0, ContinueOnError)
+	pool.Go("a", func(ctx context.Context) error { return nil })
+	assert.NoError(t, pool.Wait())
+}
+
+func TestCopyReportsEveryFailedObject(t *testing.T) {
+	src := storetest.NewMemStore()
+	dst := &failingStore{MemStore: storetest.NewMemStore(), fail: map[string]bool{"out/b.txt": true, "out/d.txt": true}}
+	for _, key := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		src.Put("bucket", "in/"+key, []byte(key))
+	}
+
+	err := Copy(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "in/", Scheme: "gs"},
+		Endpoint{Store: dst, Bucket: "bucket", Prefix: "out/"},
+		Options{})
+	assert.ErrorContains(t, err, "2 of 4 objects failed:")
+	assert.ErrorContains(t, err, "gs://bucket/in/b.txt")
+	assert.ErrorContains(t, err, "gs://bucket/in/d.txt")
+	assert.Equal(t, []string{"out/a.txt", "out/c.txt"}, dst.Keys("bucket"))
+}
+
+// failingStore fails writes to the keys in fail.
+type failingStore struct {
+	*storetest.MemStore
+	fail map[string]bool
+}
+
+func (f *failingStore) Write(ctx context.Context, bucket, key string, r io.Reader, opts store.WriteOptions) error {
+	if f.fail[key] {
+		return errors.New("write refused")
+	}
+	return f.MemStore.Write(ctx, bucket, key, r, opts)
+}
+
+func TestCopyRetriesTransientFailures(t *testing.T) {
+	src := storetest.NewMemStore()
+	src.Put("bucket", "in/a.txt", []byte("hello"))
+	dst := &flakyStore{MemStore: storetest.NewMemStore(), failures: 2}
+
+	var log []string
+	tracker := progress.NewTracker()
+	err := Copy(progress.WithTracker(context.Background(), tracker),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "in/"},
+		Endpoint{Store: dst, Bucket: "bucket", Prefix: "out/"},
+		Options{Retry: retry.Policy{
+			BaseDelay: time.Millisecond,
+			Log:       func(msg string) { log = append(log, msg) },
+		}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, dst.writes)
+	assert.Len(t, log, 2)
+	data, _ := dst.Get("bucket", "out/a.txt")
+	assert.Equal(t, "hello", string(data))
+	// Bytes of failed attempts are not counted twice.
+	assert.Equal(t, int64(5), tracker.Snapshot().BytesDone)
+}
+
+// flakyStore fails its first writes with a transient error after reading
+// the content.
+type flakyStore struct {
+	*storetest.MemStore
+	failures int
+	writes   int
+}
+
+func (f *flakyStore) Write(ctx context.Context, bucket, key string, r io.Reader, opts store.WriteOptions) error {
+	f.writes++
+	if f.writes <= f.failures {
+		io.Copy(io.Discard, progress.Reader(ctx, r))
+		return fmt.Errorf("Error writing [%s]: %w", key, syscall.ECONNRESET)
+	}
+	return f.MemStore.Write(ctx, bucket, key, progress.Reader(ctx, r), opts)
+}
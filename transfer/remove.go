@@ -0,0 +1,168 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/retry"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+// deleteBatchSize is the number of keys sent to a store.BatchDeleter at
+// once, the limit of S3 DeleteObjects.
+const deleteBatchSize = 1000
+
+// List returns the objects under e that opts.Filter selects: the single
+// object e.Prefix names, or every object below it.
+func List(ctx context.Context, e Endpoint, opts Options) ([]store.ObjectInfo, error) {
+	return listSource(ctx, e, opts)
+}
+
+// Remove deletes objects from target. Stores that implement
+// store.BatchDeleter get batches of up to 1000 keys; others get one Delete
+// per object. Objects with a VersionID have only that version deleted,
+// which needs a store.VersionDeleter. opts.Concurrency requests run at
+// once and failed ones are retried according to opts.Retry.
+//
+// Every object that could not be deleted is listed in the returned
+// *Errors; with opts.FailFast Remove stops at the first failure. When ctx
+// carries a dryrun.Plan the deletions are added to it instead. Remove
+// returns the URIs of the deleted objects in order.
+func Remove(ctx context.Context, target Endpoint, objects []store.ObjectInfo, opts Options) ([]string, error) {
+	if plan := dryrun.FromContext(ctx); plan != nil {
+		for _, obj := range objects {
+			plan.Add(dryrun.Action{Op: dryrun.Delete, Target: target.versionURI(obj.Key, obj.VersionID), Size: obj.Size})
+		}
+		return nil, nil
+	}
+
+	var mu sync.Mutex
+	var removed []string
+	var failed []Result
+	record := func(obj store.ObjectInfo, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		name := target.versionURI(obj.Key, obj.VersionID)
+		if err != nil {
+			failed = append(failed, Result{Key: name, Err: err})
+		} else {
+			removed = append(removed, name)
+		}
+	}
+
+	policy := ContinueOnError
+	if opts.FailFast {
+		policy = FailFast
+	}
+	pool := NewPool(ctx, opts.Concurrency, policy)
+	// Batches delete current objects only; versions are deleted one by
+	// one.
+	single := objects
+	if batcher, ok := target.Store.(store.BatchDeleter); ok {
+		var current []store.ObjectInfo
+		single = nil
+		for _, obj := range objects {
+			if obj.VersionID != "" {
+				single = append(single, obj)
+			} else {
+				current = append(current, obj)
+			}
+		}
+		for start := 0; start < len(current); start += deleteBatchSize {
+			end := start + deleteBatchSize
+			if end > len(current) {
+				end = len(current)
+			}
+			batch := current[start:end]
+			started := pool.Go(target.uri(batch[0].Key), func(ctx context.Context) error {
+				return deleteBatch(ctx, batcher, target, batch, opts.Retry, record)
+			})
+			if !started {
+				break
+			}
+		}
+	}
+	for _, obj := range single {
+		obj := obj
+		name := target.versionURI(obj.Key, obj.VersionID)
+		started := pool.Go(name, func(ctx context.Context) error {
+			err := retry.Do(ctx, opts.Retry, "deleting "+name, func(ctx context.Context) error {
+				return deleteObject(ctx, target, obj)
+			})
+			record(obj, err)
+			return err
+		})
+		if !started {
+			break
+		}
+	}
+
+	// The pool only sees batches; the failed objects are collected above.
+	err := pool.Wait()
+	sort.Strings(removed)
+	if len(failed) > 0 {
+		sort.Slice(failed, func(i, j int) bool { return failed[i].Key < failed[j].Key })
+		return removed, &Errors{Failed: failed, Total: len(objects)}
+	}
+	var aggregated *Errors
+	if err != nil && !errors.As(err, &aggregated) {
+		return removed, err
+	}
+	return removed, nil
+}
+
+// deleteObject deletes obj, or only its version when it has one.
+func deleteObject(ctx context.Context, target Endpoint, obj store.ObjectInfo) error {
+	if obj.VersionID == "" {
+		return target.Store.Delete(ctx, target.Bucket, obj.Key)
+	}
+	deleter, ok := target.Store.(store.VersionDeleter)
+	if !ok {
+		return fmt.Errorf("Deleting object versions is not supported for %s", target.uri(obj.Key))
+	}
+	return deleter.DeleteVersion(ctx, target.Bucket, obj.Key, obj.VersionID)
+}
+
+// deleteBatch deletes objects with one store request, sending the keys
+// that failed again while their errors are retriable.
+func deleteBatch(
+	ctx context.Context,
+	batcher store.BatchDeleter, target Endpoint, objects []store.ObjectInfo,
+	policy retry.Policy, record func(obj store.ObjectInfo, err error)) error {
+
+	pending := objects
+	var errs map[string]error
+	err := retry.Do(ctx, policy, "deleting "+target.uri(objects[0].Key)+" and others", func(ctx context.Context) error {
+		keys := make([]string, 0, len(pending))
+		for _, obj := range pending {
+			keys = append(keys, obj.Key)
+		}
+		errs = batcher.DeleteBatch(ctx, target.Bucket, keys)
+		var next []store.ObjectInfo
+		var first error
+		for _, obj := range pending {
+			if err := errs[obj.Key]; err != nil {
+				next = append(next, obj)
+				if first == nil {
+					first = err
+				}
+				continue
+			}
+			record(obj, nil)
+		}
+		pending = next
+		return first
+	})
+	for _, obj := range pending {
+		keyErr := errs[obj.Key]
+		if keyErr == nil {
+			keyErr = err
+		}
+		record(obj, keyErr)
+	}
+	return err
+}
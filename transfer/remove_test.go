@@ -0,0 +1,143 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/retry"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// batchStore deletes like S3 DeleteObjects. Keys in denied always fail;
+// keys in flaky fail on their first attempt only.
+type batchStore struct {
+	*storetest.MemStore
+	mu      sync.Mutex
+	batches []int
+	denied  map[string]bool
+	flaky   map[string]bool
+	// versions lists the versions deleted as "key@version".
+	versions []string
+}
+
+func (b *batchStore) DeleteVersion(ctx context.Context, bucket, key, version string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.versions = append(b.versions, key+"@"+version)
+	return nil
+}
+
+func (b *batchStore) DeleteBatch(ctx context.Context, bucket string, keys []string) map[string]error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, len(keys))
+	failed := map[string]error{}
+	for _, key := range keys {
+		switch {
+		case b.denied[key]:
+			failed[key] = fmt.Errorf("Error deleting %s: access denied", key)
+		case b.flaky[key]:
+			delete(b.flaky, key)
+			failed[key] = fmt.Errorf("Error deleting %s: %w", key, io.ErrUnexpectedEOF)
+		default:
+			b.MemStore.Delete(ctx, bucket, key)
+		}
+	}
+	return failed
+}
+
+func TestRemoveBatches(t *testing.T) {
+	s := &batchStore{
+		MemStore: storetest.NewMemStore(),
+		denied:   map[string]bool{"logs/0007": true},
+		flaky:    map[string]bool{"logs/2100": true},
+	}
+	for i := 0; i < 2500; i++ {
+		s.Put("bucket", fmt.Sprintf("logs/%04d", i), []byte("x"))
+	}
+	target := Endpoint{Store: s, Bucket: "bucket", Prefix: "logs/", Scheme: "s3"}
+
+	objects, err := List(context.Background(), target, Options{})
+	assert.NoError(t, err)
+	removed, err := Remove(context.Background(), target, objects,
+		Options{Retry: retry.Policy{BaseDelay: time.Millisecond}})
+
+	var failures *Errors
+	assert.True(t, errors.As(err, &failures))
+	assert.Equal(t, 2500, failures.Total)
+	assert.Len(t, failures.Failed, 1)
+	assert.Equal(t, "s3://bucket/logs/0007", failures.Failed[0].Key)
+	assert.Len(t, removed, 2499)
+	assert.True(t, sort.StringsAreSorted(removed))
+	assert.Equal(t, []string{"logs/0007"}, s.Keys("bucket"))
+
+	sort.Ints(s.batches)
+	assert.Equal(t, []int{1, 500, 1000, 1000}, s.batches)
+}
+
+func TestRemoveEachObject(t *testing.T) {
+	mem := storetest.NewMemStore()
+	mem.Put("bucket", "data/a.txt", []byte("a"))
+	mem.Put("bucket", "data/sub/b.txt", []byte("b"))
+	mem.Put("bucket", "other.txt", []byte("o"))
+	target := Endpoint{Store: mem, Bucket: "bucket", Prefix: "data/", Scheme: "gs"}
+
+	objects, err := List(context.Background(), target, Options{})
+	assert.NoError(t, err)
+	removed, err := Remove(context.Background(), target, objects, Options{Concurrency: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"gs://bucket/data/a.txt", "gs://bucket/data/sub/b.txt"}, removed)
+	assert.Equal(t, []string{"other.txt"}, mem.Keys("bucket"))
+}
+
+func TestRemoveVersion(t *testing.T) {
+	s := &batchStore{MemStore: storetest.NewMemStore()}
+	s.Put("bucket", "data/a.txt", []byte("current"))
+	target := Endpoint{Store: s, Bucket: "bucket", Prefix: "data/a.txt", Scheme: "s3"}
+	objects := []store.ObjectInfo{{Key: "data/a.txt", Size: 3, VersionID: "v1"}}
+
+	// Only the version goes; the current object stays.
+	removed, err := Remove(context.Background(), target, objects, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"s3://bucket/data/a.txt?versionId=v1"}, removed)
+	assert.Equal(t, []string{"data/a.txt@v1"}, s.versions)
+	assert.Empty(t, s.batches)
+	assert.Equal(t, []string{"data/a.txt"}, s.Keys("bucket"))
+
+	mem := storetest.NewMemStore()
+	mem.Put("bucket", "data/a.txt", []byte("current"))
+	target.Store = mem
+	_, err = Remove(context.Background(), target, objects, Options{Retry: retry.Policy{MaxAttempts: 1}})
+	assert.ErrorContains(t, err, "Deleting object versions is not supported")
+	assert.Equal(t, []string{"data/a.txt"}, mem.Keys("bucket"))
+}
+
+func TestRemoveDryRun(t *testing.T) {
+	mem := storetest.NewMemStore()
+	mem.Put("bucket", "data/a.txt", []byte("aaa"))
+	target := Endpoint{Store: mem, Bucket: "bucket", Prefix: "data/", Scheme: "s3"}
+
+	plan := dryrun.NewPlan()
+	ctx := dryrun.WithPlan(context.Background(), plan)
+	objects, err := List(ctx, target, Options{})
+	assert.NoError(t, err)
+	removed, err := Remove(ctx, target, objects, Options{})
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+	assert.Equal(t, []dryrun.Action{{Op: dryrun.Delete, Target: "s3://bucket/data/a.txt", Size: 3}}, plan.Actions())
+	assert.Equal(t, []string{"data/a.txt"}, mem.Keys("bucket"))
+}
+
+var (
+	_ store.BatchDeleter   = (*batchStore)(nil)
+	_ store.VersionDeleter = (*batchStore)(nil)
+)
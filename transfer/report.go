@@ -0,0 +1,100 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/utils"
+)
+
+// ReportEntry is an object copied by a transfer.
+type ReportEntry struct {
+	Source     string
+	Target     string
+	Size       int64
+	Encryption string
+}
+
+// Report lists the objects a transfer copied. It is safe for concurrent
+// use.
+type Report struct {
+	mu      sync.Mutex
+	entries []ReportEntry
+}
+
+func NewReport() *Report {
+	return &Report{}
+}
+
+func (r *Report) Add(entry ReportEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Entries returns the copied objects in the order they finished.
+func (r *Report) Entries() []ReportEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ReportEntry(nil), r.entries...)
+}
+
+// Print writes one line per object followed by the number of objects per
+// encryption.
+func (r *Report) Print(w io.Writer) {
+	counts := map[string]int{}
+	for _, e := range r.Entries() {
+		encryption := e.Encryption
+		if encryption == "" {
+			encryption = "-"
+		}
+		fmt.Fprintf(w, "%10s  %s -> %s  [%s]\n", utils.FormatSize(e.Size), e.Source, e.Target, encryption)
+		counts[encryption]++
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	summary := make([]string, len(names))
+	for i, name := range names {
+		summary[i] = fmt.Sprintf("%d %s", counts[name], name)
+	}
+	fmt.Fprintf(w, "Encryption: %s\n", strings.Join(summary, ", "))
+}
+
+// copyEncryption describes how the copy of an object at dstKey is
+// encrypted, as the destination store reports it. Local files have no
+// encryption at rest.
+func copyEncryption(ctx context.Context, dst Endpoint, dstKey string, opts Options) string {
+	var parts []string
+	if opts.Encrypt {
+		parts = append(parts, "client-side")
+	}
+	if !isLocal(dst.Store) {
+		info, err := dst.Store.Stat(destinationContext(ctx, opts), dst.Bucket, dstKey)
+		switch {
+		case err != nil:
+			parts = append(parts, "unknown")
+		case info.Encryption != "":
+			parts = append(parts, info.Encryption)
+		}
+	}
+	return strings.Join(parts, " + ")
+}
+
+// destinationContext returns ctx for reading objects written to the
+// destination, which need the customer key they were written with.
+func destinationContext(ctx context.Context, opts Options) context.Context {
+	var key []byte
+	if opts.Encryption.Mode == store.EncryptionCustomer {
+		key = opts.Encryption.CustomerKey
+	}
+	return store.WithCustomerKey(ctx, key)
+}
@@ -0,0 +1,187 @@
+package transfer
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/encrypt"
+	"github.com/RA-Balaji/storage-synk/retry"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+// Compare decides when an object that exists on both sides is copied again.
+type Compare string
+
+const (
+	// CompareSizeOnly copies objects whose sizes differ.
+	CompareSizeOnly Compare = "size-only"
+	// CompareMtime also copies objects that changed at the source after
+	// the destination copy was written.
+	CompareMtime Compare = "mtime"
+	// CompareChecksum copies objects whose content MD5 differs. Local
+	// files, and objects whose backend does not report an MD5, are hashed.
+	CompareChecksum Compare = "checksum"
+)
+
+func ParseCompare(s string) (Compare, error) {
+	switch c := Compare(s); c {
+	case CompareSizeOnly, CompareMtime, CompareChecksum:
+		return c, nil
+	}
+	return "", fmt.Errorf("Invalid comparison %q: use %s, %s or %s",
+		s, CompareSizeOnly, CompareMtime, CompareChecksum)
+}
+
+// SyncStats summarises what Sync did.
+type SyncStats struct {
+	Copied    int
+	Unchanged int
+	Bytes     int64
+}
+
+// Sync copies the objects under src that are missing from dst or differ
+// from their destination copy according to compare. In a dry run the
+// unchanged objects are reported as skipped.
+func Sync(ctx context.Context, src, dst Endpoint, compare Compare, opts Options) (SyncStats, error) {
+	var stats SyncStats
+	if opts.Encrypt && compare == CompareChecksum {
+		return stats, fmt.Errorf("Checksums cannot be compared when the destination is encrypted")
+	}
+	ctx = store.WithCustomerKey(ctx, opts.SourceKey)
+
+	objects, err := listSource(ctx, src, opts)
+	if err != nil {
+		return stats, err
+	}
+	existing, err := listDestination(ctx, dst, opts.Retry)
+	if err != nil {
+		return stats, err
+	}
+
+	plan := dryrun.FromContext(ctx)
+	var changed []store.ObjectInfo
+	for _, obj := range objects {
+		dstKey := destinationKey(src.Prefix, obj.Key, dst.Prefix)
+		dstObj, ok := existing[dstKey]
+		if ok {
+			cmpObj := obj
+			// Encrypted sources are copied decrypted, so the copy has
+			// their plaintext size and content.
+			if opts.Keyring != nil {
+				if obj, err = withMetadata(ctx, src, obj); err != nil {
+					return stats, err
+				}
+				cmpObj = obj
+				if encrypt.IsEncrypted(obj.Metadata) {
+					cmpObj.Size = encrypt.PlainSize(obj.Metadata, obj.Size)
+					cmpObj.MD5 = ""
+				}
+			}
+			// Encrypted copies are larger by a fixed amount per chunk.
+			if opts.Encrypt {
+				cmpObj.Size = encrypt.CiphertextSize(cmpObj.Size)
+			}
+			differ, err := differs(ctx, src, cmpObj, dst, dstObj, compare, opts)
+			if err != nil {
+				return stats, err
+			}
+			if !differ {
+				stats.Unchanged++
+				if plan != nil {
+					plan.Add(dryrun.Action{
+						Op: dryrun.Skip, Source: src.uri(obj.Key), Target: dst.uri(dstKey), Size: obj.Size,
+					})
+				}
+				continue
+			}
+		}
+		changed = append(changed, obj)
+		stats.Copied++
+		stats.Bytes += obj.Size
+	}
+
+	if plan != nil {
+		planCopies(plan, src, dst, changed, existing, opts)
+		return stats, nil
+	}
+	return stats, copyAll(ctx, src, dst, changed, opts)
+}
+
+// listDestination returns the objects below dst.Prefix by key.
+func listDestination(ctx context.Context, dst Endpoint, policy retry.Policy) (map[string]store.ObjectInfo, error) {
+	var existing map[string]store.ObjectInfo
+	err := retry.Do(ctx, policy, "listing "+dst.uri(dst.Prefix), func(ctx context.Context) error {
+		existing = map[string]store.ObjectInfo{}
+		return dst.Store.List(ctx, dst.Bucket, dst.Prefix, store.ListOptions{Recursive: true},
+			func(obj store.ObjectInfo) error {
+				if !obj.IsPrefix {
+					existing[obj.Key] = obj
+				}
+				return nil
+			})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func differs(
+	ctx context.Context,
+	src Endpoint, srcObj store.ObjectInfo,
+	dst Endpoint, dstObj store.ObjectInfo, compare Compare, opts Options) (bool, error) {
+
+	if srcObj.Size != dstObj.Size {
+		return true, nil
+	}
+
+	switch compare {
+	case CompareMtime:
+		// S3 and HTTP dates only carry whole seconds.
+		return srcObj.ModTime.Truncate(time.Second).After(dstObj.ModTime.Truncate(time.Second)), nil
+
+	case CompareChecksum:
+		srcMD5, err := objectMD5(ctx, src, srcObj, opts.Keyring)
+		if err != nil {
+			return false, err
+		}
+		dstMD5, err := objectMD5(destinationContext(ctx, opts), dst, dstObj, nil)
+		if err != nil {
+			return false, err
+		}
+		return srcMD5 != dstMD5, nil
+	}
+	return false, nil
+}
+
+// objectMD5 returns the MD5 reported by the store, or hashes the content
+// when there is none. Objects encrypted client-side are hashed decrypted
+// with keyring, if given.
+func objectMD5(ctx context.Context, e Endpoint, obj store.ObjectInfo, keyring *encrypt.Keyring) (string, error) {
+	if obj.MD5 != "" {
+		return obj.MD5, nil
+	}
+
+	reader, err := openObject(ctx, e, obj)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var r io.Reader = reader
+	if keyring != nil && encrypt.IsEncrypted(obj.Metadata) {
+		if r, err = keyring.Decrypt(reader, obj.Metadata); err != nil {
+			return "", fmt.Errorf("Error decrypting [%s]: %w", obj.Key, err)
+		}
+	}
+	hash := md5.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", fmt.Errorf("Error computing checksum of [%s]: %w", obj.Key, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,84 @@
+package transfer
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/local"
+	"github.com/RA-Balaji/storage-synk/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncStrategies(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	setup := func() (*storetest.MemStore, *storetest.MemStore) {
+		src := storetest.NewMemStore()
+		dst := storetest.NewMemStore()
+		// same: identical on both sides
+		src.Put("src", "data/same.txt", []byte("same"))
+		dst.Put("dst", "same.txt", []byte("same"))
+		// resized: different size
+		src.Put("src", "data/resized.txt", []byte("longer content"))
+		dst.Put("dst", "resized.txt", []byte("short"))
+		// edited: same size, changed at the source after the copy
+		src.Put("src", "data/edited.txt", []byte("new!"))
+		dst.Put("dst", "edited.txt", []byte("old!"))
+		dst.Touch("dst", "edited.txt", old)
+		// new: missing at the destination
+		src.Put("src", "data/new.txt", []byte("new"))
+		return src, dst
+	}
+
+	tests := []struct {
+		compare   Compare
+		copied    int
+		unchanged int
+	}{
+		{CompareSizeOnly, 2, 2},
+		{CompareMtime, 3, 1},
+		{CompareChecksum, 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.compare), func(t *testing.T) {
+			src, dst := setup()
+			stats, err := Sync(context.Background(),
+				Endpoint{Store: src, Bucket: "src", Prefix: "data/"},
+				Endpoint{Store: dst, Bucket: "dst"},
+				tt.compare, Options{})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.copied, stats.Copied)
+			assert.Equal(t, tt.unchanged, stats.Unchanged)
+
+			data, _ := dst.Get("dst", "resized.txt")
+			assert.Equal(t, "longer content", string(data))
+		})
+	}
+}
+
+func TestSyncChecksumHashesLocalFiles(t *testing.T) {
+	dir := filepath.ToSlash(t.TempDir())
+	src := storetest.NewMemStore()
+	src.Put("src", "a.txt", []byte("aaaa"))
+	src.Put("src", "b.txt", []byte("bbbb"))
+	dst := Endpoint{Store: local.NewFileStore(), Prefix: dir + "/"}
+
+	stats, err := Sync(context.Background(), Endpoint{Store: src, Bucket: "src"}, dst, CompareChecksum, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Copied)
+
+	src.Put("src", "b.txt", []byte("BBBB"))
+	stats, err = Sync(context.Background(), Endpoint{Store: src, Bucket: "src"}, dst, CompareChecksum, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, SyncStats{Copied: 1, Unchanged: 1, Bytes: 4}, stats)
+}
+
+func TestParseCompare(t *testing.T) {
+	c, err := ParseCompare("mtime")
+	assert.NoError(t, err)
+	assert.Equal(t, CompareMtime, c)
+
+	_, err = ParseCompare("fast")
+	assert.Error(t, err)
+}
@@ -0,0 +1,498 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/encrypt"
+	"github.com/RA-Balaji/storage-synk/filter"
+	"github.com/RA-Balaji/storage-synk/journal"
+	"github.com/RA-Balaji/storage-synk/local"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/retry"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/uri"
+)
+
+const defaultConcurrency = 10
+
+// Endpoint is one side of a transfer: a store plus a bucket and a key
+// prefix (or a single object key) inside it.
+type Endpoint struct {
+	Store  store.ObjectStore
+	Bucket string
+	Prefix string
+	// Pattern, when set, selects the keys under Prefix to transfer.
+	Pattern *regexp.Regexp
+	// Version selects a specific version of the single object at Prefix.
+	Version string
+	// Scheme and Account are only used to print object URIs.
+	Scheme  string
+	Account string
+}
+
+// NewEndpoint builds an Endpoint for a parsed URI. Wildcard URIs list the
+// non-wildcard prefix and match the rest of the key against the pattern.
+func NewEndpoint(s store.ObjectStore, u uri.URI) (Endpoint, error) {
+	e := Endpoint{
+		Store:   s,
+		Bucket:  u.Bucket,
+		Prefix:  u.Prefix(),
+		Version: u.VersionID,
+		Scheme:  u.Scheme,
+		Account: u.Account,
+	}
+	if u.HasWildcard() {
+		pattern, err := uri.CompileGlob(u.Key)
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("Invalid pattern in %s: %w", u, err)
+		}
+		e.Pattern = pattern
+	}
+	return e, nil
+}
+
+type Options struct {
+	Concurrency int
+	// StagingDir, when set, makes cloud to cloud copies download each
+	// object to a temporary file in this directory before uploading it.
+	// By default objects are streamed from source to destination.
+	StagingDir string
+	// PartSize and PartConcurrency are passed to the destination store
+	// for chunked uploads; zero keeps the store defaults.
+	PartSize        int64
+	PartConcurrency int
+	// Objects of at least SliceThreshold bytes are downloaded as
+	// SliceConcurrency concurrent ranges of SliceSize bytes when they are
+	// written to a local file. A negative threshold disables sliced
+	// downloads.
+	SliceSize        int64
+	SliceThreshold   int64
+	SliceConcurrency int
+	// Keyring decrypts source objects that were encrypted client-side.
+	// With Encrypt set, every copy is encrypted with it as well.
+	Keyring *encrypt.Keyring
+	Encrypt bool
+	// Encryption selects how the destination store encrypts the copies at
+	// rest. SourceKey is the customer key (SSE-C, CSEK) needed to read
+	// the source objects, if any.
+	Encryption store.Encryption
+	SourceKey  []byte
+	// MetadataDirective decides whether the copies get the headers and
+	// user metadata of their source, Headers and Metadata, or both; the
+	// empty directive keeps those of the source.
+	MetadataDirective MetadataDirective
+	Headers           store.Headers
+	Metadata          map[string]string
+	// Filter selects the source objects to transfer; nil selects all.
+	Filter *filter.Filter
+	// Retry sets how failed listings and object copies are retried.
+	Retry retry.Policy
+	// FailFast stops the transfer at the first failed object instead of
+	// copying the remaining ones and reporting all failures at the end.
+	FailFast bool
+	// Report, when set, receives every copied object with the encryption
+	// the destination reports for it.
+	Report *Report
+	// Journal, when set, records finished objects and in-flight uploads so
+	// that the job can be resumed. Objects it already lists as finished
+	// are skipped unless they changed at the source since.
+	Journal *journal.Journal
+}
+
+// Copy copies every object under src into dst, keeping the key layout
+// relative to src.Prefix. If src.Prefix names a single object only that
+// object is copied.
+//
+// When ctx carries a dryrun.Plan nothing is written; the objects that
+// would be copied or overwritten are added to the plan instead.
+func Copy(ctx context.Context, src, dst Endpoint, opts Options) error {
+	ctx = store.WithCustomerKey(ctx, opts.SourceKey)
+	objects, err := listSource(ctx, src, opts)
+	if err != nil {
+		return err
+	}
+
+	if plan := dryrun.FromContext(ctx); plan != nil {
+		existing, err := listDestination(ctx, dst, opts.Retry)
+		if err != nil {
+			return err
+		}
+		planCopies(plan, src, dst, objects, existing, opts)
+		return nil
+	}
+	return copyAll(ctx, src, dst, objects, opts)
+}
+
+// planCopies adds the copies of objects to plan. Objects present in
+// existing are reported as overwrites.
+func planCopies(
+	plan *dryrun.Plan,
+	src, dst Endpoint, objects []store.ObjectInfo,
+	existing map[string]store.ObjectInfo, opts Options) {
+
+	for _, obj := range objects {
+		dstKey := destinationKey(src.Prefix, obj.Key, dst.Prefix)
+		action := dryrun.Action{Op: dryrun.Copy, Source: src.uri(obj.Key), Target: dst.uri(dstKey), Size: obj.Size}
+		if opts.Journal != nil && opts.Journal.Done(dstKey, obj) {
+			action.Op = dryrun.Skip
+		} else if _, ok := existing[dstKey]; ok {
+			action.Op = dryrun.Overwrite
+		}
+		plan.Add(action)
+	}
+}
+
+// copyAll copies objects from src to dst with opts.Concurrency workers.
+// Every failed object is listed in the returned *Errors; with
+// opts.FailFast the copy stops at the first failure. Objects are reported
+// to the progress tracker of ctx, if any.
+func copyAll(ctx context.Context, src, dst Endpoint, objects []store.ObjectInfo, opts Options) error {
+	tracker := progress.FromContext(ctx)
+	var pending []store.ObjectInfo
+	for _, obj := range objects {
+		dstKey := destinationKey(src.Prefix, obj.Key, dst.Prefix)
+		if opts.Journal == nil || !opts.Journal.Done(dstKey, obj) {
+			pending = append(pending, obj)
+		}
+	}
+	if tracker != nil {
+		var size int64
+		for _, obj := range pending {
+			size += obj.Size
+		}
+		tracker.AddTotal(len(pending), size)
+	}
+
+	policy := ContinueOnError
+	if opts.FailFast {
+		policy = FailFast
+	}
+	pool := NewPool(ctx, opts.Concurrency, policy)
+	for _, obj := range pending {
+		obj := obj
+		dstKey := destinationKey(src.Prefix, obj.Key, dst.Prefix)
+
+		started := pool.Go(src.uri(obj.Key), func(ctx context.Context) error {
+			var file *progress.File
+			if tracker != nil {
+				file = tracker.StartFile(dstKey, obj.Size)
+				ctx = progress.WithFile(ctx, file)
+			}
+
+			err := retry.Do(ctx, opts.Retry, src.uri(obj.Key), func(ctx context.Context) error {
+				if file != nil {
+					file.Reset()
+				}
+				return copyObject(ctx, src, obj, dst, dstKey, opts)
+			})
+			if err == nil && opts.Journal != nil {
+				err = opts.Journal.MarkDone(dstKey, obj)
+			}
+			if err == nil && opts.Report != nil {
+				opts.Report.Add(ReportEntry{
+					Source:     src.uri(obj.Key),
+					Target:     dst.uri(dstKey),
+					Size:       obj.Size,
+					Encryption: copyEncryption(ctx, dst, dstKey, opts),
+				})
+			}
+			if file != nil {
+				file.Finish(err)
+			}
+			return err
+		})
+		if !started {
+			break
+		}
+	}
+	return pool.Wait()
+}
+
+// uri formats key as a URI of the endpoint's backend.
+func (e Endpoint) uri(key string) string {
+	return e.versionURI(key, "")
+}
+
+// versionURI returns the URI of a version of the object at key; the
+// current object when version is empty.
+func (e Endpoint) versionURI(key, version string) string {
+	if e.Scheme == "" {
+		return path.Join(e.Bucket, key)
+	}
+	return uri.URI{Scheme: e.Scheme, Account: e.Account, Bucket: e.Bucket, Key: key, VersionID: version}.String()
+}
+
+// listSource returns the objects to copy from src that opts.Filter
+// selects, retrying the listing according to opts.Retry.
+func listSource(ctx context.Context, src Endpoint, opts Options) ([]store.ObjectInfo, error) {
+	var objects []store.ObjectInfo
+	err := retry.Do(ctx, opts.Retry, "listing "+src.uri(src.Prefix), func(ctx context.Context) error {
+		var err error
+		objects, err = listSourceOnce(ctx, src, opts.Filter)
+		return err
+	})
+	return objects, err
+}
+
+func listSourceOnce(ctx context.Context, src Endpoint, f *filter.Filter) ([]store.ObjectInfo, error) {
+	if src.Version != "" {
+		versioned, ok := src.Store.(store.VersionedStore)
+		if !ok {
+			return nil, fmt.Errorf("Object versions are not supported for [%s]", src.Prefix)
+		}
+		info, err := versioned.StatVersion(ctx, src.Bucket, src.Prefix, src.Version)
+		if err != nil {
+			return nil, err
+		}
+		return selected(src, f, info), nil
+	}
+
+	if src.Pattern == nil && src.Prefix != "" && !strings.HasSuffix(src.Prefix, "/") {
+		info, err := src.Store.Stat(ctx, src.Bucket, src.Prefix)
+		if err == nil {
+			return selected(src, f, info), nil
+		}
+		if !store.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	prefix := src.Prefix
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	// Includes confined to subdirectories are pushed down into the
+	// listing, so that only those subdirectories are walked.
+	prefixes := []string{prefix}
+	if sub := f.Prefixes(); sub != nil {
+		prefixes = prefixes[:0]
+		for _, p := range sub {
+			prefixes = append(prefixes, prefix+p)
+		}
+	}
+
+	var objects []store.ObjectInfo
+	for _, prefix := range prefixes {
+		err := src.Store.List(ctx, src.Bucket, prefix, store.ListOptions{Recursive: true},
+			func(obj store.ObjectInfo) error {
+				if obj.IsPrefix || strings.HasSuffix(obj.Key, "/") {
+					return nil
+				}
+				if src.Pattern == nil || src.Pattern.MatchString(obj.Key) {
+					objects = append(objects, selected(src, f, obj)...)
+				}
+				return nil
+			})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// selected returns obj if f selects it, or nothing. Keys are matched
+// relative to the source prefix; a single object by its base name.
+func selected(src Endpoint, f *filter.Filter, obj store.ObjectInfo) []store.ObjectInfo {
+	rel := strings.TrimPrefix(strings.TrimPrefix(obj.Key, src.Prefix), "/")
+	if rel == "" {
+		rel = path.Base(obj.Key)
+	}
+	if !f.Match(rel, obj) {
+		return nil
+	}
+	return []store.ObjectInfo{obj}
+}
+
+// destinationKey maps a source key below srcPrefix onto dstPrefix. A single
+// object copied onto a "directory" destination keeps its base name.
+func destinationKey(srcPrefix, srcKey, dstPrefix string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(srcKey, srcPrefix), "/")
+	if rel == "" {
+		if dstPrefix != "" && !strings.HasSuffix(dstPrefix, "/") {
+			return dstPrefix
+		}
+		rel = path.Base(srcKey)
+	}
+	if dstPrefix == "" {
+		return rel
+	}
+	return strings.TrimSuffix(dstPrefix, "/") + "/" + rel
+}
+
+func copyObject(
+	ctx context.Context,
+	src Endpoint, obj store.ObjectInfo,
+	dst Endpoint, dstKey string, opts Options) error {
+
+	if opts.Keyring != nil {
+		var err error
+		obj, err = withMetadata(ctx, src, obj)
+		if err != nil {
+			return err
+		}
+		if opts.Encrypt || encrypt.IsEncrypted(obj.Metadata) {
+			return copyEncrypted(ctx, src, obj, dst, dstKey, opts)
+		}
+	}
+	if src.Store == dst.Store && obj.VersionID == "" {
+		copyOpts := store.CopyOptions{
+			Encryption:      opts.Encryption,
+			Size:            obj.Size,
+			PartSize:        opts.PartSize,
+			PartConcurrency: opts.PartConcurrency,
+		}
+		// Server-side copies keep the headers and metadata of the source
+		// by themselves.
+		if opts.MetadataDirective == MetadataReplace || opts.MetadataDirective == MetadataAdd {
+			var err error
+			if obj, err = withMetadata(ctx, src, obj); err != nil {
+				return err
+			}
+			copyOpts.ReplaceMetadata = true
+			copyOpts.Headers, copyOpts.Metadata = copyMetadata(obj, opts)
+		}
+		return dst.Store.Copy(ctx, src.Bucket, obj.Key, dst.Bucket, dstKey, copyOpts)
+	}
+	if opts.MetadataDirective != MetadataReplace {
+		var err error
+		if obj, err = withMetadata(ctx, src, obj); err != nil {
+			return err
+		}
+	}
+	if opts.StagingDir != "" && !isLocal(src.Store) && !isLocal(dst.Store) {
+		return copyViaStaging(ctx, src, obj, dst, dstKey, opts)
+	}
+	if fileStore, ok := dst.Store.(*local.FileStore); ok && sliced(src, obj, opts) {
+		err := fileStore.WriteSliced(ctx, dst.Bucket, dstKey, writeOptions(obj, dstKey, opts),
+			func(w io.WriterAt) error {
+				return DownloadSliced(ctx, src, obj, w, opts.SliceSize, opts.SliceConcurrency)
+			})
+		if err != nil {
+			return fmt.Errorf("Error copying [%s]: %w", obj.Key, err)
+		}
+		// Ranges arrive out of order, so the file is hashed once complete.
+		return discardCorrupt(ctx, dst, dstKey, verifyFile(ctx, src, obj, dst, dstKey))
+	}
+
+	reader, verify, err := openVerified(ctx, src, obj)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	err = dst.Store.Write(ctx, dst.Bucket, dstKey, reader, writeOptions(obj, dstKey, opts))
+	if err != nil {
+		return fmt.Errorf("Error copying [%s]: %w", obj.Key, err)
+	}
+	return discardCorrupt(ctx, dst, dstKey, verify())
+}
+
+// statObject looks obj up again in src.
+func statObject(ctx context.Context, src Endpoint, obj store.ObjectInfo) (store.ObjectInfo, error) {
+	if obj.VersionID != "" {
+		return src.Store.(store.VersionedStore).StatVersion(ctx, src.Bucket, obj.Key, obj.VersionID)
+	}
+	return src.Store.Stat(ctx, src.Bucket, obj.Key)
+}
+
+func openObject(ctx context.Context, src Endpoint, obj store.ObjectInfo) (io.ReadCloser, error) {
+	return openRange(ctx, src, obj, 0, -1)
+}
+
+func openRange(
+	ctx context.Context,
+	src Endpoint, obj store.ObjectInfo, offset, length int64) (io.ReadCloser, error) {
+
+	if obj.VersionID != "" {
+		return src.Store.(store.VersionedStore).OpenVersion(ctx, src.Bucket, obj.Key, obj.VersionID, offset, length)
+	}
+	return src.Store.Open(ctx, src.Bucket, obj.Key, offset, length)
+}
+
+// copyViaStaging downloads the object to a temporary file and uploads it
+// from there. It needs disk space for the object, but the upload reads from
+// a local file instead of a network stream.
+func copyViaStaging(
+	ctx context.Context,
+	src Endpoint, obj store.ObjectInfo,
+	dst Endpoint, dstKey string, opts Options) error {
+
+	tmp, err := os.CreateTemp(opts.StagingDir, "storage-synk-*")
+	if err != nil {
+		return fmt.Errorf("Error creating staging file in [%s]: %w", opts.StagingDir, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if sliced(src, obj, opts) {
+		err = DownloadSliced(ctx, src, obj, tmp, opts.SliceSize, opts.SliceConcurrency)
+		if err == nil {
+			err = verifyStaged(ctx, src, obj, tmp)
+		}
+	} else {
+		var (
+			reader io.ReadCloser
+			verify func() error
+		)
+		reader, verify, err = openVerified(ctx, src, obj)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tmp, reader)
+		reader.Close()
+		if err == nil {
+			err = verify()
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Error staging [%s]: %w", obj.Key, err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("Error staging [%s]: %w", obj.Key, err)
+	}
+
+	err = dst.Store.Write(ctx, dst.Bucket, dstKey, tmp, writeOptions(obj, dstKey, opts))
+	if err != nil {
+		return fmt.Errorf("Error copying [%s]: %w", obj.Key, err)
+	}
+	return nil
+}
+
+func writeOptions(obj store.ObjectInfo, dstKey string, opts Options) store.WriteOptions {
+	writeOpts := store.WriteOptions{
+		Size:            obj.Size,
+		ModTime:         obj.ModTime,
+		Mode:            obj.Mode,
+		PartSize:        opts.PartSize,
+		PartConcurrency: opts.PartConcurrency,
+		Encryption:      opts.Encryption,
+		CRC32C:          obj.CRC32C,
+	}
+	writeOpts.Headers, writeOpts.Metadata = copyMetadata(obj, opts)
+	if opts.Journal != nil {
+		writeOpts.Checkpoint = opts.Journal.Checkpoint(dstKey, obj)
+	}
+	return writeOpts
+}
+
+// sliced reports whether obj is large enough to be downloaded in ranges.
+// Local sources gain nothing from it.
+func sliced(src Endpoint, obj store.ObjectInfo, opts Options) bool {
+	threshold := opts.SliceThreshold
+	if threshold == 0 {
+		threshold = defaultSliceThreshold
+	}
+	return threshold > 0 && obj.Size >= threshold && !isLocal(src.Store)
+}
+
+func isLocal(s store.ObjectStore) bool {
+	_, ok := s.(*local.FileStore)
+	return ok
+}
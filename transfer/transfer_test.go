@@ -0,0 +1,277 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/RA-Balaji/storage-synk/dryrun"
+	"github.com/RA-Balaji/storage-synk/filter"
+	"github.com/RA-Balaji/storage-synk/local"
+	"github.com/RA-Balaji/storage-synk/progress"
+	"github.com/RA-Balaji/storage-synk/store"
+	"github.com/RA-Balaji/storage-synk/store/storetest"
+	"github.com/RA-Balaji/storage-synk/uri"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyPrefixBetweenStores(t *testing.T) {
+	src := storetest.NewMemStore()
+	dst := storetest.NewMemStore()
+	src.Put("src-bucket", "data/file1.txt", []byte("one"))
+	src.Put("src-bucket", "data/subdir/file2.txt", []byte("two"))
+	src.Put("src-bucket", "data2/other.txt", []byte("other"))
+
+	err := Copy(context.Background(),
+		Endpoint{Store: src, Bucket: "src-bucket", Prefix: "data"},
+		Endpoint{Store: dst, Bucket: "dst-bucket", Prefix: "backup"},
+		Options{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"backup/file1.txt", "backup/subdir/file2.txt"}, dst.Keys("dst-bucket"))
+	data, _ := dst.Get("dst-bucket", "backup/subdir/file2.txt")
+	assert.Equal(t, "two", string(data))
+}
+
+func TestCopySingleObjectWithinStore(t *testing.T) {
+	mem := storetest.NewMemStore()
+	mem.Put("bucket", "dir/file.txt", []byte("content"))
+
+	err := Copy(context.Background(),
+		Endpoint{Store: mem, Bucket: "bucket", Prefix: "dir/file.txt"},
+		Endpoint{Store: mem, Bucket: "other", Prefix: "copies/"},
+		Options{})
+	assert.NoError(t, err)
+
+	data, ok := mem.Get("other", "copies/file.txt")
+	assert.True(t, ok)
+	assert.Equal(t, "content", string(data))
+}
+
+func TestCopyReportsServerSideEncryption(t *testing.T) {
+	mem := storetest.NewMemStore()
+	other := storetest.NewMemStore()
+	mem.Put("bucket", "dir/a.txt", []byte("aaa"))
+	arn := "arn:aws:kms:us-east-1:123456789012:key/abc"
+
+	// Both server-side copies and streamed copies are encrypted.
+	for _, dst := range []*storetest.MemStore{mem, other} {
+		report := NewReport()
+		err := Copy(context.Background(),
+			Endpoint{Store: mem, Bucket: "bucket", Prefix: "dir/"},
+			Endpoint{Store: dst, Bucket: "out", Prefix: "copies/"},
+			Options{Encryption: store.Encryption{Mode: store.EncryptionKMS, KMSKey: arn}, Report: report})
+		assert.NoError(t, err)
+
+		info, err := dst.Stat(context.Background(), "out", "copies/a.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "KMS "+arn, info.Encryption)
+		assert.Equal(t, []ReportEntry{
+			{Source: "bucket/dir/a.txt", Target: "out/copies/a.txt", Size: 3, Encryption: "KMS " + arn},
+		}, report.Entries())
+
+		var out bytes.Buffer
+		report.Print(&out)
+		assert.Contains(t, out.String(), "Encryption: 1 KMS "+arn)
+	}
+}
+
+func TestDestinationKey(t *testing.T) {
+	assert.Equal(t, "b/x.txt", destinationKey("a/", "a/x.txt", "b"))
+	assert.Equal(t, "x.txt", destinationKey("a", "a/x.txt", ""))
+	assert.Equal(t, "renamed.txt", destinationKey("a/x.txt", "a/x.txt", "renamed.txt"))
+	assert.Equal(t, "b/x.txt", destinationKey("a/x.txt", "a/x.txt", "b/"))
+}
+
+func TestCopyWildcardSource(t *testing.T) {
+	src := storetest.NewMemStore()
+	dst := storetest.NewMemStore()
+	src.Put("bucket", "logs/a.gz", []byte("a"))
+	src.Put("bucket", "logs/b.txt", []byte("b"))
+	src.Put("bucket", "logs/sub/c.gz", []byte("c"))
+
+	u, err := uri.Parse("s3://bucket/logs/*.gz")
+	assert.NoError(t, err)
+	srcEndpoint, err := NewEndpoint(src, u)
+	assert.NoError(t, err)
+
+	err = Copy(context.Background(), srcEndpoint, Endpoint{Store: dst, Bucket: "out"}, Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.gz"}, dst.Keys("out"))
+}
+
+func TestCopyViaStagingDir(t *testing.T) {
+	src := storetest.NewMemStore()
+	dst := storetest.NewMemStore()
+	src.Put("bucket", "dir/a.txt", []byte("staged"))
+	stagingDir := t.TempDir()
+
+	err := Copy(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "dir/"},
+		Endpoint{Store: dst, Bucket: "out"},
+		Options{StagingDir: stagingDir})
+	assert.NoError(t, err)
+
+	data, _ := dst.Get("out", "a.txt")
+	assert.Equal(t, "staged", string(data))
+
+	// Staging files are removed once the object has been uploaded.
+	entries, _ := os.ReadDir(stagingDir)
+	assert.Empty(t, entries)
+}
+
+// rangeStore records the ranges opened on the wrapped store.
+type rangeStore struct {
+	*storetest.MemStore
+	mu     sync.Mutex
+	ranges [][2]int64
+	fail   int64
+}
+
+func (r *rangeStore) Open(
+	ctx context.Context,
+	bucket, key string, offset, length int64) (io.ReadCloser, error) {
+
+	r.mu.Lock()
+	r.ranges = append(r.ranges, [2]int64{offset, length})
+	r.mu.Unlock()
+	if offset == r.fail && offset > 0 {
+		return nil, errors.New("range failed")
+	}
+	return r.MemStore.Open(ctx, bucket, key, offset, length)
+}
+
+func TestCopySlicedDownloadToLocal(t *testing.T) {
+	src := &rangeStore{MemStore: storetest.NewMemStore()}
+	content := bytes.Repeat([]byte("0123456789"), 105)
+	src.Put("bucket", "big.bin", content)
+	dir := filepath.ToSlash(t.TempDir())
+
+	err := Copy(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "big.bin"},
+		Endpoint{Store: local.NewFileStore(), Prefix: dir + "/"},
+		Options{SliceSize: 100, SliceThreshold: 1000, PartConcurrency: 3})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dir, "big.bin"))
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+	assert.Len(t, src.ranges, 11)
+	assert.Contains(t, src.ranges, [2]int64{1000, 50})
+}
+
+func TestCopySlicedDownloadFailureLeavesNoFile(t *testing.T) {
+	src := &rangeStore{MemStore: storetest.NewMemStore(), fail: 200}
+	src.Put("bucket", "big.bin", bytes.Repeat([]byte("x"), 1000))
+	dir := filepath.ToSlash(t.TempDir())
+
+	err := Copy(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "big.bin"},
+		Endpoint{Store: local.NewFileStore(), Prefix: dir + "/"},
+		Options{SliceSize: 100, SliceThreshold: 500})
+	assert.ErrorContains(t, err, "range failed")
+
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestCopyDryRunWritesNothing(t *testing.T) {
+	src := storetest.NewMemStore()
+	dst := storetest.NewMemStore()
+	src.Put("src", "data/a.txt", []byte("aaa"))
+	src.Put("src", "data/b.txt", []byte("bbbbb"))
+	dst.Put("dst", "out/b.txt", []byte("old"))
+
+	plan := dryrun.NewPlan()
+	err := Copy(dryrun.WithPlan(context.Background(), plan),
+		Endpoint{Store: src, Bucket: "src", Prefix: "data/", Scheme: "s3"},
+		Endpoint{Store: dst, Bucket: "dst", Prefix: "out/", Scheme: "gs"},
+		Options{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []dryrun.Action{
+		{Op: dryrun.Copy, Source: "s3://src/data/a.txt", Target: "gs://dst/out/a.txt", Size: 3},
+		{Op: dryrun.Overwrite, Source: "s3://src/data/b.txt", Target: "gs://dst/out/b.txt", Size: 5},
+	}, plan.Actions())
+	assert.Equal(t, []string{"out/b.txt"}, dst.Keys("dst"))
+}
+
+func TestCopyReportsProgress(t *testing.T) {
+	src := &rangeStore{MemStore: storetest.NewMemStore(), fail: 200}
+	src.Put("bucket", "data/small.txt", []byte("hello"))
+	src.Put("bucket", "data/big.bin", bytes.Repeat([]byte("x"), 1050))
+	dir := filepath.ToSlash(t.TempDir())
+
+	tracker := progress.NewTracker()
+	err := Copy(progress.WithTracker(context.Background(), tracker),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "data/"},
+		Endpoint{Store: local.NewFileStore(), Prefix: dir + "/"},
+		Options{SliceSize: 100, SliceThreshold: 1000})
+	assert.Error(t, err)
+
+	s := tracker.Snapshot()
+	assert.Equal(t, int64(2), s.ObjectsTotal)
+	assert.Equal(t, int64(1055), s.BytesTotal)
+	assert.Equal(t, int64(1), s.ObjectsDone)
+	assert.Equal(t, int64(1), s.ObjectsFailed)
+	assert.Equal(t, int64(5), s.BytesDone)
+	assert.Empty(t, s.Active)
+}
+
+func TestCopyFiltered(t *testing.T) {
+	src := &listingStore{MemStore: storetest.NewMemStore()}
+	src.Put("bucket", "data/logs/2024/a.gz", []byte("aaaa"))
+	src.Put("bucket", "data/logs/2024/b.tmp", []byte("bbbb"))
+	src.Put("bucket", "data/logs/2023/c.gz", []byte("c"))
+	src.Put("bucket", "data/images/d.png", []byte("dddd"))
+	dst := storetest.NewMemStore()
+
+	f, err := filter.New(filter.Options{
+		Include: []string{"logs/**/*.gz", "logs/**/*.tmp"},
+		Exclude: []string{"*.tmp"},
+		MinSize: 2,
+	})
+	assert.NoError(t, err)
+	err = Copy(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "data/"},
+		Endpoint{Store: dst, Bucket: "out"},
+		Options{Filter: f})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"logs/2024/a.gz"}, dst.Keys("out"))
+	// Only the directory the includes are confined to is listed.
+	assert.Equal(t, []string{"data/logs/"}, src.listed)
+}
+
+func TestCopyFilteredLocalWalk(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"keep.txt": "k", "sub/keep.txt": "k", "sub/skip.bak": "s"} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	dst := storetest.NewMemStore()
+
+	f, err := filter.New(filter.Options{Exclude: []string{"*.bak"}})
+	assert.NoError(t, err)
+	err = Copy(context.Background(),
+		Endpoint{Store: local.NewFileStore(), Prefix: dir + "/"},
+		Endpoint{Store: dst, Bucket: "out"},
+		Options{Filter: f})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"keep.txt", "sub/keep.txt"}, dst.Keys("out"))
+}
+
+// listingStore records the prefixes it lists.
+type listingStore struct {
+	*storetest.MemStore
+	listed []string
+}
+
+func (l *listingStore) List(ctx context.Context, bucket, prefix string, opts store.ListOptions, fn store.WalkFunc) error {
+	l.listed = append(l.listed, prefix)
+	return l.MemStore.List(ctx, bucket, prefix, opts, fn)
+}
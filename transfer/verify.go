@@ -0,0 +1,92 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/store"
+)
+
+// openVerified opens obj and hashes what is read from it. The returned
+// function checks the content against the checksums the source store
+// reported, once it has all been read. Objects without checksums, such as
+// local files, are not checked.
+func openVerified(ctx context.Context, src Endpoint, obj store.ObjectInfo) (io.ReadCloser, func() error, error) {
+	reader, err := openObject(ctx, src, obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	want := checksum.Of(obj)
+	if want.Empty() {
+		return reader, func() error { return nil }, nil
+	}
+
+	hashed := checksum.NewReader(reader, want)
+	verify := func() error {
+		return verifySource(ctx, src, obj, hashed)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{hashed, reader}, verify, nil
+}
+
+// verifySource checks the content hashed by r against obj. On a mismatch
+// the object is looked up again before it counts as corrupt: the listing
+// may predate an overwrite, or carry an S3 ETag that is not an MD5.
+func verifySource(ctx context.Context, src Endpoint, obj store.ObjectInfo, r *checksum.Reader) error {
+	err := r.Check(checksum.Of(obj), obj.Size)
+	if err == nil {
+		return nil
+	}
+	if info, statErr := statObject(ctx, src, obj); statErr == nil && r.Check(checksum.Of(info), info.Size) == nil {
+		return nil
+	}
+	return fmt.Errorf("Error verifying [%s]: %w", obj.Key, err)
+}
+
+// verifyFile hashes the object at key of e, which was written from obj,
+// and checks it against obj.
+func verifyFile(ctx context.Context, src Endpoint, obj store.ObjectInfo, e Endpoint, key string) error {
+	if checksum.Of(obj).Empty() {
+		return nil
+	}
+	reader, err := e.Store.Open(ctx, e.Bucket, key, 0, -1)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return verifyContent(ctx, src, obj, reader)
+}
+
+// verifyStaged hashes a staging file downloaded from obj.
+func verifyStaged(ctx context.Context, src Endpoint, obj store.ObjectInfo, f *os.File) error {
+	if checksum.Of(obj).Empty() {
+		return nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return verifyContent(ctx, src, obj, f)
+}
+
+func verifyContent(ctx context.Context, src Endpoint, obj store.ObjectInfo, r io.Reader) error {
+	hashed := checksum.NewReader(r, checksum.Of(obj))
+	if _, err := io.Copy(io.Discard, hashed); err != nil {
+		return fmt.Errorf("Error verifying [%s]: %w", obj.Key, err)
+	}
+	return verifySource(ctx, src, obj, hashed)
+}
+
+// discardCorrupt removes a copy that failed verification, so that a
+// corrupt object is not left behind if the retries give up. err is
+// returned unchanged.
+func discardCorrupt(ctx context.Context, dst Endpoint, dstKey string, err error) error {
+	if err != nil {
+		dst.Store.Delete(ctx, dst.Bucket, dstKey)
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RA-Balaji/storage-synk/checksum"
+	"github.com/RA-Balaji/storage-synk/local"
+	"github.com/RA-Balaji/storage-synk/retry"
+	"github.com/RA-Balaji/storage-synk/store/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+// corruptingStore flips a byte of the content of its first opens.
+type corruptingStore struct {
+	*storetest.MemStore
+	corrupt int
+	opens   int
+}
+
+func (c *corruptingStore) Open(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	r, err := c.MemStore.Open(ctx, bucket, key, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	c.opens++
+	if c.opens > c.corrupt {
+		return r, nil
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	data[0] ^= 0xff
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestCopyRetriesCorruptedDownload(t *testing.T) {
+	src := &corruptingStore{MemStore: storetest.NewMemStore(), corrupt: 1}
+	src.Put("bucket", "in/a.txt", []byte("hello"))
+	dir := t.TempDir()
+
+	err := Copy(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "in/"},
+		Endpoint{Store: local.NewFileStore(), Prefix: dir + "/"},
+		Options{Retry: retry.Policy{BaseDelay: time.Millisecond}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, src.opens)
+	data, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestCopyDiscardsCorruptedCopy(t *testing.T) {
+	src := &corruptingStore{MemStore: storetest.NewMemStore(), corrupt: 100}
+	src.Put("bucket", "in/a.txt", []byte("hello"))
+	dir := t.TempDir()
+
+	err := Copy(context.Background(),
+		Endpoint{Store: src, Bucket: "bucket", Prefix: "in/"},
+		Endpoint{Store: local.NewFileStore(), Prefix: dir + "/"},
+		Options{Retry: retry.Policy{BaseDelay: time.Millisecond, MaxAttempts: 2}})
+	assert.ErrorIs(t, err, checksum.ErrMismatch)
+	assert.ErrorContains(t, err, "Error verifying [in/a.txt]")
+	_, err = os.Stat(filepath.Join(dir, "a.txt"))
+	assert.True(t, os.IsNotExist(err))
+}
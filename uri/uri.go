@@ -0,0 +1,297 @@
+package uri
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	SchemeS3    = "s3"
+	SchemeGCS   = "gs"
+	SchemeAzure = "az"
+	SchemeFile  = "file"
+
+	maxKeyLength = 1024
+)
+
+// URI is a parsed storage location:
+//
+//	s3://bucket/key/prefix/             directory under an S3 bucket
+//	gs://bucket/logs/*.gz               wildcard below gs://bucket/logs/
+//	s3://bucket/object?versionId=abc    specific object version
+//	gs://bucket/object#1712345678901    specific object generation
+//	az://account/container/prefix       Azure blob container of an account
+//	file:///tmp/data or /tmp/data       local path
+type URI struct {
+	Scheme string
+	// Account is the Azure storage account; it is empty for other schemes.
+	Account string
+	Bucket  string
+	// Key is the object key or key prefix inside the bucket. For local
+	// paths it is the slash separated path.
+	Key       string
+	VersionID string
+	// Remote is the name of the configured remote the location was given
+	// with, if any. String always prints the full URI.
+	Remote string
+}
+
+// Error reports which part of a URI is invalid.
+type Error struct {
+	URI    string
+	Part   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Invalid %s in %q: %s", e.Part, e.URI, e.Reason)
+}
+
+var (
+	s3BucketRegex     = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+	gcsBucketRegex    = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*[a-z0-9]$`)
+	azAccountRegex    = regexp.MustCompile(`^[a-z0-9]{3,24}$`)
+	azContainerRegex  = regexp.MustCompile(`^[a-z0-9]([a-z0-9]|-[a-z0-9])*$`)
+	gcsGenerationExpr = regexp.MustCompile(`^[0-9]+$`)
+)
+
+func Parse(raw string) (URI, error) {
+	if raw == "" {
+		return URI{}, &Error{URI: raw, Part: "uri", Reason: "must not be empty"}
+	}
+
+	scheme, rest, found := strings.Cut(raw, "://")
+	if !found {
+		return URI{Scheme: SchemeFile, Key: filepath.ToSlash(raw)}, nil
+	}
+
+	u := URI{Scheme: strings.ToLower(scheme)}
+	switch u.Scheme {
+	case SchemeFile:
+		if rest == "" {
+			return URI{}, &Error{URI: raw, Part: "path", Reason: "must not be empty"}
+		}
+		u.Key = filepath.ToSlash(rest)
+		return u, nil
+	case SchemeS3, SchemeGCS, SchemeAzure:
+	default:
+		return URI{}, &Error{URI: raw, Part: "scheme", Reason: fmt.Sprintf(
+			"unsupported scheme %q, expected one of s3, gs, az or file", scheme)}
+	}
+
+	rest, u.VersionID = cutVersion(u.Scheme, rest)
+
+	if u.Scheme == SchemeAzure {
+		u.Account, rest, _ = strings.Cut(rest, "/")
+		if !azAccountRegex.MatchString(u.Account) {
+			return URI{}, &Error{URI: raw, Part: "account", Reason: fmt.Sprintf(
+				"storage account %q must be 3-24 lowercase letters or digits", u.Account)}
+		}
+	}
+
+	u.Bucket, u.Key, _ = strings.Cut(rest, "/")
+	if err := validateBucket(u.Scheme, u.Bucket); err != "" {
+		return URI{}, &Error{URI: raw, Part: "bucket", Reason: err}
+	}
+
+	if len(u.Key) > maxKeyLength {
+		return URI{}, &Error{URI: raw, Part: "key", Reason: fmt.Sprintf(
+			"must be at most %d bytes long, got %d", maxKeyLength, len(u.Key))}
+	}
+	if !utf8.ValidString(u.Key) {
+		return URI{}, &Error{URI: raw, Part: "key", Reason: "must be valid UTF-8"}
+	}
+	if u.HasWildcard() {
+		if _, err := CompileGlob(u.Key); err != nil {
+			return URI{}, &Error{URI: raw, Part: "key", Reason: err.Error()}
+		}
+	}
+
+	if u.VersionID != "" && (u.IsDir() || u.HasWildcard()) {
+		return URI{}, &Error{URI: raw, Part: "version", Reason: "a version can only be given for a single object key"}
+	}
+
+	return u, nil
+}
+
+// ParseRoot parses a URI that names a whole provider or Azure storage
+// account, "s3://", "gs://" or "az://account", whose buckets can be
+// listed. It reports false for any other URI.
+func ParseRoot(raw string) (URI, bool) {
+	scheme, rest, found := strings.Cut(raw, "://")
+	if !found {
+		return URI{}, false
+	}
+	rest = strings.TrimSuffix(rest, "/")
+	switch u := (URI{Scheme: strings.ToLower(scheme)}); u.Scheme {
+	case SchemeS3, SchemeGCS:
+		return u, rest == ""
+	case SchemeAzure:
+		u.Account = rest
+		return u, azAccountRegex.MatchString(rest)
+	}
+	return URI{}, false
+}
+
+// cutVersion strips an S3/Azure "?versionId=" query or a GCS "#generation"
+// suffix from the path. A bare "?" stays part of the key as a wildcard.
+func cutVersion(scheme, rest string) (string, string) {
+	if scheme == SchemeGCS {
+		if i := strings.LastIndex(rest, "#"); i >= 0 && gcsGenerationExpr.MatchString(rest[i+1:]) {
+			return rest[:i], rest[i+1:]
+		}
+		return rest, ""
+	}
+
+	lower := strings.ToLower(rest)
+	if i := strings.LastIndex(lower, "?versionid="); i >= 0 {
+		return rest[:i], rest[i+len("?versionid="):]
+	}
+	return rest, ""
+}
+
+func validateBucket(scheme, bucket string) string {
+	name := "bucket name"
+	if scheme == SchemeAzure {
+		name = "container name"
+	}
+
+	if bucket == "" {
+		return name + " is missing"
+	}
+	if strings.ContainsAny(bucket, "*?[") {
+		return fmt.Sprintf("wildcards are not supported in the %s %q", name, bucket)
+	}
+	if len(bucket) < 3 || len(bucket) > 63 {
+		return fmt.Sprintf("%s %q must be 3-63 characters long", name, bucket)
+	}
+
+	switch scheme {
+	case SchemeS3:
+		if !s3BucketRegex.MatchString(bucket) {
+			return fmt.Sprintf("%s %q may only contain lowercase letters, digits, dots and hyphens "+
+				"and must start and end with a letter or digit", name, bucket)
+		}
+	case SchemeGCS:
+		if !gcsBucketRegex.MatchString(bucket) {
+			return fmt.Sprintf("%s %q may only contain lowercase letters, digits, dots, hyphens and "+
+				"underscores and must start and end with a letter or digit", name, bucket)
+		}
+	case SchemeAzure:
+		if !azContainerRegex.MatchString(bucket) {
+			return fmt.Sprintf("%s %q may only contain lowercase letters, digits and single hyphens "+
+				"and must start and end with a letter or digit", name, bucket)
+		}
+	}
+	return ""
+}
+
+// IsDir reports whether the URI names a "directory": the whole bucket or a
+// key ending with "/".
+func (u URI) IsDir() bool {
+	return u.Key == "" || strings.HasSuffix(u.Key, "/")
+}
+
+func (u URI) HasWildcard() bool {
+	return strings.ContainsAny(u.Key, "*?[")
+}
+
+// Prefix is the part of the key that can be used for listing: the key up to
+// the last "/" before the first wildcard, or the whole key without wildcards.
+func (u URI) Prefix() string {
+	if !u.HasWildcard() {
+		return u.Key
+	}
+	head := u.Key[:strings.IndexAny(u.Key, "*?[")]
+	return head[:strings.LastIndex(head, "/")+1]
+}
+
+// Match reports whether key is selected by the URI's wildcard pattern. It
+// always returns true for URIs without wildcards.
+func (u URI) Match(key string) bool {
+	if !u.HasWildcard() {
+		return true
+	}
+	re, err := CompileGlob(u.Key)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(key)
+}
+
+// IsLocal reports whether the URI points at the local filesystem.
+func (u URI) IsLocal() bool {
+	return u.Scheme == SchemeFile
+}
+
+func (u URI) String() string {
+	if u.Scheme == SchemeFile {
+		return u.Key
+	}
+
+	s := u.Scheme + "://"
+	if u.Account != "" {
+		s += u.Account + "/"
+	}
+	s += u.Bucket
+	if u.Key != "" {
+		s += "/" + u.Key
+	}
+	if u.VersionID != "" {
+		if u.Scheme == SchemeGCS {
+			s += "#" + u.VersionID
+		} else {
+			s += "?versionId=" + u.VersionID
+		}
+	}
+	return s
+}
+
+// CompileGlob turns a glob into an anchored regular expression. "*" and "?"
+// do not cross "/", "**" does, and "[...]" is a character class.
+func CompileGlob(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				// "**/" also matches zero directories.
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class in %q", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			if c < utf8.RuneSelf {
+				sb.WriteString(regexp.QuoteMeta(string(c)))
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
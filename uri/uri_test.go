@@ -0,0 +1,105 @@
+package uri
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want URI
+	}{
+		{"s3://bucket", URI{Scheme: SchemeS3, Bucket: "bucket"}},
+		{"s3://bucket/dir/", URI{Scheme: SchemeS3, Bucket: "bucket", Key: "dir/"}},
+		{"gs://my_bucket/a/b.txt", URI{Scheme: SchemeGCS, Bucket: "my_bucket", Key: "a/b.txt"}},
+		{"s3://bucket/a.txt?versionId=3HL4kqt", URI{Scheme: SchemeS3, Bucket: "bucket", Key: "a.txt", VersionID: "3HL4kqt"}},
+		{"gs://bucket/a.txt#1712345678901", URI{Scheme: SchemeGCS, Bucket: "bucket", Key: "a.txt", VersionID: "1712345678901"}},
+		{"gs://bucket/a#b", URI{Scheme: SchemeGCS, Bucket: "bucket", Key: "a#b"}},
+		{"az://account1/container/x/y", URI{Scheme: SchemeAzure, Account: "account1", Bucket: "container", Key: "x/y"}},
+		{"file:///tmp/data", URI{Scheme: SchemeFile, Key: "/tmp/data"}},
+		{"./relative/dir", URI{Scheme: SchemeFile, Key: "./relative/dir"}},
+		{"S3://bucket/k", URI{Scheme: SchemeS3, Bucket: "bucket", Key: "k"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.raw)
+		assert.NoError(t, err, tt.raw)
+		assert.Equal(t, tt.want, got, tt.raw)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		raw  string
+		part string
+	}{
+		{"", "uri"},
+		{"ftp://host/file", "scheme"},
+		{"file://", "path"},
+		{"s3://", "bucket"},
+		{"s3://ab/key", "bucket"},
+		{"s3://Bad_Bucket/key", "bucket"},
+		{"gs://buck*et/key", "bucket"},
+		{"az://A/container", "account"},
+		{"az://account1/bad--container", "bucket"},
+		{"s3://bucket/dir/?versionId=1", "version"},
+		{"s3://bucket/logs/[abc", "key"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.raw)
+		var uriErr *Error
+		if assert.True(t, errors.As(err, &uriErr), tt.raw) {
+			assert.Equal(t, tt.part, uriErr.Part, tt.raw)
+		}
+	}
+}
+
+func TestDirAndWildcards(t *testing.T) {
+	u, _ := Parse("s3://bucket/logs/2024-*/app.log")
+	assert.False(t, u.IsDir())
+	assert.True(t, u.HasWildcard())
+	assert.Equal(t, "logs/", u.Prefix())
+	assert.True(t, u.Match("logs/2024-01/app.log"))
+	assert.False(t, u.Match("logs/2024-01/sub/app.log"))
+
+	u, _ = Parse("gs://bucket/data/**/*.csv")
+	assert.Equal(t, "data/", u.Prefix())
+	assert.True(t, u.Match("data/a.csv"))
+	assert.True(t, u.Match("data/x/y/a.csv"))
+	assert.False(t, u.Match("data/x/y/a.json"))
+
+	u, _ = Parse("s3://bucket")
+	assert.True(t, u.IsDir())
+	assert.Equal(t, "", u.Prefix())
+}
+
+func TestString(t *testing.T) {
+	for _, raw := range []string{
+		"s3://bucket/a/b",
+		"s3://bucket/a?versionId=xyz",
+		"gs://bucket/a#123",
+		"az://account1/container/a",
+	} {
+		u, err := Parse(raw)
+		assert.NoError(t, err)
+		assert.Equal(t, raw, u.String())
+	}
+}
+
+func TestParseRoot(t *testing.T) {
+	for raw, want := range map[string]URI{
+		"s3://":         {Scheme: SchemeS3},
+		"GS:///":        {Scheme: SchemeGCS},
+		"az://account/": {Scheme: SchemeAzure, Account: "account"},
+	} {
+		u, ok := ParseRoot(raw)
+		assert.True(t, ok, raw)
+		assert.Equal(t, want, u, raw)
+	}
+	for _, raw := range []string{"s3://bucket", "az://", "az://account/container", "/tmp", "file:///"} {
+		_, ok := ParseRoot(raw)
+		assert.False(t, ok, raw)
+	}
+}
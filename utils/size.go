@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a byte count such as "5242880", "64MiB", "100MB" or
+// "1.5G". Single letter units are binary.
+func ParseSize(s string) (int64, error) {
+	value := strings.TrimSpace(s)
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(value), strings.ToUpper(unit.suffix)) {
+			value = strings.TrimSpace(value[:len(value)-len(unit.suffix)])
+			multiplier = unit.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("Invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize formats a byte count with binary units, e.g. "1.5 GiB".
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit && exp < 5; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
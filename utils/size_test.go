@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"0":       0,
+		"5242880": 5242880,
+		"64MiB":   64 << 20,
+		"64mib":   64 << 20,
+		"100MB":   100 * 1000 * 1000,
+		"1.5G":    3 << 29,
+		"512 KiB": 512 << 10,
+		"10B":     10,
+	}
+	for in, want := range tests {
+		got, err := ParseSize(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "MiB", "-1", "ten"} {
+		_, err := ParseSize(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0 B", FormatSize(0))
+	assert.Equal(t, "1023 B", FormatSize(1023))
+	assert.Equal(t, "1.0 KiB", FormatSize(1024))
+	assert.Equal(t, "1.5 MiB", FormatSize(3<<19))
+	assert.Equal(t, "2.0 TiB", FormatSize(2<<40))
+}